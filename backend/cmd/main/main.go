@@ -1,18 +1,20 @@
 package main
 
 import (
-	"crypto/tls"
+	"context"
 	"errors"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
 	"time"
 
 	"github.com/getsentry/sentry-go"
 
 	"github.com/SuperPingPong/tournoi/internal/controllers/public"
+	"github.com/SuperPingPong/tournoi/internal/fftt"
+	"github.com/SuperPingPong/tournoi/internal/mailer"
 	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/SuperPingPong/tournoi/internal/payments"
 	"gorm.io/gorm"
 
 	"github.com/gin-gonic/gin"
@@ -28,7 +30,7 @@ func main() {
 	_ = godotenv.Load()
 
 	var mandatoryEnvVars = []string{
-		"ADMIN_EMAIL", "EXTERNAL_URL", "JWT_SECRET_KEY", "SENTRY_DSN", "TOKEN_JSON", "CREDENTIALS_JSON",
+		"ADMIN_EMAIL", "EXTERNAL_URL", "JWT_SECRET_KEY", "SENTRY_DSN",
 	}
 	for _, envVar := range mandatoryEnvVars {
 		if os.Getenv(envVar) == "" {
@@ -51,13 +53,32 @@ func main() {
 	// Flush buffered events before the program terminates.
 	defer sentry.Flush(2 * time.Second)
 
+	// The mailer backend is selected by MAILER_BACKEND (gmail, smtp or file)
+	emailMailer, err := mailer.NewFromEnv(db)
+	if err != nil {
+		log.Fatalf("mailer.NewFromEnv: %s", err)
+	}
+
+	// The FFTT client is configured by the FFTT_* environment variables
+	ffttClient, err := fftt.NewFromEnv()
+	if err != nil {
+		log.Fatalf("fftt.NewFromEnv: %s", err)
+	}
+
+	// The online payments are enabled by PAYMENT_PROVIDER
+	paymentProvider, err := payments.NewFromEnv()
+	if err != nil {
+		log.Fatalf("payments.NewFromEnv: %s", err)
+	}
+
 	r := gin.Default()
 
-	public.NewAPI(db, r, &http.Client{
-		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		},
-	}, sentryDsn)
+	api := public.NewAPI(db, r, ffttClient, emailMailer, paymentProvider, sentryDsn)
+	go api.Outbox().Run(context.Background())
+	go api.RunEntriesDigests(context.Background())
+	go api.Campaigns().Run(context.Background())
+	go api.RunPointsSync(context.Background())
+	go api.RunOnlinePayments(context.Background())
 
 	// OnlyCategories: []string{"P", "B1", "B2", "M1", "M2"},
 	bands := []models.Band{
@@ -207,11 +228,6 @@ func main() {
 		}
 	}
 
-	_, err = public.GetGmailService()
-	if err != nil {
-		panic(err)
-	}
-
 	err = r.Run("0.0.0.0:8080")
 	if err != nil {
 		panic(err)
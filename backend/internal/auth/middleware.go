@@ -17,6 +17,17 @@ import (
 
 var IdentityKey = "uid"
 
+// ImpersonatorKey is the claim holding the admin acting as the identity of the token
+var ImpersonatorKey = "imp"
+
+const ImpersonationTimeout = 30 * time.Minute
+
+// Impersonation is the payload of a token issued to an admin acting as another user
+type Impersonation struct {
+	User  *models.User
+	Admin *models.User
+}
+
 func (a *AuthBusiness) AuthMiddleware() (*jwt.GinJWTMiddleware, error) {
 	// _ = godotenv.Load()
 	jwtSecretKey := os.Getenv("JWT_SECRET_KEY")
@@ -35,6 +46,12 @@ func (a *AuthBusiness) AuthMiddleware() (*jwt.GinJWTMiddleware, error) {
 					IdentityKey: user.ID,
 				}
 			}
+			if impersonation, ok := data.(*Impersonation); ok {
+				return jwt.MapClaims{
+					IdentityKey:     impersonation.User.ID,
+					ImpersonatorKey: impersonation.Admin.ID,
+				}
+			}
 			return jwt.MapClaims{}
 		},
 		Authenticator: func(ctx *gin.Context) (interface{}, error) {
@@ -55,6 +72,17 @@ func (a *AuthBusiness) AuthMiddleware() (*jwt.GinJWTMiddleware, error) {
 
 			var user models.User
 			a.db.First(&user, uuid.MustParse(claims[IdentityKey].(string)))
+
+			// Impersonation tokens are only honored while the impersonator is still an admin
+			if impersonatorID, ok := claims[ImpersonatorKey].(string); ok {
+				var impersonator models.User
+				err := a.db.First(&impersonator, uuid.MustParse(impersonatorID)).Error
+				if err != nil || !impersonator.IsAdmin {
+					return nil
+				}
+				c.Set(ImpersonatorKey, &impersonator)
+				c.Header("X-Impersonated-By", impersonator.Email)
+			}
 			return &user
 		},
 		Authorizator: func(data interface{}, ctx *gin.Context) bool {
@@ -82,3 +110,10 @@ func (a *AuthBusiness) AuthMiddleware() (*jwt.GinJWTMiddleware, error) {
 
 	return authMiddleware, nil
 }
+
+// ImpersonationToken issues a short-lived token letting admin act as user
+func ImpersonationToken(authMiddleware *jwt.GinJWTMiddleware, admin *models.User, user *models.User) (string, time.Time, error) {
+	shortLived := *authMiddleware
+	shortLived.Timeout = ImpersonationTimeout
+	return shortLived.TokenGenerator(&Impersonation{User: user, Admin: admin})
+}
@@ -0,0 +1,69 @@
+package badge
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	// signatureLength is the number of bytes of the HMAC kept in the token, enough for a QR code read by a tablet
+	signatureLength = 16
+	// PNGSize is the width and height of the QR code images, in pixels
+	PNGSize = 256
+)
+
+var ErrInvalidToken = errors.New("invalid badge token")
+
+// Signer issues and verifies the badge tokens of the members, formatted as <member id>.<signature>
+type Signer struct {
+	key []byte
+}
+
+func NewSigner(key []byte) *Signer {
+	return &Signer{key: key}
+}
+
+func (s *Signer) signature(memberID uuid.UUID) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte("badge:"))
+	mac.Write([]byte(memberID.String()))
+	return mac.Sum(nil)[:signatureLength]
+}
+
+// Token returns the badge token of the member, it is stable as long as the key does not change
+func (s *Signer) Token(memberID uuid.UUID) string {
+	return memberID.String() + "." + base64.RawURLEncoding.EncodeToString(s.signature(memberID))
+}
+
+// Verify returns the member of a token issued by Token
+func (s *Signer) Verify(token string) (uuid.UUID, error) {
+	id, signature, ok := strings.Cut(strings.TrimSpace(token), ".")
+	if !ok {
+		return uuid.Nil, ErrInvalidToken
+	}
+	memberID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, ErrInvalidToken
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(decoded, s.signature(memberID)) {
+		return uuid.Nil, ErrInvalidToken
+	}
+	return memberID, nil
+}
+
+// PNG returns the QR code of the token of the member
+func (s *Signer) PNG(memberID uuid.UUID) ([]byte, error) {
+	png, err := qrcode.Encode(s.Token(memberID), qrcode.Medium, PNGSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode QR code: %w", err)
+	}
+	return png, nil
+}
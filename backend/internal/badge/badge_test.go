@@ -0,0 +1,54 @@
+package badge
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenRoundTrip(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	memberID := uuid.New()
+
+	token := signer.Token(memberID)
+	require.True(t, strings.HasPrefix(token, memberID.String()+"."))
+	require.Equal(t, token, signer.Token(memberID))
+
+	verified, err := signer.Verify(token)
+	require.NoError(t, err)
+	require.Equal(t, memberID, verified)
+
+	// Scanners may add a trailing newline
+	verified, err = signer.Verify(token + "\n")
+	require.NoError(t, err)
+	require.Equal(t, memberID, verified)
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	signer := NewSigner([]byte("secret"))
+	memberID := uuid.New()
+	token := signer.Token(memberID)
+	_, signature, _ := strings.Cut(token, ".")
+
+	for _, invalid := range []string{
+		"",
+		memberID.String(),
+		"not-a-uuid." + signature,
+		uuid.NewString() + "." + signature,
+		memberID.String() + ".!!!",
+		memberID.String() + "." + signature[1:],
+		NewSigner([]byte("other")).Token(memberID),
+	} {
+		_, err := signer.Verify(invalid)
+		require.ErrorIs(t, err, ErrInvalidToken, invalid)
+	}
+}
+
+func TestPNG(t *testing.T) {
+	png, err := NewSigner([]byte("secret")).PNG(uuid.New())
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(png, []byte("\x89PNG")))
+}
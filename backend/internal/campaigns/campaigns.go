@@ -0,0 +1,172 @@
+package campaigns
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+
+	"github.com/SuperPingPong/tournoi/internal/mailer"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	InvalidSegmentError = errors.New("invalid segment")
+)
+
+// Data is given to the templates of a campaign
+type Data struct {
+	ExternalURL string
+	Email       string
+}
+
+// confirmedEmailsQuery selects the emails of the users with confirmed entries
+const confirmedEmailsQuery = `
+	SELECT DISTINCT users.email
+	FROM entries
+	JOIN members ON members.id = entries.member_id AND members.deleted_at IS NULL
+	JOIN users ON users.id = members.user_id AND users.deleted_at IS NULL
+	WHERE entries.confirmed IS TRUE AND entries.deleted_at IS NULL`
+
+// waitingListEmailsQuery selects the emails of the users with a member on the waiting list of a band
+const waitingListEmailsQuery = `
+	SELECT DISTINCT users.email
+	FROM (
+	  SELECT
+	    entries.member_id,
+	    entries.band_id,
+	    ROW_NUMBER() OVER (PARTITION BY entries.band_id ORDER BY entries.created_at ASC) AS band_rank
+	  FROM entries
+	  WHERE entries.confirmed IS TRUE AND entries.deleted_at IS NULL
+	) AS ranked
+	JOIN bands ON bands.id = ranked.band_id
+	JOIN members ON members.id = ranked.member_id AND members.deleted_at IS NULL
+	JOIN users ON users.id = members.user_id AND users.deleted_at IS NULL
+	WHERE ranked.band_id = ? AND ranked.band_rank > bands.max_entries`
+
+// previousEditionEmailsQuery selects the emails of the participants of a past edition without entries in this one
+const previousEditionEmailsQuery = `
+	SELECT DISTINCT past_participants.email
+	FROM past_participants
+	WHERE past_participants.edition = ? AND past_participants.email NOT IN (` + confirmedEmailsQuery + `)`
+
+// Validate checks the segment and the templates of the campaign
+func Validate(campaign *models.Campaign) error {
+	switch campaign.Segment {
+	case models.CampaignSegment_CONFIRMED:
+	case models.CampaignSegment_WAITING_LIST:
+		if !campaign.SegmentBandID.Valid {
+			return fmt.Errorf("%w: %s requires a band", InvalidSegmentError, campaign.Segment)
+		}
+	case models.CampaignSegment_PREVIOUS_EDITION:
+		if campaign.SegmentEdition == "" {
+			return fmt.Errorf("%w: %s requires an edition", InvalidSegmentError, campaign.Segment)
+		}
+	default:
+		return fmt.Errorf("%w: %s", InvalidSegmentError, campaign.Segment)
+	}
+
+	_, err := Render(campaign, "", "")
+	return err
+}
+
+// Recipients returns the emails targeted by the segment of the campaign
+func Recipients(db *gorm.DB, campaign *models.Campaign) ([]string, error) {
+	var query string
+	var args []interface{}
+	switch campaign.Segment {
+	case models.CampaignSegment_CONFIRMED:
+		query = confirmedEmailsQuery
+	case models.CampaignSegment_WAITING_LIST:
+		query = waitingListEmailsQuery
+		args = append(args, campaign.SegmentBandID.UUID)
+	case models.CampaignSegment_PREVIOUS_EDITION:
+		query = previousEditionEmailsQuery
+		args = append(args, campaign.SegmentEdition)
+	default:
+		return nil, fmt.Errorf("%w: %s", InvalidSegmentError, campaign.Segment)
+	}
+
+	emails := []string{}
+	if err := db.Raw(query+" ORDER BY email", args...).Scan(&emails).Error; err != nil {
+		return nil, fmt.Errorf("failed to list recipients: %w", err)
+	}
+	return emails, nil
+}
+
+// Start adds the recipients of the campaign to its send log and marks it as sending.
+// Recipients already in the log keep their status, failed ones are retried if retryFailed is set.
+func Start(db *gorm.DB, campaign *models.Campaign, retryFailed bool) error {
+	emails, err := Recipients(db, campaign)
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		recipients := make([]models.CampaignRecipient, 0, len(emails))
+		for _, email := range emails {
+			recipients = append(recipients, models.CampaignRecipient{
+				CampaignID: campaign.ID,
+				Email:      email,
+				Status:     models.CampaignRecipientStatus_PENDING,
+			})
+		}
+		if len(recipients) > 0 {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				CreateInBatches(&recipients, 500).Error; err != nil {
+				return fmt.Errorf("failed to create recipients: %w", err)
+			}
+		}
+
+		if retryFailed {
+			if err := tx.Model(&models.CampaignRecipient{}).
+				Where("campaign_id = ? AND status = ?", campaign.ID, models.CampaignRecipientStatus_FAILED).
+				Updates(map[string]interface{}{"status": models.CampaignRecipientStatus_PENDING, "error": ""}).Error; err != nil {
+				return fmt.Errorf("failed to retry recipients: %w", err)
+			}
+		}
+
+		return tx.Model(campaign).Update("status", models.CampaignStatus_SENDING).Error
+	})
+}
+
+// Render returns the campaign as sent to the recipient to
+func Render(campaign *models.Campaign, externalURL string, to string) (*mailer.Message, error) {
+	data := Data{ExternalURL: externalURL, Email: to}
+
+	htmlTemplate, err := htmltemplate.New("html").Parse(campaign.HTML)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML template: %w", err)
+	}
+	var html bytes.Buffer
+	if err = htmlTemplate.Execute(&html, data); err != nil {
+		return nil, fmt.Errorf("failed to render HTML template: %w", err)
+	}
+
+	textTemplate, err := texttemplate.New("text").Parse(campaign.Text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse text template: %w", err)
+	}
+	var text bytes.Buffer
+	if err = textTemplate.Execute(&text, data); err != nil {
+		return nil, fmt.Errorf("failed to render text template: %w", err)
+	}
+
+	message := &mailer.Message{
+		To:      to,
+		Subject: campaign.Subject,
+		HTML:    html.String(),
+		Text:    text.String(),
+	}
+	if campaign.AttachmentName != "" {
+		message.Attachments = []mailer.Attachment{{
+			Filename:    campaign.AttachmentName,
+			ContentType: campaign.AttachmentContentType,
+			Data:        campaign.Attachment,
+		}}
+	}
+	return message, nil
+}
@@ -0,0 +1,53 @@
+package campaigns
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	campaign := models.Campaign{
+		Subject:               "Information importante: Tournoi de Lognes",
+		HTML:                  `<a href="{{.ExternalURL}}">Gérer mes inscriptions</a> {{.Email}}`,
+		Text:                  `Gérer mes inscriptions: {{.ExternalURL}}`,
+		AttachmentName:        "buvette.pdf",
+		AttachmentContentType: "application/pdf",
+		Attachment:            []byte("%PDF"),
+	}
+
+	message, err := Render(&campaign, "https://tournoi.example.com", "<jdoe@example.com>")
+
+	require.NoError(t, err)
+	require.Equal(t, "<jdoe@example.com>", message.To)
+	require.Equal(t, campaign.Subject, message.Subject)
+	require.Equal(t, `<a href="https://tournoi.example.com">Gérer mes inscriptions</a> &lt;jdoe@example.com&gt;`, message.HTML)
+	require.Equal(t, "Gérer mes inscriptions: https://tournoi.example.com", message.Text)
+	require.Len(t, message.Attachments, 1)
+	require.Equal(t, "buvette.pdf", message.Attachments[0].Filename)
+}
+
+func TestValidate(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		require.NoError(t, Validate(&models.Campaign{
+			Segment:       models.CampaignSegment_WAITING_LIST,
+			SegmentBandID: uuid.NullUUID{UUID: uuid.New(), Valid: true},
+			HTML:          "<p>Hello</p>",
+		}))
+	})
+	t.Run("MissingBand", func(t *testing.T) {
+		err := Validate(&models.Campaign{Segment: models.CampaignSegment_WAITING_LIST, HTML: "<p>Hello</p>"})
+		require.True(t, errors.Is(err, InvalidSegmentError))
+	})
+	t.Run("UnknownSegment", func(t *testing.T) {
+		err := Validate(&models.Campaign{Segment: "everyone", HTML: "<p>Hello</p>"})
+		require.True(t, errors.Is(err, InvalidSegmentError))
+	})
+	t.Run("InvalidTemplate", func(t *testing.T) {
+		err := Validate(&models.Campaign{Segment: models.CampaignSegment_CONFIRMED, HTML: "{{.Unknown"})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,123 @@
+package campaigns
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/mailer"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/getsentry/sentry-go"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// defaultInterval throttles the sending, Gmail rejects bursts of emails
+	defaultInterval = time.Second
+	defaultIdle     = 30 * time.Second
+)
+
+// Sender sends the campaigns being sent, one recipient at a time
+type Sender struct {
+	db          *gorm.DB
+	mailer      mailer.Mailer
+	externalURL string
+	wake        chan struct{}
+
+	// Interval is the delay between two emails
+	Interval time.Duration
+	// Idle is the delay between two checks when there is nothing to send
+	Idle time.Duration
+}
+
+func NewSender(db *gorm.DB, mailer mailer.Mailer, externalURL string) *Sender {
+	return &Sender{
+		db:          db,
+		mailer:      mailer,
+		externalURL: externalURL,
+		wake:        make(chan struct{}, 1),
+		Interval:    defaultInterval,
+		Idle:        defaultIdle,
+	}
+}
+
+// Wake triggers the sending without waiting for the next check
+func (s *Sender) Wake() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends the pending recipients until ctx is done
+func (s *Sender) Run(ctx context.Context) {
+	for {
+		delay := s.Interval
+		sent, err := s.SendNext()
+		if err != nil {
+			log.Printf("campaigns: %s", err)
+			sentry.CaptureException(err)
+		}
+		if !sent {
+			delay = s.Idle
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		case <-s.wake:
+		}
+	}
+}
+
+// SendNext sends the campaign to its next pending recipient and returns false if there was none.
+// The recipient is locked while sending so that several senders never send it twice.
+func (s *Sender) SendNext() (bool, error) {
+	var sent bool
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var recipient models.CampaignRecipient
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Joins("JOIN campaigns ON campaigns.id = campaign_recipients.campaign_id").
+			Where("campaigns.status = ? AND campaign_recipients.status = ?", models.CampaignStatus_SENDING, models.CampaignRecipientStatus_PENDING).
+			Order("campaign_recipients.created_at ASC").
+			First(&recipient).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return s.completeCampaigns(tx)
+			}
+			return fmt.Errorf("failed to get next recipient: %w", err)
+		}
+
+		var campaign models.Campaign
+		if err = tx.First(&campaign, recipient.CampaignID).Error; err != nil {
+			return fmt.Errorf("failed to get campaign: %w", err)
+		}
+
+		updates := map[string]interface{}{"status": models.CampaignRecipientStatus_SENT, "error": "", "sent_at": time.Now()}
+		message, err := Render(&campaign, s.externalURL, recipient.Email)
+		if err == nil {
+			err = s.mailer.Send(message)
+		}
+		if err != nil {
+			updates = map[string]interface{}{"status": models.CampaignRecipientStatus_FAILED, "error": err.Error()}
+		}
+		if err = tx.Model(&recipient).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update recipient %s: %w", recipient.Email, err)
+		}
+		sent = true
+		return nil
+	})
+	return sent, err
+}
+
+// completeCampaigns marks the campaigns without pending recipients as done
+func (s *Sender) completeCampaigns(tx *gorm.DB) error {
+	return tx.Model(&models.Campaign{}).
+		Where("status = ?", models.CampaignStatus_SENDING).
+		Where("NOT EXISTS (SELECT 1 FROM campaign_recipients WHERE campaign_recipients.campaign_id = campaigns.id AND campaign_recipients.status = ?)", models.CampaignRecipientStatus_PENDING).
+		Updates(map[string]interface{}{"status": models.CampaignStatus_DONE, "sent_at": time.Now()}).Error
+}
@@ -1,8 +1,17 @@
 package public
 
 import (
+	"os"
+
 	"github.com/SuperPingPong/tournoi/internal/auth"
+	"github.com/SuperPingPong/tournoi/internal/badge"
+	"github.com/SuperPingPong/tournoi/internal/campaigns"
+	"github.com/SuperPingPong/tournoi/internal/emails"
+	"github.com/SuperPingPong/tournoi/internal/fftt"
+	"github.com/SuperPingPong/tournoi/internal/mailer"
 	"github.com/SuperPingPong/tournoi/internal/middlewares"
+	"github.com/SuperPingPong/tournoi/internal/outbox"
+	"github.com/SuperPingPong/tournoi/internal/payments"
 
 	jwt "github.com/appleboy/gin-jwt/v2"
 	"github.com/gin-gonic/gin"
@@ -12,13 +21,22 @@ import (
 )
 
 type API struct {
-	db             *gorm.DB
-	router         *gin.Engine
-	httpClient     HTTPClient
+	db     *gorm.DB
+	router *gin.Engine
+	fftt   fftt.Client
+	// playerDirectory enables the local directory of FFTT players, see PLAYER_DIRECTORY
+	playerDirectory bool
+	mailer          mailer.Mailer
+	emails          *emails.Renderer
+	outbox          *outbox.Sender
+	campaigns       *campaigns.Sender
+	badges          *badge.Signer
+	// payments is the provider of the online payments, nil when they are disabled
+	payments       payments.Provider
 	authMiddleware *jwt.GinJWTMiddleware
 }
 
-func NewAPI(db *gorm.DB, r *gin.Engine, client HTTPClient, sentryDSN string) *API {
+func NewAPI(db *gorm.DB, r *gin.Engine, ffttClient fftt.Client, mailer mailer.Mailer, paymentProvider payments.Provider, sentryDSN string) *API {
 	// Initialize Sentry
 	err := sentry.Init(sentry.ClientOptions{
 		Dsn: sentryDSN,
@@ -28,15 +46,49 @@ func NewAPI(db *gorm.DB, r *gin.Engine, client HTTPClient, sentryDSN string) *AP
 	}
 
 	c := &API{
-		db:         db,
-		router:     r,
-		httpClient: client,
+		db:              db,
+		router:          r,
+		fftt:            ffttClient,
+		playerDirectory: os.Getenv("PLAYER_DIRECTORY") == "true",
+		mailer:          mailer,
+		emails:          emails.NewRenderer(emailTemplatesDir(), os.Getenv("EXTERNAL_URL"), paymentProvider != nil),
+		outbox:          outbox.NewSender(db, mailer),
+		campaigns:       campaigns.NewSender(db, mailer, os.Getenv("EXTERNAL_URL")),
+		badges:          badge.NewSigner([]byte(badgeSecretKey())),
+		payments:        paymentProvider,
 	}
 
 	c.setupRouter()
 	return c
 }
 
+// emailTemplatesDir returns EMAIL_TEMPLATES_DIR, email_templates by default
+func emailTemplatesDir() string {
+	if dir := os.Getenv("EMAIL_TEMPLATES_DIR"); dir != "" {
+		return dir
+	}
+	return "email_templates"
+}
+
+// badgeSecretKey returns BADGE_SECRET_KEY, JWT_SECRET_KEY by default.
+// Changing it invalidates the badges already sent.
+func badgeSecretKey() string {
+	if key := os.Getenv("BADGE_SECRET_KEY"); key != "" {
+		return key
+	}
+	return os.Getenv("JWT_SECRET_KEY")
+}
+
+// Outbox returns the sender delivering the emails queued by the API, it has to be run by the caller
+func (api *API) Outbox() *outbox.Sender {
+	return api.outbox
+}
+
+// Campaigns returns the sender of the admin campaigns, it has to be run by the caller
+func (api *API) Campaigns() *campaigns.Sender {
+	return api.campaigns
+}
+
 func (api *API) setupRouter() {
 	var err error
 
@@ -52,18 +104,88 @@ func (api *API) setupRouter() {
 	api.router.GET("/api/logout", api.authMiddleware.LogoutHandler)
 	api.router.GET("/api/players/:id", api.GetFFTTPlayer)
 	api.router.POST("/api/players", api.SearchFFTTPlayers)
+	api.router.GET("/api/calendar.ics", api.GetCalendar)
+	api.router.POST("/api/payments/webhook", api.PaymentWebhook)
 
 	authenticated := api.router.Group("/api")
-	authenticated.Use(api.authMiddleware.MiddlewareFunc())
+	authenticated.Use(api.authMiddleware.MiddlewareFunc(), api.AuditImpersonation())
 	{
 		authenticated.GET("/members", api.ListMembers)
 		authenticated.GET("/members/:id", api.GetMember)
 		authenticated.POST("/members", api.CreateMember)
+		authenticated.POST("/members/manual", api.CreateManualMember)
+		authenticated.PUT("/members/:id", api.UpdateMember)
 		authenticated.DELETE("/members/:id", api.DeleteMember)
 		authenticated.GET("/members/:id/get-entries-history", api.GetMemberEntriesHistory)
 		authenticated.POST("/members/:id/set-entries", api.SetMemberEntries)
 		authenticated.GET("/members/:id/band-availabilities", api.ListBandAvailabilities)
+		authenticated.GET("/members/:id/badge", api.GetMemberBadge)
+		authenticated.POST("/members/:id/checkout", api.CreateCheckout)
+		authenticated.GET("/members/:id/online-payments", api.ListOnlinePayments)
+		authenticated.GET("/members/:id/receipts/:day", api.GetMemberReceipt)
 		authenticated.GET("/bands", api.ListBands)
 		authenticated.POST("/check-auth", api.CheckAuth)
+		authenticated.PUT("/preferences", api.UpdatePreferences)
+		authenticated.GET("/calendar-token", api.GetCalendarToken)
+		authenticated.POST("/calendar-token/reset", api.ResetCalendarToken)
+		authenticated.GET("/member-transfers", api.ListMemberTransfers)
+		authenticated.POST("/member-transfers", api.RequestMemberTransfer)
+		authenticated.POST("/member-transfers/:id/approve", api.ApproveMemberTransfer)
+		authenticated.POST("/member-transfers/:id/reject", api.RejectMemberTransfer)
+		authenticated.POST("/member-transfers/:id/cancel", api.CancelMemberTransfer)
+	}
+
+	club := authenticated.Group("/club")
+	club.Use(ClubManagerRequired())
+	{
+		club.GET("/players", api.ListClubPlayers)
+		club.POST("/registrations", api.RegisterClubPlayers)
+		club.GET("/entries", api.ListClubEntries)
+	}
+
+	admin := authenticated.Group("/admin")
+	admin.Use(AdminRequired())
+	{
+		admin.GET("/club-managers", api.ListClubManagers)
+		admin.POST("/club-managers", api.SetClubManager)
+		admin.DELETE("/club-managers/:id", api.DeleteClubManager)
+		admin.POST("/impersonate", api.Impersonate)
+		admin.GET("/audit-logs", api.ListAuditLogs)
+		admin.GET("/email-templates", api.ListEmailTemplates)
+		admin.GET("/email-templates/:name/preview", api.PreviewEmailTemplate)
+		admin.GET("/campaigns", api.ListCampaigns)
+		admin.POST("/campaigns", api.CreateCampaign)
+		admin.GET("/campaigns/:id", api.GetCampaign)
+		admin.GET("/campaigns/:id/preview", api.PreviewCampaign)
+		admin.POST("/campaigns/:id/send", api.SendCampaign)
+		admin.GET("/campaigns/:id/recipients", api.ListCampaignRecipients)
+		admin.POST("/past-participants", api.ImportPastParticipants)
+		admin.GET("/outbox", api.ListOutboxMessages)
+		admin.POST("/outbox/:id/resend", api.ResendOutboxMessage)
+		admin.GET("/mailer/token", api.GetMailerTokenHealth)
+		admin.POST("/points-sync", api.SyncMembersPoints)
+		admin.GET("/eligibility-issues", api.ListEligibilityIssues)
+		admin.POST("/eligibility-issues/:id/resolve", api.ResolveEligibilityIssue)
+		admin.POST("/players/import", api.ImportPlayers)
+		admin.POST("/members/:id/validate", api.ValidateManualMember)
+		admin.POST("/members/:id/reconcile", api.ReconcileManualMember)
+		admin.GET("/licence-rules", api.GetLicenceRules)
+		admin.PUT("/licence-rules", api.UpdateLicenceRules)
+		admin.GET("/licence-issues", api.ListLicenceIssues)
+		admin.GET("/pricing-rules", api.GetPricingRules)
+		admin.PUT("/pricing-rules", api.UpdatePricingRules)
+		admin.GET("/export", api.ExportRegistrations)
+		admin.GET("/export/girpe", api.ExportGirpe)
+		admin.GET("/check-in/summary", api.GetCheckInSummary)
+		admin.GET("/check-in/bands/:id", api.ListBandCheckIns)
+		admin.POST("/check-in/bands/:id/deadline", api.CloseBandCheckIn)
+		admin.PUT("/check-in/entries/:id", api.SetEntryCheckIn)
+		admin.POST("/check-in/members/:id", api.SetMemberCheckIn)
+		admin.GET("/check-in/badge", api.ScanBadge)
+		admin.POST("/check-in/badge", api.CheckInBadge)
+		admin.GET("/payments/members/:id", api.GetMemberLedger)
+		admin.POST("/payments/members/:id", api.RecordPayment)
+		admin.GET("/payments/report", api.GetCashReport)
+		admin.GET("/receipts", api.ExportReceipts)
 	}
 }
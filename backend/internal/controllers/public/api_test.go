@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/SuperPingPong/tournoi/internal/auth"
+	"github.com/SuperPingPong/tournoi/internal/fftt"
+	"github.com/SuperPingPong/tournoi/internal/mailer"
 	"github.com/SuperPingPong/tournoi/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -18,13 +20,15 @@ import (
 )
 
 type testEnv struct {
-	ctx      *gin.Context
-	api      *API
-	db       *gorm.DB
-	jwt      string
-	adminJWT string
-	user     *models.User
-	teardown func()
+	ctx        *gin.Context
+	api        *API
+	db         *gorm.DB
+	jwt        string
+	adminJWT   string
+	user       *models.User
+	mailer     *mailer.MemoryMailer
+	httpClient *MockHTTPClient
+	teardown   func()
 }
 
 func getTestEnv(t *testing.T) testEnv {
@@ -39,8 +43,16 @@ func getTestEnv(t *testing.T) testEnv {
 	recorder := httptest.NewRecorder()
 	ctx, r := gin.CreateTestContext(recorder)
 
+	// Email templates are read relatively to the backend directory
+	t.Setenv("EMAIL_TEMPLATES_DIR", "../../../email_templates")
+	t.Setenv("EXTERNAL_URL", "https://tournoi.example.com")
+	t.Setenv("TOURNAMENT_START_DATE", "2024-06-08")
+
 	mockHTTPClient := NewMockHTTPClient(t)
-	api := NewAPI(tx, r, mockHTTPClient, "")
+	memoryMailer := mailer.NewMemoryMailer()
+	// The FFTT calls are neither cached nor retried, so that each of them has to be mocked
+	ffttClient := fftt.NewClient(mockHTTPClient, fftt.Config{BaseURL: fftt.DefaultBaseURL})
+	api := NewAPI(tx, r, ffttClient, memoryMailer, nil, "")
 
 	// Create OTP
 	otp := models.OTP{
@@ -120,12 +132,14 @@ func getTestEnv(t *testing.T) testEnv {
 	}
 
 	return testEnv{
-		ctx:      ctx,
-		api:      api,
-		db:       tx,
-		jwt:      response.Token,
-		adminJWT: adminResponse.Token,
-		user:     &user,
+		ctx:        ctx,
+		api:        api,
+		db:         tx,
+		jwt:        response.Token,
+		adminJWT:   adminResponse.Token,
+		user:       &user,
+		mailer:     memoryMailer,
+		httpClient: mockHTTPClient,
 		teardown: func() {
 			tx.Rollback()
 		},
@@ -0,0 +1,196 @@
+package public
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/mailer"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// tournamentDay returns the day of the tournament at now, starting at 1
+func tournamentDay(now time.Time) (int, error) {
+	startDate, err := tournamentStartDate()
+	if err != nil {
+		return 0, err
+	}
+	now = now.In(startDate.Location())
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, startDate.Location())
+	// Days are not always 24 hours long around DST changes
+	day := int(today.Sub(startDate).Round(24*time.Hour)/(24*time.Hour)) + 1
+	if day < 1 {
+		return 0, fmt.Errorf("the tournament starts on %s", startDate.Format("2006-01-02"))
+	}
+	return day, nil
+}
+
+// badgeDay returns the day of the check in, today by default
+func badgeDay(day int) (int, error) {
+	if day > 0 {
+		return day, nil
+	}
+	return tournamentDay(time.Now())
+}
+
+// memberBadgeAttachment returns the QR code of the member as an attachment of its confirmation email
+func (api *API) memberBadgeAttachment(member models.Member) (*mailer.Attachment, error) {
+	png, err := api.badges.PNG(member.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &mailer.Attachment{
+		Filename:    "badge.png",
+		ContentType: "image/png",
+		Data:        png,
+	}, nil
+}
+
+// GetMemberBadge returns the QR code of a member, to be printed or shown at the check-in desk
+func (api *API) GetMemberBadge(ctx *gin.Context) {
+	member, ok := api.getOwnedMember(ctx)
+	if !ok {
+		return
+	}
+
+	png, err := api.badges.PNG(member.ID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	ctx.Header("Content-Disposition", fmt.Sprintf(`inline; filename="badge-%s.png"`, member.ID))
+	ctx.Data(http.StatusOK, "image/png", png)
+}
+
+// getBadgeMember returns the member of a badge token, which has to be valid
+func (api *API) getBadgeMember(ctx *gin.Context, token string) (models.Member, bool) {
+	var member models.Member
+	memberID, err := api.badges.Verify(token)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return member, false
+	}
+	if err = api.db.First(&member, memberID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("member %s not found", memberID))
+			return member, false
+		}
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get member: %w", err))
+		return member, false
+	}
+	return member, true
+}
+
+func (api *API) badgeResponse(ctx *gin.Context, member models.Member, day int) {
+	entries, err := listDueEntries(api.db, member, day)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"member": gin.H{
+			"ID":        member.ID,
+			"PermitID":  member.PermitID,
+			"FirstName": member.FirstName,
+			"LastName":  member.LastName,
+			"ClubName":  member.ClubName,
+		},
+		"day":        day,
+		"entries":    entries,
+		"amount_due": amountDue(entries),
+	})
+}
+
+// ScanBadge returns the member of the scanned ?token= with its entries of the day and the amount due, of today by default or ?day=
+func (api *API) ScanBadge(ctx *gin.Context) {
+	var day int
+	if value := ctx.Query("day"); value != "" {
+		var err error
+		if day, err = strconv.Atoi(value); err != nil || day < 1 {
+			ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid day: %s", value))
+			return
+		}
+	}
+	day, err := badgeDay(day)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("day is required: %w", err))
+		return
+	}
+
+	member, ok := api.getBadgeMember(ctx, ctx.Query("token"))
+	if !ok {
+		return
+	}
+	api.badgeResponse(ctx, member, day)
+}
+
+type CheckInBadgeInput struct {
+	Token string `binding:"required"`
+	// Day is today by default
+	Day int `binding:"omitempty,min=1"`
+}
+
+// CheckInBadge marks present all the entries of the day of the member of a scanned badge
+func (api *API) CheckInBadge(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	var input CheckInBadgeInput
+	if err = ctx.ShouldBindBodyWith(&input, binding.JSON); err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
+		return
+	}
+	day, err := badgeDay(input.Day)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("day is required: %w", err))
+		return
+	}
+
+	member, ok := api.getBadgeMember(ctx, input.Token)
+	if !ok {
+		return
+	}
+	entries, err := listDueEntries(api.db, member, day)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if len(entries) == 0 {
+		ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("no entries of member %s on day %d", member.ID, day))
+		return
+	}
+
+	err = api.db.Transaction(func(tx *gorm.DB) error {
+		for _, entry := range entries {
+			// A scratched player has lost its place, it is up to an admin to give it back
+			if entry.Status == models.CheckInStatus_SCRATCHED {
+				continue
+			}
+			if err := upsertCheckIn(tx, models.CheckIn{
+				EntryID:   entry.EntryID,
+				MemberID:  member.ID,
+				BandID:    entry.BandID,
+				Status:    models.CheckInStatus_PRESENT,
+				CheckedBy: uuid.NullUUID{UUID: user.ID, Valid: true},
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to check in member: %w", err))
+		return
+	}
+
+	api.badgeResponse(ctx, member, day)
+}
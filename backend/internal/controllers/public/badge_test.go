@@ -0,0 +1,114 @@
+package public
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/badge"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTournamentDay(t *testing.T) {
+	t.Setenv("TOURNAMENT_START_DATE", "2024-06-08")
+	location, err := time.LoadLocation(tournamentTimezone)
+	require.NoError(t, err)
+
+	day, err := tournamentDay(time.Date(2024, 6, 8, 8, 30, 0, 0, location))
+	require.NoError(t, err)
+	require.Equal(t, 1, day)
+	// Late in the evening in Paris, already the next day in UTC
+	day, err = tournamentDay(time.Date(2024, 6, 9, 23, 30, 0, 0, location))
+	require.NoError(t, err)
+	require.Equal(t, 2, day)
+
+	_, err = tournamentDay(time.Date(2024, 6, 7, 23, 30, 0, 0, location))
+	require.Error(t, err)
+
+	t.Setenv("TOURNAMENT_START_DATE", "")
+	_, err = tournamentDay(time.Now())
+	require.ErrorIs(t, err, tournamentDatesNotConfiguredError)
+}
+
+func TestBadge(t *testing.T) {
+	env := getTestEnv(t)
+	defer env.teardown()
+
+	bands := []models.Band{
+		{Name: "A", Day: 1, Color: models.BandColor_PINK, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 2, Price: 9, StartTime: "09:00"},
+		{Name: "B", Day: 1, Color: models.BandColor_BLUE, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 1, Price: 8, StartTime: "13:00"},
+		{Name: "C", Day: 2, Color: models.BandColor_GREEN, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 2, Price: 10},
+	}
+	require.NoError(t, env.db.Create(&bands).Error)
+	other := models.Member{FirstName: "Paul", LastName: "Martin", Sex: "M", Points: 700, Category: "S", PermitID: "123450", UserID: env.user.ID}
+	require.NoError(t, env.db.Create(&other).Error)
+	member := models.Member{FirstName: "Jean", LastName: "Pierre", Sex: "M", Points: 580, Category: "S", PermitID: "123451", UserID: env.user.ID}
+	require.NoError(t, env.db.Create(&member).Error)
+	now := time.Now()
+	// The other member takes the only place of band B
+	require.NoError(t, env.db.Create(&models.Entry{MemberID: other.ID, BandID: bands[1].ID, Confirmed: true, CreatedAt: now}).Error)
+	for _, band := range bands {
+		require.NoError(t, env.db.Create(&models.Entry{MemberID: member.ID, BandID: band.ID, Confirmed: true, CreatedAt: now.Add(time.Minute)}).Error)
+	}
+
+	res := performRequest("GET", fmt.Sprintf("/api/members/%s/badge", member.ID), nil, map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Equal(t, "image/png", res.Header().Get("Content-Type"))
+	require.True(t, strings.HasPrefix(res.Body.String(), "\x89PNG"))
+
+	token := env.api.badges.Token(member.ID)
+	res = performRequest("GET", "/api/admin/check-in/badge?day=1&token="+url.QueryEscape(token), nil, map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusForbidden, res.Code)
+
+	res = performRequest("GET", "/api/admin/check-in/badge?day=1&token="+url.QueryEscape(badge.NewSigner([]byte("other")).Token(member.ID)), nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = performRequest("GET", "/api/admin/check-in/badge?day=1&token="+url.QueryEscape(token), nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	var scanned struct {
+		Member    struct{ ID uuid.UUID }
+		Day       int
+		Entries   []DueEntry
+		AmountDue int `json:"amount_due"`
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&scanned))
+	require.Equal(t, member.ID, scanned.Member.ID)
+	require.Equal(t, 1, scanned.Day)
+	require.Len(t, scanned.Entries, 2)
+	require.Equal(t, "A", scanned.Entries[0].BandName)
+	require.True(t, scanned.Entries[1].Waiting)
+	// The waiting list of band B is not due
+	require.Equal(t, 9, scanned.AmountDue)
+
+	res = performRequest("POST", "/api/admin/check-in/badge", strings.NewReader(fmt.Sprintf(`{"Token":%q,"Day":1}`, token)), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&scanned))
+	for _, entry := range scanned.Entries {
+		require.Equal(t, models.CheckInStatus_PRESENT, entry.Status)
+	}
+	var checkIns []models.CheckIn
+	require.NoError(t, env.db.Where("member_id = ?", member.ID).Find(&checkIns).Error)
+	require.Len(t, checkIns, 2)
+
+	require.NoError(t, env.db.Delete(&member).Error)
+	res = performRequest("POST", "/api/admin/check-in/badge", strings.NewReader(fmt.Sprintf(`{"Token":%q,"Day":1}`, token)), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusNotFound, res.Code)
+}
@@ -0,0 +1,232 @@
+package public
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	// The container image has no timezone database
+	_ "time/tzdata"
+
+	"github.com/SuperPingPong/tournoi/internal/emails"
+	"github.com/SuperPingPong/tournoi/internal/ical"
+	"github.com/SuperPingPong/tournoi/internal/mailer"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	tournamentTimezone = "Europe/Paris"
+	// tournamentDayEnd is the end of the events, the time the last bands are usually over
+	tournamentDayEnd = 20 * time.Hour
+	calendarProdID   = "-//SuperPingPong//Tournoi de Lognes//FR"
+)
+
+var tournamentDatesNotConfiguredError = errors.New("TOURNAMENT_START_DATE environment variable not set")
+
+// tournamentStartDate returns the first day of the tournament, from TOURNAMENT_START_DATE formatted as 2006-01-02
+func tournamentStartDate() (time.Time, error) {
+	value := os.Getenv("TOURNAMENT_START_DATE")
+	if value == "" {
+		return time.Time{}, tournamentDatesNotConfiguredError
+	}
+
+	location, err := time.LoadLocation(tournamentTimezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to load timezone: %w", err)
+	}
+	startDate, err := time.ParseInLocation("2006-01-02", value, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid TOURNAMENT_START_DATE: %w", err)
+	}
+	return startDate, nil
+}
+
+// memberEvents returns one event per day the member plays, starting with its earliest band of the day
+func memberEvents(member models.Member, entries []ListMembersEntry, startDate time.Time, language string) []ical.Event {
+	entriesByDay := map[int][]ListMembersEntry{}
+	for _, entry := range entries {
+		entriesByDay[entry.BandDay] = append(entriesByDay[entry.BandDay], entry)
+	}
+	days := make([]int, 0, len(entriesByDay))
+	for day := range entriesByDay {
+		days = append(days, day)
+	}
+	sort.Ints(days)
+
+	var events []ical.Event
+	for _, day := range days {
+		date := startDate.AddDate(0, 0, day-1)
+		event := ical.Event{
+			UID:      fmt.Sprintf("%s-day%d@tournoi", member.ID, day),
+			Location: os.Getenv("TOURNAMENT_LOCATION"),
+			Summary:  fmt.Sprintf("Tournoi de Lognes: %s %s", member.FirstName, member.LastName),
+		}
+
+		var start time.Duration
+		var lines []string
+		for _, entry := range entriesByDay[day] {
+			if t, err := time.Parse("15:04", entry.BandStartTime); err == nil {
+				bandStart := time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+				if start == 0 || bandStart < start {
+					start = bandStart
+				}
+			}
+			lines = append(lines, calendarEntryLine(entry, language))
+		}
+		event.Description = strings.Join(lines, "\n")
+
+		if start == 0 {
+			event.AllDay = true
+			event.Start = date
+			event.End = date.AddDate(0, 0, 1)
+		} else {
+			event.Start = date.Add(start)
+			event.End = date.Add(tournamentDayEnd)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func calendarEntryLine(entry ListMembersEntry, language string) string {
+	band := entry.BandName
+	if entry.BandStartTime != "" {
+		band = fmt.Sprintf("%s (%s)", entry.BandName, entry.BandStartTime)
+	}
+
+	waitingListRank := emails.Entry{Rank: entry.BandRank, MaxEntries: entry.BandMaxEntries}.WaitingListRank()
+	if emails.Language(language) == emails.Language_EN {
+		if waitingListRank > 0 {
+			return fmt.Sprintf("Band %s: waiting list #%d", band, waitingListRank)
+		}
+		return fmt.Sprintf("Band %s: rank %d", band, entry.BandRank)
+	}
+	if waitingListRank > 0 {
+		return fmt.Sprintf("Tableau %s : liste d'attente n°%d", band, waitingListRank)
+	}
+	return fmt.Sprintf("Tableau %s : rang %d", band, entry.BandRank)
+}
+
+// memberCalendarAttachment returns the events of the member as an attachment of its confirmation email
+func memberCalendarAttachment(member models.Member, entries []ListMembersEntry, language string) (*mailer.Attachment, error) {
+	startDate, err := tournamentStartDate()
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := ical.Calendar{
+		ProdID: calendarProdID,
+		Method: "PUBLISH",
+		Events: memberEvents(member, entries, startDate, language),
+	}
+	return &mailer.Attachment{
+		Filename:    "tournoi.ics",
+		ContentType: "text/calendar; charset=utf-8; method=PUBLISH",
+		Data:        calendar.Bytes(time.Now()),
+	}, nil
+}
+
+// GetCalendarToken returns the URL of the calendar feed of the current user, creating its token if needed
+func (api *API) GetCalendarToken(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if !user.CalendarToken.Valid {
+		api.resetCalendarToken(ctx, user)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"url": calendarURL(user.CalendarToken.UUID)})
+}
+
+// ResetCalendarToken revokes the calendar feed URL of the current user and returns a new one
+func (api *API) ResetCalendarToken(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	api.resetCalendarToken(ctx, user)
+}
+
+func (api *API) resetCalendarToken(ctx *gin.Context, user *models.User) {
+	token := uuid.New()
+	if err := api.db.Model(user).Update("calendar_token", token).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to update user: %w", err))
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"url": calendarURL(token)})
+}
+
+// calendarURL is served by the API on the host of EXTERNAL_URL, which may include the path of the frontend
+func calendarURL(token uuid.UUID) string {
+	externalURL, err := url.Parse(os.Getenv("EXTERNAL_URL"))
+	if err != nil {
+		externalURL = &url.URL{}
+	}
+	feedURL := url.URL{
+		Scheme:   externalURL.Scheme,
+		Host:     externalURL.Host,
+		Path:     "/api/calendar.ics",
+		RawQuery: url.Values{"token": {token.String()}}.Encode(),
+	}
+	return feedURL.String()
+}
+
+// GetCalendar is the calendar feed of the user owning the token, with the entries of all its members
+func (api *API) GetCalendar(ctx *gin.Context) {
+	token, err := uuid.Parse(ctx.Query("token"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusUnauthorized, fmt.Errorf("invalid calendar token"))
+		return
+	}
+
+	var user models.User
+	err = api.db.Where("calendar_token = ?", token).First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.AbortWithError(http.StatusUnauthorized, fmt.Errorf("invalid calendar token"))
+			return
+		}
+
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get user: %w", err))
+		return
+	}
+
+	startDate, err := tournamentStartDate()
+	if err != nil {
+		ctx.AbortWithError(http.StatusServiceUnavailable, err)
+		return
+	}
+
+	var members []models.Member
+	if err = api.db.Where("user_id = ?", user.ID).Order("created_at ASC").Find(&members).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list members: %w", err))
+		return
+	}
+
+	calendar := ical.Calendar{
+		ProdID: calendarProdID,
+		Name:   "Tournoi de Lognes",
+	}
+	for _, member := range members {
+		entries, err := listMemberEntries(api.db, member.ID)
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list member entries: %w", err))
+			return
+		}
+		calendar.Events = append(calendar.Events, memberEvents(member, entries, startDate, user.Language)...)
+	}
+
+	ctx.Data(http.StatusOK, "text/calendar; charset=utf-8", calendar.Bytes(time.Now()))
+}
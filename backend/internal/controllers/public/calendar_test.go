@@ -0,0 +1,80 @@
+package public
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemberEvents(t *testing.T) {
+	paris, err := time.LoadLocation(tournamentTimezone)
+	require.NoError(t, err)
+	startDate := time.Date(2024, 6, 8, 0, 0, 0, 0, paris)
+
+	member := models.Member{ID: uuid.New(), FirstName: "John", LastName: "Doe"}
+	entries := []ListMembersEntry{
+		{BandName: "C", BandDay: 1, BandStartTime: "13:30", BandRank: 3, BandMaxEntries: 72},
+		{BandName: "A", BandDay: 1, BandStartTime: "09:00", BandRank: 75, BandMaxEntries: 72},
+		{BandName: "E", BandDay: 2, BandRank: 1, BandMaxEntries: 72},
+	}
+
+	events := memberEvents(member, entries, startDate, "fr")
+
+	require.Len(t, events, 2)
+	require.Equal(t, fmt.Sprintf("%s-day1@tournoi", member.ID), events[0].UID)
+	require.False(t, events[0].AllDay)
+	require.Equal(t, time.Date(2024, 6, 8, 9, 0, 0, 0, paris), events[0].Start)
+	require.Equal(t, time.Date(2024, 6, 8, 20, 0, 0, 0, paris), events[0].End)
+	require.Equal(t, "Tableau C (13:30) : rang 3\nTableau A (09:00) : liste d'attente n°3", events[0].Description)
+	// No start time is known for the second day
+	require.True(t, events[1].AllDay)
+	require.Equal(t, time.Date(2024, 6, 9, 0, 0, 0, 0, paris), events[1].Start)
+
+	events = memberEvents(member, entries, startDate, "en")
+	require.Equal(t, "Band E: rank 1", events[1].Description)
+}
+
+func TestGetCalendar(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		band := models.Band{Name: "S", Day: 2, MaxEntries: 10, StartTime: "10:00"}
+		require.NoError(t, env.db.Create(&band).Error)
+		member := models.Member{FirstName: "John", LastName: "Doe", Sex: "M", PermitID: "000000", UserID: env.user.ID}
+		require.NoError(t, env.db.Create(&member).Error)
+		require.NoError(t, env.db.Create(&models.Entry{MemberID: member.ID, BandID: band.ID, Confirmed: true}).Error)
+
+		res := performRequest("GET", "/api/calendar-token", nil, map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		var user models.User
+		require.NoError(t, env.db.First(&user, env.user.ID).Error)
+		require.True(t, user.CalendarToken.Valid)
+		require.Contains(t, res.Body.String(), "https://tournoi.example.com/api/calendar.ics?token="+user.CalendarToken.UUID.String())
+
+		res = performRequest("GET", "/api/calendar.ics?token="+user.CalendarToken.UUID.String(), nil, nil, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		require.Equal(t, "text/calendar; charset=utf-8", res.Header().Get("Content-Type"))
+		require.Equal(t, 1, strings.Count(res.Body.String(), "BEGIN:VEVENT"))
+		// 10:00 in Paris is 08:00 UTC in summer
+		require.Contains(t, res.Body.String(), "DTSTART:20240609T080000Z")
+	})
+	t.Run("InvalidToken", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		res := performRequest("GET", "/api/calendar.ics?token="+uuid.NewString(), nil, nil, env.api.router)
+
+		require.Equal(t, http.StatusUnauthorized, res.Code)
+	})
+}
@@ -0,0 +1,256 @@
+package public
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/SuperPingPong/tournoi/internal/campaigns"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CreateCampaignInput struct {
+	Name           string `binding:"required"`
+	Subject        string `binding:"required"`
+	HTML           string `binding:"required"`
+	Text           string
+	Segment        string `binding:"required"`
+	SegmentBandID  uuid.NullUUID
+	SegmentEdition string
+
+	AttachmentName        string
+	AttachmentContentType string
+	// Attachment is base64 encoded in JSON
+	Attachment []byte
+}
+
+func (api *API) CreateCampaign(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var input CreateCampaignInput
+	err = ctx.ShouldBindBodyWith(&input, binding.JSON)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
+		return
+	}
+
+	campaign := models.Campaign{
+		Name:                  input.Name,
+		Subject:               input.Subject,
+		HTML:                  input.HTML,
+		Text:                  input.Text,
+		Segment:               input.Segment,
+		SegmentBandID:         input.SegmentBandID,
+		SegmentEdition:        input.SegmentEdition,
+		AttachmentName:        input.AttachmentName,
+		AttachmentContentType: input.AttachmentContentType,
+		Attachment:            input.Attachment,
+		Status:                models.CampaignStatus_DRAFT,
+		CreatedBy:             uuid.NullUUID{UUID: user.ID, Valid: true},
+	}
+	if len(campaign.Attachment) > 0 && campaign.AttachmentName == "" {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: attachment requires a name"))
+		return
+	}
+	if len(campaign.Attachment) > 0 && campaign.AttachmentContentType == "" {
+		campaign.AttachmentContentType = http.DetectContentType(campaign.Attachment)
+	}
+	if err = campaigns.Validate(&campaign); err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid campaign: %w", err))
+		return
+	}
+
+	if err = api.db.Create(&campaign).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to create campaign: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, &campaign)
+}
+
+func (api *API) ListCampaigns(ctx *gin.Context) {
+	campaignList := []models.Campaign{}
+	if err := api.db.
+		Omit("html", "text", "attachment").
+		Order("created_at DESC").
+		Find(&campaignList).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list campaigns: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"campaigns": campaignList})
+}
+
+func (api *API) getCampaign(ctx *gin.Context) (*models.Campaign, bool) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid campaign id: %s", ctx.Param("id")))
+		return nil, false
+	}
+
+	var campaign models.Campaign
+	err = api.db.First(&campaign, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("campaign %s not found", id))
+			return nil, false
+		}
+
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get campaign: %w", err))
+		return nil, false
+	}
+	return &campaign, true
+}
+
+type CampaignRecipientsCount struct {
+	Status string
+	Count  int
+}
+
+// GetCampaign returns the campaign along with the number of recipients by status
+func (api *API) GetCampaign(ctx *gin.Context) {
+	campaign, ok := api.getCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	counts := []CampaignRecipientsCount{}
+	if err := api.db.Model(&models.CampaignRecipient{}).
+		Select("status, COUNT(*) AS count").
+		Where("campaign_id = ?", campaign.ID).
+		Group("status").
+		Scan(&counts).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to count recipients: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"campaign": campaign, "recipients": counts})
+}
+
+// PreviewCampaign returns the recipients targeted by the segment and the email as sent to the first one
+func (api *API) PreviewCampaign(ctx *gin.Context) {
+	campaign, ok := api.getCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	recipients, err := campaigns.Recipients(api.db, campaign)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var to string
+	if len(recipients) > 0 {
+		to = recipients[0]
+	}
+	message, err := campaigns.Render(campaign, os.Getenv("EXTERNAL_URL"), to)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"count":      len(recipients),
+		"recipients": recipients,
+		"subject":    message.Subject,
+		"html":       message.HTML,
+		"text":       message.Text,
+	})
+}
+
+// SendCampaign starts or resumes the sending of the campaign, recipients already sent are skipped
+func (api *API) SendCampaign(ctx *gin.Context) {
+	campaign, ok := api.getCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	retryFailed := ctx.Query("retry_failed") == "true"
+	if err := campaigns.Start(api.db, campaign, retryFailed); err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to start campaign: %w", err))
+		return
+	}
+	api.campaigns.Wake()
+
+	ctx.JSON(http.StatusAccepted, campaign)
+}
+
+// ListCampaignRecipients returns the send log of the campaign
+func (api *API) ListCampaignRecipients(ctx *gin.Context) {
+	campaign, ok := api.getCampaign(ctx)
+	if !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid page: %s", ctx.Query("page")))
+		return
+	}
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "100"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid page size: %s", ctx.Query("page_size")))
+		return
+	}
+
+	query := api.db.Where("campaign_id = ?", campaign.ID)
+	if status := ctx.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	recipients := []models.CampaignRecipient{}
+	if err = query.
+		Scopes(Paginate(page, pageSize)).
+		Order("email ASC").
+		Find(&recipients).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list recipients: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"recipients": recipients})
+}
+
+type ImportPastParticipantsInput struct {
+	Edition string   `binding:"required"`
+	Emails  []string `binding:"required"`
+}
+
+// ImportPastParticipants records the participants of a past edition, typically extracted from its database backup
+func (api *API) ImportPastParticipants(ctx *gin.Context) {
+	var input ImportPastParticipantsInput
+	err := ctx.ShouldBindBodyWith(&input, binding.JSON)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
+		return
+	}
+
+	emails := lo.Uniq(lo.FilterMap(input.Emails, func(email string, _ int) (string, bool) {
+		email = strings.ToLower(strings.TrimSpace(email))
+		return email, email != ""
+	}))
+	participants := lo.Map(emails, func(email string, _ int) models.PastParticipant {
+		return models.PastParticipant{Edition: input.Edition, Email: email}
+	})
+
+	result := api.db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(&participants, 500)
+	if result.Error != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to import participants: %w", result.Error))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"imported": result.RowsAffected})
+}
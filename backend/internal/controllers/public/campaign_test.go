@@ -0,0 +1,176 @@
+package public
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendCampaign(t *testing.T) {
+	setup := func(t *testing.T, env testEnv) models.Band {
+		band := models.Band{Name: "S", Day: 1, MaxEntries: 1}
+		require.NoError(t, env.db.Create(&band).Error)
+
+		otherUser := models.User{Email: "hdupont@example.com"}
+		require.NoError(t, env.db.Create(&otherUser).Error)
+
+		members := []models.Member{
+			{FirstName: "John", LastName: "Doe", Sex: "M", PermitID: "000000", UserID: env.user.ID},
+			{FirstName: "Hervé", LastName: "Dupont", Sex: "M", PermitID: "000001", UserID: otherUser.ID},
+		}
+		require.NoError(t, env.db.Create(&members).Error)
+		// The second member is on the waiting list
+		require.NoError(t, env.db.Create(&models.Entry{MemberID: members[0].ID, BandID: band.ID, Confirmed: true}).Error)
+		require.NoError(t, env.db.Create(&models.Entry{MemberID: members[1].ID, BandID: band.ID, Confirmed: true}).Error)
+		return band
+	}
+	t.Run("Success", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		setup(t, env)
+
+		body, err := json.Marshal(map[string]interface{}{
+			"Name":           "Rappel",
+			"Subject":        "Information importante: Tournoi de Lognes",
+			"HTML":           `<a href="{{.ExternalURL}}">Gérer mes inscriptions</a>`,
+			"Segment":        models.CampaignSegment_CONFIRMED,
+			"AttachmentName": "buvette.txt",
+			"Attachment":     []byte("Menu"),
+		})
+		require.NoError(t, err)
+
+		res := performRequest("POST", "/api/admin/campaigns", bytes.NewBuffer(body), map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusCreated, res.Code)
+		var campaign models.Campaign
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&campaign))
+		require.Equal(t, models.CampaignStatus_DRAFT, campaign.Status)
+
+		res = performRequest("GET", fmt.Sprintf("/api/admin/campaigns/%s/preview", campaign.ID), nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		var preview struct {
+			Count      int
+			Recipients []string
+			HTML       string
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&preview))
+		require.Equal(t, 2, preview.Count)
+		require.ElementsMatch(t, []string{env.user.Email, "hdupont@example.com"}, preview.Recipients)
+		require.Contains(t, preview.HTML, "https://tournoi.example.com")
+
+		res = performRequest("POST", fmt.Sprintf("/api/admin/campaigns/%s/send", campaign.ID), nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+		require.Equal(t, http.StatusAccepted, res.Code)
+
+		// Send to the first recipient, then resume the campaign
+		sent, err := env.api.campaigns.SendNext()
+		require.NoError(t, err)
+		require.True(t, sent)
+		res = performRequest("POST", fmt.Sprintf("/api/admin/campaigns/%s/send", campaign.ID), nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+		require.Equal(t, http.StatusAccepted, res.Code)
+
+		for {
+			sent, err = env.api.campaigns.SendNext()
+			require.NoError(t, err)
+			if !sent {
+				break
+			}
+		}
+
+		messages := env.mailer.Sent()
+		require.Len(t, messages, 2)
+		require.ElementsMatch(t, []string{env.user.Email, "hdupont@example.com"}, []string{messages[0].To, messages[1].To})
+		require.Len(t, messages[0].Attachments, 1)
+		require.Equal(t, "buvette.txt", messages[0].Attachments[0].Filename)
+
+		var recipients []models.CampaignRecipient
+		require.NoError(t, env.db.Where(&models.CampaignRecipient{CampaignID: campaign.ID}).Find(&recipients).Error)
+		require.Len(t, recipients, 2)
+		for _, recipient := range recipients {
+			require.Equal(t, models.CampaignRecipientStatus_SENT, recipient.Status)
+		}
+		require.NoError(t, env.db.First(&campaign, campaign.ID).Error)
+		require.Equal(t, models.CampaignStatus_DONE, campaign.Status)
+	})
+	t.Run("WaitingList", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		band := setup(t, env)
+
+		campaign := models.Campaign{
+			Name:          "Liste d'attente",
+			Subject:       "Liste d'attente",
+			HTML:          "<p>Liste d'attente</p>",
+			Segment:       models.CampaignSegment_WAITING_LIST,
+			SegmentBandID: uuid.NullUUID{UUID: band.ID, Valid: true},
+			Status:        models.CampaignStatus_DRAFT,
+		}
+		require.NoError(t, env.db.Create(&campaign).Error)
+
+		res := performRequest("GET", fmt.Sprintf("/api/admin/campaigns/%s/preview", campaign.ID), nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		var preview struct {
+			Recipients []string
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&preview))
+		require.Equal(t, []string{"hdupont@example.com"}, preview.Recipients)
+	})
+	t.Run("PreviousEdition", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		setup(t, env)
+
+		body, err := json.Marshal(map[string]interface{}{
+			"Edition": "2023",
+			"Emails":  []string{"HDupont@example.com", "mmartin@example.com", "mmartin@example.com"},
+		})
+		require.NoError(t, err)
+
+		res := performRequest("POST", "/api/admin/past-participants", bytes.NewBuffer(body), map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		campaign := models.Campaign{
+			Name:           "Ouverture",
+			Subject:        "Ouverture des inscriptions",
+			HTML:           "<p>Ouverture</p>",
+			Segment:        models.CampaignSegment_PREVIOUS_EDITION,
+			SegmentEdition: "2023",
+			Status:         models.CampaignStatus_DRAFT,
+		}
+		require.NoError(t, env.db.Create(&campaign).Error)
+
+		res = performRequest("GET", fmt.Sprintf("/api/admin/campaigns/%s/preview", campaign.ID), nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		var preview struct {
+			Recipients []string
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&preview))
+		// hdupont@example.com already registered this year
+		require.Equal(t, []string{"mmartin@example.com"}, preview.Recipients)
+	})
+}
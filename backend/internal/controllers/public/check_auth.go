@@ -32,5 +32,5 @@ func (api *API) CheckAuth(ctx *gin.Context) {
 	if user.(*models.User).Email == input.Email {
 		valid = true
 	}
-	ctx.JSON(http.StatusOK, gin.H{"valid": valid})
+	ctx.JSON(http.StatusOK, gin.H{"valid": valid, "impersonated": ExtractImpersonatorFromContext(ctx).Valid})
 }
@@ -0,0 +1,405 @@
+package public
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CheckInEntry is a confirmed entry of a band with its rank and check in status, empty when the player is not checked yet
+type CheckInEntry struct {
+	EntryID   uuid.UUID
+	MemberID  uuid.UUID
+	PermitID  string
+	FirstName string
+	LastName  string
+	ClubName  string
+	Points    float64
+	Rank      int
+	// Waiting is set on the entries of the waiting list
+	Waiting  bool
+	Status   string
+	Promoted bool
+}
+
+type CheckInSummary struct {
+	BandID     uuid.UUID
+	BandName   string
+	BandDay    int
+	MaxEntries int
+	// The statuses of the main draw
+	Present          int
+	Late             int
+	AbsentWithNotice int
+	Scratched        int
+	Unchecked        int
+	// WaitingPresent counts the players of the waiting list who are present and not promoted yet
+	WaitingPresent int
+	Promoted       int
+	// FreePlaces are the places of the main draw released by absent players and not taken by promoted ones
+	FreePlaces int
+}
+
+// checkInEntriesQuery lists the confirmed entries of a band in rank order, with their check in
+const checkInEntriesQuery = `
+	SELECT
+	  entries.id AS entry_id,
+	  members.id AS member_id,
+	  members.permit_id,
+	  members.first_name,
+	  members.last_name,
+	  members.club_name,
+	  members.points,
+	  ROW_NUMBER() OVER (ORDER BY entries.created_at ASC) AS rank,
+	  ROW_NUMBER() OVER (ORDER BY entries.created_at ASC) > bands.max_entries AS waiting,
+	  COALESCE(check_ins.status, '') AS status,
+	  COALESCE(check_ins.promoted, FALSE) AS promoted
+	FROM entries
+	JOIN members ON members.id = entries.member_id AND members.deleted_at IS NULL
+	JOIN bands ON bands.id = entries.band_id
+	LEFT JOIN check_ins ON check_ins.entry_id = entries.id
+	WHERE entries.band_id = ? AND entries.confirmed IS TRUE AND entries.deleted_at IS NULL
+	ORDER BY rank ASC
+`
+
+func listCheckInEntries(db *gorm.DB, bandID uuid.UUID) ([]CheckInEntry, error) {
+	entries := []CheckInEntry{}
+	if err := db.Raw(checkInEntriesQuery, bandID).Scan(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list check ins of band %s: %w", bandID, err)
+	}
+	return entries, nil
+}
+
+// holdsPlace tells whether the entry holds a place of the main draw
+func holdsPlace(entry CheckInEntry) bool {
+	if entry.Waiting && !entry.Promoted {
+		return false
+	}
+	return entry.Status != models.CheckInStatus_ABSENT_WITH_NOTICE && entry.Status != models.CheckInStatus_SCRATCHED
+}
+
+func summarizeCheckIns(band models.Band, entries []CheckInEntry) CheckInSummary {
+	summary := CheckInSummary{
+		BandID:     band.ID,
+		BandName:   band.Name,
+		BandDay:    band.Day,
+		MaxEntries: band.MaxEntries,
+	}
+
+	heldPlaces := 0
+	for _, entry := range entries {
+		if holdsPlace(entry) {
+			heldPlaces++
+		}
+		if entry.Waiting {
+			if entry.Promoted {
+				summary.Promoted++
+			} else if entry.Status == models.CheckInStatus_PRESENT {
+				summary.WaitingPresent++
+			}
+			continue
+		}
+
+		switch entry.Status {
+		case models.CheckInStatus_PRESENT:
+			summary.Present++
+		case models.CheckInStatus_LATE:
+			summary.Late++
+		case models.CheckInStatus_ABSENT_WITH_NOTICE:
+			summary.AbsentWithNotice++
+		case models.CheckInStatus_SCRATCHED:
+			summary.Scratched++
+		default:
+			summary.Unchecked++
+		}
+	}
+	summary.FreePlaces = lo.Max([]int{band.MaxEntries - heldPlaces, 0})
+	return summary
+}
+
+// deadlineChanges returns the entries of the main draw to scratch since they are not checked in,
+// and the present entries of the waiting list to promote, in rank order, to the places freed
+func deadlineChanges(band models.Band, entries []CheckInEntry) (scratched []uuid.UUID, promoted []uuid.UUID) {
+	heldPlaces := 0
+	for _, entry := range entries {
+		if !entry.Waiting && entry.Status == "" {
+			scratched = append(scratched, entry.EntryID)
+			continue
+		}
+		if holdsPlace(entry) {
+			heldPlaces++
+		}
+	}
+
+	for _, entry := range entries {
+		if heldPlaces >= band.MaxEntries {
+			break
+		}
+		if entry.Waiting && !entry.Promoted && entry.Status == models.CheckInStatus_PRESENT {
+			promoted = append(promoted, entry.EntryID)
+			heldPlaces++
+		}
+	}
+	return scratched, promoted
+}
+
+// upsertCheckIn records the status of the confirmed entry, promoted entries stay promoted.
+// The band is locked in share mode so that the check in waits for a deadline being applied to the band.
+func upsertCheckIn(tx *gorm.DB, checkIn models.CheckIn) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).First(&models.Band{}, checkIn.BandID).Error; err != nil {
+			return fmt.Errorf("failed to lock band: %w", err)
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "entry_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"status", "checked_by", "updated_at"}),
+		}).Create(&checkIn).Error
+	})
+}
+
+func (api *API) getBand(ctx *gin.Context) (models.Band, bool) {
+	var band models.Band
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid band id: %s", ctx.Param("id")))
+		return band, false
+	}
+	if err = api.db.First(&band, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("band %s not found", id))
+			return band, false
+		}
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get band: %w", err))
+		return band, false
+	}
+	return band, true
+}
+
+// ListBandCheckIns lists the players of a band, main draw then waiting list, with their check in
+func (api *API) ListBandCheckIns(ctx *gin.Context) {
+	band, ok := api.getBand(ctx)
+	if !ok {
+		return
+	}
+
+	entries, err := listCheckInEntries(api.db, band.ID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"entries": entries, "summary": summarizeCheckIns(band, entries)})
+}
+
+// GetCheckInSummary counts the present, absent players and free places of every band, of a day with ?day=
+func (api *API) GetCheckInSummary(ctx *gin.Context) {
+	query := api.db.Order("day ASC, created_at ASC")
+	if value := ctx.Query("day"); value != "" {
+		day, err := strconv.Atoi(value)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid day: %s", value))
+			return
+		}
+		query = query.Where("day = ?", day)
+	}
+	var bands []models.Band
+	if err := query.Find(&bands).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list bands: %w", err))
+		return
+	}
+
+	summaries := []CheckInSummary{}
+	for _, band := range bands {
+		entries, err := listCheckInEntries(api.db, band.ID)
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		summaries = append(summaries, summarizeCheckIns(band, entries))
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"bands": summaries})
+}
+
+type CheckInInput struct {
+	Status string `binding:"required,oneof=present late absent_with_notice"`
+}
+
+// SetEntryCheckIn sets the status of a confirmed entry
+func (api *API) SetEntryCheckIn(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid entry id: %s", ctx.Param("id")))
+		return
+	}
+	var input CheckInInput
+	if err = ctx.ShouldBindBodyWith(&input, binding.JSON); err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
+		return
+	}
+
+	var entry models.Entry
+	if err = api.db.Where("confirmed IS TRUE").First(&entry, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("entry %s not found", id))
+			return
+		}
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get entry: %w", err))
+		return
+	}
+
+	checkIn := models.CheckIn{
+		EntryID:   entry.ID,
+		MemberID:  entry.MemberID,
+		BandID:    entry.BandID,
+		Status:    input.Status,
+		CheckedBy: uuid.NullUUID{UUID: user.ID, Valid: true},
+	}
+	if err = upsertCheckIn(api.db, checkIn); err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to check in entry: %w", err))
+		return
+	}
+
+	ctx.Status(http.StatusOK)
+}
+
+type CheckInMemberInput struct {
+	Day    int    `binding:"required,min=1"`
+	Status string `binding:"required,oneof=present late absent_with_notice"`
+}
+
+// SetMemberCheckIn sets the status of all the confirmed entries of a member on a day, as players check in once per day
+func (api *API) SetMemberCheckIn(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid member id: %s", ctx.Param("id")))
+		return
+	}
+	var input CheckInMemberInput
+	if err = ctx.ShouldBindBodyWith(&input, binding.JSON); err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
+		return
+	}
+
+	var entries []models.Entry
+	if err = api.db.
+		Joins("JOIN bands ON bands.id = entries.band_id").
+		Where("entries.member_id = ? AND entries.confirmed IS TRUE AND bands.day = ?", id, input.Day).
+		Find(&entries).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list member entries: %w", err))
+		return
+	}
+	if len(entries) == 0 {
+		ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("no entries of member %s on day %d", id, input.Day))
+		return
+	}
+
+	err = api.db.Transaction(func(tx *gorm.DB) error {
+		for _, entry := range entries {
+			if err := upsertCheckIn(tx, models.CheckIn{
+				EntryID:   entry.ID,
+				MemberID:  entry.MemberID,
+				BandID:    entry.BandID,
+				Status:    input.Status,
+				CheckedBy: uuid.NullUUID{UUID: user.ID, Valid: true},
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to check in member: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"checked": len(entries)})
+}
+
+// CloseBandCheckIn applies the deadline of a band: the players of the main draw who are not checked in are scratched,
+// and the present players of the waiting list take their places in rank order. It can be run again for late changes.
+func (api *API) CloseBandCheckIn(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	band, ok := api.getBand(ctx)
+	if !ok {
+		return
+	}
+
+	var scratched, promoted []uuid.UUID
+	err = api.db.Transaction(func(tx *gorm.DB) error {
+		// Concurrent check ins of the band wait for the deadline to be applied, see upsertCheckIn
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Band{}, band.ID).Error; err != nil {
+			return fmt.Errorf("failed to lock band: %w", err)
+		}
+		bandEntries, err := listCheckInEntries(tx, band.ID)
+		if err != nil {
+			return err
+		}
+		entriesByID := lo.KeyBy(bandEntries, func(entry CheckInEntry) uuid.UUID {
+			return entry.EntryID
+		})
+
+		scratched, promoted = deadlineChanges(band, bandEntries)
+		for _, entryID := range scratched {
+			if err := upsertCheckIn(tx, models.CheckIn{
+				EntryID:   entryID,
+				MemberID:  entriesByID[entryID].MemberID,
+				BandID:    band.ID,
+				Status:    models.CheckInStatus_SCRATCHED,
+				CheckedBy: uuid.NullUUID{UUID: user.ID, Valid: true},
+			}); err != nil {
+				return fmt.Errorf("failed to scratch entry: %w", err)
+			}
+		}
+		if len(promoted) > 0 {
+			if err := tx.Model(&models.CheckIn{}).Where("entry_id IN ?", promoted).Update("promoted", true).Error; err != nil {
+				return fmt.Errorf("failed to promote entries: %w", err)
+			}
+		}
+		if err := tx.Model(&band).Update("check_in_closed_at", time.Now()).Error; err != nil {
+			return fmt.Errorf("failed to close band check in: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to close check in: %w", err))
+		return
+	}
+
+	entries, err := listCheckInEntries(api.db, band.ID)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"scratched": len(scratched),
+		"promoted":  len(promoted),
+		"entries":   entries,
+		"summary":   summarizeCheckIns(band, entries),
+	})
+}
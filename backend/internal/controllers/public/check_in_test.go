@@ -0,0 +1,122 @@
+package public
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadlineChanges(t *testing.T) {
+	band := models.Band{ID: uuid.New(), Name: "A", MaxEntries: 3}
+	entries := []CheckInEntry{
+		{EntryID: uuid.New(), Rank: 1, Status: models.CheckInStatus_PRESENT},
+		{EntryID: uuid.New(), Rank: 2},
+		{EntryID: uuid.New(), Rank: 3, Status: models.CheckInStatus_ABSENT_WITH_NOTICE},
+		{EntryID: uuid.New(), Rank: 4, Waiting: true},
+		{EntryID: uuid.New(), Rank: 5, Waiting: true, Status: models.CheckInStatus_PRESENT},
+		{EntryID: uuid.New(), Rank: 6, Waiting: true, Status: models.CheckInStatus_PRESENT},
+		{EntryID: uuid.New(), Rank: 7, Waiting: true, Status: models.CheckInStatus_PRESENT},
+	}
+
+	summary := summarizeCheckIns(band, entries)
+	require.Equal(t, CheckInSummary{
+		BandID:           band.ID,
+		BandName:         "A",
+		MaxEntries:       3,
+		Present:          1,
+		AbsentWithNotice: 1,
+		Unchecked:        1,
+		WaitingPresent:   3,
+		FreePlaces:       1,
+	}, summary)
+
+	scratched, promoted := deadlineChanges(band, entries)
+	require.Equal(t, []uuid.UUID{entries[1].EntryID}, scratched)
+	// The waiting list is followed in rank order, skipping the players who are not there
+	require.Equal(t, []uuid.UUID{entries[4].EntryID, entries[5].EntryID}, promoted)
+
+	// Late players keep their place
+	entries[1].Status = models.CheckInStatus_LATE
+	scratched, promoted = deadlineChanges(band, entries)
+	require.Empty(t, scratched)
+	require.Equal(t, []uuid.UUID{entries[4].EntryID}, promoted)
+}
+
+func TestCheckIn(t *testing.T) {
+	env := getTestEnv(t)
+	defer env.teardown()
+
+	band := models.Band{Name: "A", Day: 1, Color: models.BandColor_PINK, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 2}
+	require.NoError(t, env.db.Create(&band).Error)
+	var members []models.Member
+	var entries []models.Entry
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		member := models.Member{FirstName: "Jean", LastName: fmt.Sprintf("Pierre %d", i), Sex: "M", Points: 580, Category: "S", PermitID: fmt.Sprintf("12345%d", i), UserID: env.user.ID}
+		require.NoError(t, env.db.Create(&member).Error)
+		entry := models.Entry{MemberID: member.ID, BandID: band.ID, Confirmed: true, CreatedAt: now.Add(time.Duration(i) * time.Minute)}
+		require.NoError(t, env.db.Create(&entry).Error)
+		members = append(members, member)
+		entries = append(entries, entry)
+	}
+
+	res := performRequest("PUT", fmt.Sprintf("/api/admin/check-in/entries/%s", entries[0].ID), strings.NewReader(`{"Status":"scratched"}`), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = performRequest("PUT", fmt.Sprintf("/api/admin/check-in/entries/%s", entries[0].ID), strings.NewReader(`{"Status":"present"}`), map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusForbidden, res.Code)
+
+	res = performRequest("PUT", fmt.Sprintf("/api/admin/check-in/entries/%s", entries[0].ID), strings.NewReader(`{"Status":"present"}`), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	res = performRequest("POST", fmt.Sprintf("/api/admin/check-in/members/%s", members[3].ID), strings.NewReader(`{"Day":1,"Status":"present"}`), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	res = performRequest("POST", fmt.Sprintf("/api/admin/check-in/members/%s", members[3].ID), strings.NewReader(`{"Day":2,"Status":"present"}`), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusNotFound, res.Code)
+
+	res = performRequest("POST", fmt.Sprintf("/api/admin/check-in/bands/%s/deadline", band.ID), nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	var closed struct {
+		Scratched int
+		Promoted  int
+		Entries   []CheckInEntry
+		Summary   CheckInSummary
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&closed))
+	require.Equal(t, 1, closed.Scratched)
+	require.Equal(t, 1, closed.Promoted)
+	require.Equal(t, models.CheckInStatus_SCRATCHED, closed.Entries[1].Status)
+	require.True(t, closed.Entries[3].Promoted)
+	require.Equal(t, 0, closed.Summary.FreePlaces)
+
+	res = performRequest("GET", "/api/admin/check-in/summary?day=1", nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	var summary struct {
+		Bands []CheckInSummary
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&summary))
+	require.Len(t, summary.Bands, 1)
+	require.Equal(t, 1, summary.Bands[0].Present)
+	require.Equal(t, 1, summary.Bands[0].Scratched)
+	require.Equal(t, 1, summary.Bands[0].Promoted)
+}
@@ -0,0 +1,407 @@
+package public
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/emails"
+	"github.com/SuperPingPong/tournoi/internal/fftt"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/SuperPingPong/tournoi/internal/outbox"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+type SetClubManagerInput struct {
+	Email      string `binding:"required,email"`
+	ClubNumber string `binding:"required,min=2"`
+}
+
+func (api *API) ListClubManagers(ctx *gin.Context) {
+	var users []models.User
+	if err := api.db.Where("is_club_manager IS TRUE").Order("email ASC").Find(&users).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list club managers: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"users": users})
+}
+
+func (api *API) SetClubManager(ctx *gin.Context) {
+	var input SetClubManagerInput
+	err := ctx.ShouldBindBodyWith(&input, binding.JSON)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
+		return
+	}
+
+	// The user may not have logged in yet, in which case it is created beforehand
+	var user models.User
+	err = api.db.Where(models.User{Email: strings.ToLower(input.Email)}).FirstOrCreate(&user).Error
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get user: %w", err))
+		return
+	}
+
+	user.IsClubManager = true
+	user.ClubNumber = input.ClubNumber
+	if err = api.db.Select("is_club_manager", "club_number").Updates(&user).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to update user: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &user)
+}
+
+func (api *API) DeleteClubManager(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid user id: %s", ctx.Param("id")))
+		return
+	}
+
+	result := api.db.Model(&models.User{}).
+		Where("id = ? AND is_club_manager IS TRUE", id).
+		Select("is_club_manager", "club_number").
+		Updates(models.User{IsClubManager: false, ClubNumber: ""})
+	if result.Error != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to update user: %w", result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("club manager %s not found", id))
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+type ClubPlayer struct {
+	fftt.Player
+	MemberID          uuid.NullUUID
+	RegisteredByOther bool
+}
+
+func (api *API) ListClubPlayers(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	players, err := api.fftt.ListClubPlayers(ctx.Request.Context(), user.ClubNumber)
+	if err != nil {
+		ctx.AbortWithError(ffttErrorStatus(err), fmt.Errorf("failed to list club players: %w", err))
+		return
+	}
+	api.rememberPlayers(players...)
+
+	search := strings.ToLower(ctx.Query("search"))
+	players = lo.Filter(players, func(player fftt.Player, _ int) bool {
+		return search == "" ||
+			strings.Contains(strings.ToLower(player.LastName), search) ||
+			strings.Contains(strings.ToLower(player.FirstName), search) ||
+			strings.Contains(player.PermitID, search)
+	})
+
+	// Flag the players which are already registered
+	var members []models.Member
+	permitIDs := lo.Map(players, func(player fftt.Player, _ int) string {
+		return player.PermitID
+	})
+	if err = api.db.Where("permit_id IN ?", append(permitIDs, "")).Find(&members).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list members: %w", err))
+		return
+	}
+	membersByPermitID := lo.KeyBy(members, func(member models.Member) string {
+		return member.PermitID
+	})
+
+	clubPlayers := []ClubPlayer{}
+	for _, player := range players {
+		clubPlayer := ClubPlayer{Player: player}
+		if member, ok := membersByPermitID[player.PermitID]; ok {
+			if member.UserID == user.ID {
+				clubPlayer.MemberID = uuid.NullUUID{UUID: member.ID, Valid: true}
+			} else {
+				clubPlayer.RegisteredByOther = true
+			}
+		}
+		clubPlayers = append(clubPlayers, clubPlayer)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"players": clubPlayers})
+}
+
+type ClubRegistrationInput struct {
+	PermitID string      `binding:"required,min=2"`
+	BandIDs  []uuid.UUID `binding:"required"`
+}
+
+type RegisterClubPlayersInput struct {
+	Registrations []ClubRegistrationInput `binding:"required,min=1,dive"`
+}
+
+type clubRegistration struct {
+	member models.Member
+	bands  []models.Band
+}
+
+// RegisterClubPlayers creates the members of the manager's club and sets their entries in a single transaction.
+// Each registration replaces the confirmed entries of the member, like SetMemberEntries does.
+func (api *API) RegisterClubPlayers(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var input RegisterClubPlayersInput
+	err = ctx.ShouldBindBodyWith(&input, binding.JSON)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
+		return
+	}
+
+	duplicates := lo.FindDuplicates(lo.Map(input.Registrations, func(r ClubRegistrationInput, _ int) string {
+		return r.PermitID
+	}))
+	if len(duplicates) > 0 {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("duplicated permits %v", duplicates))
+		return
+	}
+
+	clubPlayers, err := api.fftt.ListClubPlayers(ctx.Request.Context(), user.ClubNumber)
+	if err != nil {
+		ctx.AbortWithError(ffttErrorStatus(err), fmt.Errorf("failed to list club players: %w", err))
+		return
+	}
+	clubPermitIDs := lo.Map(clubPlayers, func(player fftt.Player, _ int) string {
+		return player.PermitID
+	})
+
+	// Validate every registration before writing anything
+	var registrations []clubRegistration
+	for _, registration := range input.Registrations {
+		if !lo.Contains(clubPermitIDs, registration.PermitID) {
+			ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("player %s not found in club %s", registration.PermitID, user.ClubNumber))
+			return
+		}
+
+		var member models.Member
+		err = api.db.Where("permit_id = ?", registration.PermitID).First(&member).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get member: %w", err))
+			return
+		}
+		if err == nil && member.UserID != user.ID {
+			ctx.AbortWithError(http.StatusConflict, fmt.Errorf("member with permit %s already exists", registration.PermitID))
+			return
+		}
+		if err == nil && member.PendingValidation && len(registration.BandIDs) > 0 {
+			ctx.AbortWithError(http.StatusConflict, fmt.Errorf("member with permit %s: %w", registration.PermitID, memberPendingValidationError))
+			return
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			data, err := api.fftt.GetPlayer(ctx.Request.Context(), registration.PermitID)
+			if err != nil {
+				ctx.AbortWithError(ffttErrorStatus(err), fmt.Errorf("failed to get member data from FFTT: %w", err))
+				return
+			}
+			if abortOnLicenceIssue(ctx, api.db, *data) {
+				return
+			}
+			member = models.Member{
+				UserID:     user.ID,
+				PermitID:   data.PermitID,
+				FirstName:  data.FirstName,
+				LastName:   data.LastName,
+				Sex:        data.Sex,
+				Points:     data.Points,
+				Category:   data.Category,
+				ClubName:   data.ClubName,
+				ClubNumber: user.ClubNumber,
+				PermitType: data.PermitType,
+			}
+		}
+
+		var bands []models.Band
+		if err = api.db.Scopes(possibleBandsScope(member)).Where("id IN ?", append(registration.BandIDs, uuid.Nil)).Find(&bands).Error; err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to find bands: %w", err))
+			return
+		}
+		if len(bands) != len(lo.Uniq(registration.BandIDs)) {
+			missingBands := lo.Filter(registration.BandIDs, func(bandID uuid.UUID, _ int) bool {
+				return !lo.Contains(mapBandIDs(bands), bandID)
+			})
+			ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("bands %v not found for permit %s", missingBands, registration.PermitID))
+			return
+		}
+		if err = enforceBandsLimit(bands); err != nil {
+			ctx.AbortWithError(http.StatusConflict, fmt.Errorf("permit %s: %w", registration.PermitID, err))
+			return
+		}
+
+		registrations = append(registrations, clubRegistration{member: member, bands: bands})
+	}
+
+	var members []ListMembersMember
+	impersonator := ExtractImpersonatorFromContext(ctx)
+	err = api.db.Transaction(func(tx *gorm.DB) error {
+		for i := range registrations {
+			member := &registrations[i].member
+			if member.ID == uuid.Nil {
+				if err := tx.Create(member).Error; err != nil {
+					return fmt.Errorf("failed to create member %s: %w", member.PermitID, err)
+				}
+			}
+
+			// Release the locks held by the member, they are not needed for bulk registrations
+			if err := tx.Where("member_id = ? AND confirmed IS FALSE", member.ID).Delete(&models.Entry{}).Error; err != nil {
+				return fmt.Errorf("failed to delete locked entries: %w", err)
+			}
+
+			bandIDs := append(mapBandIDs(registrations[i].bands), uuid.Nil)
+			if err := tx.
+				Where("member_id = ? AND band_id NOT IN ?", member.ID, bandIDs).
+				Updates(&models.Entry{
+					DeletedAt:             gorm.DeletedAt{Time: time.Now(), Valid: true},
+					DeletedBy:             uuid.NullUUID{UUID: user.ID, Valid: true},
+					DeletedByImpersonator: impersonator,
+				}).Error; err != nil {
+				return fmt.Errorf("failed to delete entry: %w", err)
+			}
+
+			var confirmedEntries []models.Entry
+			if err := tx.Where("member_id = ? AND confirmed IS TRUE", member.ID).Find(&confirmedEntries).Error; err != nil {
+				return fmt.Errorf("failed to list member entries: %w", err)
+			}
+			confirmedBandIDs := lo.Map(confirmedEntries, func(entry models.Entry, _ int) uuid.UUID {
+				return entry.BandID
+			})
+
+			sessionID := uuid.New()
+			for _, band := range registrations[i].bands {
+				if lo.Contains(confirmedBandIDs, band.ID) {
+					continue
+				}
+				if err := tx.Create(&models.Entry{
+					BandID:      band.ID,
+					MemberID:    member.ID,
+					ExpiresAt:   time.Now(),
+					Confirmed:   true,
+					SessionID:   sessionID,
+					CreatedBy:   uuid.NullUUID{UUID: user.ID, Valid: true},
+					ConfirmedBy: uuid.NullUUID{UUID: user.ID, Valid: true},
+
+					CreatedByImpersonator:   impersonator,
+					ConfirmedByImpersonator: impersonator,
+				}).Error; err != nil {
+					return fmt.Errorf("failed to create entry: %w", err)
+				}
+			}
+		}
+
+		rules, err := getPricingRules(tx)
+		if err != nil {
+			return err
+		}
+		for _, registration := range registrations {
+			member, err := buildListMembersMember(tx, rules, registration.member)
+			if err != nil {
+				return fmt.Errorf("failed to list member entries: %w", err)
+			}
+			members = append(members, member)
+		}
+
+		// A single email summarizes the whole operation
+		clubName := clubPlayers[0].ClubName
+		if registrations[0].member.ClubName != "" {
+			clubName = registrations[0].member.ClubName
+		}
+		message, err := api.renderEmail(user.Email, user.Language, emails.ClubRegistrations{
+			ClubName: clubName,
+			Members:  clubMembersEmail(members),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to build email: %w", err)
+		}
+		if err = outbox.Enqueue(tx, "club-registrations:"+uuid.NewString(), message); err != nil {
+			return fmt.Errorf("failed to enqueue email: %w", err)
+		}
+
+		// The consolidated email already tells the current entries of each member
+		for _, member := range members {
+			bandIDs := lo.Map(member.Entries, func(entry ListMembersEntry, _ int) uuid.UUID {
+				return entry.BandID
+			})
+			if err = markEntriesNotified(tx, member.ID, bandIDs); err != nil {
+				return fmt.Errorf("failed to update entries digest: %w", err)
+			}
+		}
+
+		memberIDs := lo.Map(registrations, func(r clubRegistration, _ int) uuid.UUID {
+			return r.member.ID
+		})
+		if err = tx.Model(models.Member{}).
+			Where("id IN ?", memberIDs).
+			Updates(models.Member{HasBeenNotified: true}).Error; err != nil {
+			return fmt.Errorf("failed to update members: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		// A member of the registrations was created concurrently since it was looked up
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			ctx.AbortWithError(http.StatusConflict, err)
+			return
+		}
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	api.outbox.Wake()
+
+	ctx.JSON(http.StatusOK, gin.H{"members": members})
+}
+
+// ListClubEntries lists the members of the manager's club, whoever registered them, with their entries
+func (api *API) ListClubEntries(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var members []models.Member
+	if err := api.db.
+		Where("club_number = ? OR user_id = ?", user.ClubNumber, user.ID).
+		Order("last_name ASC, first_name ASC").
+		Find(&members).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list members: %w", err))
+		return
+	}
+
+	rules, err := getPricingRules(api.db)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list member entries: %w", err))
+		return
+	}
+	result := []ListMembersMember{}
+	for _, member := range members {
+		listMember, err := buildListMembersMember(api.db, rules, member)
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list member entries: %w", err))
+			return
+		}
+		result = append(result, listMember)
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"members": result})
+}
@@ -0,0 +1,288 @@
+package public
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func mockFFTTClubPlayers(t *testing.T, env testEnv, clubNumber string, players string) {
+	expectedFFTTReq, err := http.NewRequest(http.MethodGet, "https://fftt.dafunker.com/v1//proxy/xml_liste_joueur_o.php?club="+clubNumber, nil)
+	require.NoError(t, err)
+	mockFFTTRes := fmt.Sprintf(`<?xml version="1.0" encoding="ISO-8859-1"?><liste>%s</liste>`, players)
+	env.httpClient.EXPECT().Do(expectedFFTTReq).Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewReader([]byte(mockFFTTRes))),
+	}, nil)
+}
+
+func TestSetClubManager(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		body, err := json.Marshal(map[string]string{"Email": env.user.Email, "ClubNumber": "08770047"})
+		require.NoError(t, err)
+
+		res := performRequest("POST", "/api/admin/club-managers", bytes.NewBuffer(body), map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		var user models.User
+		require.NoError(t, env.db.First(&user, env.user.ID).Error)
+		require.True(t, user.IsClubManager)
+		require.Equal(t, "08770047", user.ClubNumber)
+
+		// Revoke the role
+		res = performRequest("DELETE", "/api/admin/club-managers/"+user.ID.String(), nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusNoContent, res.Code)
+		require.NoError(t, env.db.First(&user, env.user.ID).Error)
+		require.False(t, user.IsClubManager)
+		require.Empty(t, user.ClubNumber)
+	})
+	t.Run("NotAdmin", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		body, err := json.Marshal(map[string]string{"Email": env.user.Email, "ClubNumber": "08770047"})
+		require.NoError(t, err)
+
+		res := performRequest("POST", "/api/admin/club-managers", bytes.NewBuffer(body), map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusForbidden, res.Code)
+	})
+}
+
+func TestListClubEntries(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		require.NoError(t, env.db.Model(env.user).Updates(models.User{IsClubManager: true, ClubNumber: "08770047"}).Error)
+
+		otherUser := models.User{Email: "hdupont@example.com"}
+		require.NoError(t, env.db.Create(&otherUser).Error)
+
+		band := models.Band{Name: "S", Day: 1, MaxEntries: 1}
+		require.NoError(t, env.db.Create(&band).Error)
+
+		members := []models.Member{
+			{
+				FirstName:  "John",
+				LastName:   "Doe",
+				Sex:        "M",
+				PermitID:   "000000",
+				ClubNumber: "08770047",
+				UserID:     otherUser.ID,
+			},
+			{
+				FirstName:  "Jane",
+				LastName:   "Doe",
+				Sex:        "F",
+				PermitID:   "000001",
+				ClubNumber: "08770048",
+				UserID:     otherUser.ID,
+			},
+		}
+		require.NoError(t, env.db.Create(&members).Error)
+		require.NoError(t, env.db.Create(&models.Entry{MemberID: members[0].ID, BandID: band.ID, Confirmed: true}).Error)
+
+		res := performRequest("GET", "/api/club/entries", nil, map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		var got struct {
+			Members []ListMembersMember
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		require.Len(t, got.Members, 1)
+		require.Equal(t, members[0].ID, got.Members[0].ID)
+		require.Len(t, got.Members[0].Entries, 1)
+		require.Equal(t, band.ID, got.Members[0].Entries[0].BandID)
+		require.Equal(t, 1, got.Members[0].Entries[0].BandRank)
+	})
+	t.Run("NotClubManager", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		res := performRequest("GET", "/api/club/entries", nil, map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusForbidden, res.Code)
+	})
+}
+
+func TestRegisterClubPlayers(t *testing.T) {
+	t.Run("PlayerNotInClub", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		require.NoError(t, env.db.Model(env.user).Updates(models.User{IsClubManager: true, ClubNumber: "08770047"}).Error)
+		mockFFTTClubPlayers(t, env, "08770047", `<joueur><licence>123456</licence><nom>PIERRE</nom><prenom>Jean</prenom><nclub>Caillouville</nclub><sexe>M</sexe><points>801</points></joueur>`)
+
+		body, err := json.Marshal(map[string]interface{}{
+			"Registrations": []map[string]interface{}{
+				{"PermitID": "654321", "BandIDs": []uuid.UUID{}},
+			},
+		})
+		require.NoError(t, err)
+
+		res := performRequest("POST", "/api/club/registrations", bytes.NewBuffer(body), map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		var actual map[string]string
+		require.Equal(t, http.StatusNotFound, res.Code)
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+		require.Equal(t, "player 654321 not found in club 08770047", actual["error"])
+	})
+	t.Run("Success", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		require.NoError(t, env.db.Model(env.user).Updates(models.User{IsClubManager: true, ClubNumber: "08770047"}).Error)
+		mockFFTTClubPlayers(t, env, "08770047", `<joueur><licence>123456</licence><nom>PIERRE</nom><prenom>Jean</prenom><nclub>Caillouville</nclub><sexe>M</sexe><points>801</points></joueur>`)
+
+		expectedFFTTReq, err := http.NewRequest(http.MethodGet, "https://fftt.dafunker.com/v1/joueur/123456", nil)
+		require.NoError(t, err)
+		mockFFTTRes := `{"nom":"PIERRE","prenom":"Jean","licence":"123456","sexe":"M","point":801,"cat":"S","nomclub":"Caillouville","type":"T"}`
+		env.httpClient.EXPECT().Do(expectedFFTTReq).Return(&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader([]byte(mockFFTTRes))),
+		}, nil)
+
+		bands := []models.Band{
+			{
+				Name:       "S",
+				Day:        1,
+				Color:      models.BandColor_BLUE,
+				SexAllowed: models.BandSex_ALL,
+				MaxEntries: 1,
+				MaxPoints:  999,
+			},
+			{
+				Name:       "T",
+				Day:        2,
+				Color:      models.BandColor_BLUE,
+				SexAllowed: models.BandSex_ALL,
+				MaxEntries: 1,
+				MaxPoints:  999,
+			},
+		}
+		require.NoError(t, env.db.Create(&bands).Error)
+
+		body, err := json.Marshal(map[string]interface{}{
+			"Registrations": []map[string]interface{}{
+				{"PermitID": "123456", "BandIDs": []uuid.UUID{bands[0].ID, bands[1].ID}},
+			},
+		})
+		require.NoError(t, err)
+
+		res := performRequest("POST", "/api/club/registrations", bytes.NewBuffer(body), map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		var got struct {
+			Members []ListMembersMember
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		require.Len(t, got.Members, 1)
+		require.Equal(t, "123456", got.Members[0].PermitID)
+		require.Len(t, got.Members[0].Entries, 2)
+
+		var member models.Member
+		require.NoError(t, env.db.Where(&models.Member{PermitID: "123456"}).First(&member).Error)
+		require.Equal(t, env.user.ID, member.UserID)
+		require.Equal(t, "08770047", member.ClubNumber)
+		require.True(t, member.HasBeenNotified)
+
+		var entries []models.Entry
+		require.NoError(t, env.db.Where(&models.Entry{MemberID: member.ID, Confirmed: true}).Find(&entries).Error)
+		require.Len(t, entries, 2)
+
+		// A single consolidated email is sent
+		_, err = env.api.outbox.ProcessBatch()
+		require.NoError(t, err)
+		sent := env.mailer.Sent()
+		require.Len(t, sent, 1)
+		require.Equal(t, env.user.Email, sent[0].To)
+		require.Contains(t, sent[0].HTML, "PIERRE Jean")
+	})
+	t.Run("AlreadyRegisteredByOther", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		require.NoError(t, env.db.Model(env.user).Updates(models.User{IsClubManager: true, ClubNumber: "08770047"}).Error)
+		mockFFTTClubPlayers(t, env, "08770047", `<joueur><licence>123456</licence><nom>PIERRE</nom><prenom>Jean</prenom><nclub>Caillouville</nclub><sexe>M</sexe><points>801</points></joueur>`)
+
+		user := models.User{
+			Email: "hdupont@example.com",
+			Members: []models.Member{
+				{
+					FirstName: "Jean",
+					LastName:  "Pierre",
+					Sex:       "M",
+					PermitID:  "123456",
+				},
+			},
+		}
+		require.NoError(t, env.db.Create(&user).Error)
+
+		body, err := json.Marshal(map[string]interface{}{
+			"Registrations": []map[string]interface{}{
+				{"PermitID": "123456", "BandIDs": []uuid.UUID{}},
+			},
+		})
+		require.NoError(t, err)
+
+		res := performRequest("POST", "/api/club/registrations", bytes.NewBuffer(body), map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusConflict, res.Code)
+	})
+	t.Run("PendingValidation", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		require.NoError(t, env.db.Model(env.user).Updates(models.User{IsClubManager: true, ClubNumber: "08770047"}).Error)
+		mockFFTTClubPlayers(t, env, "08770047", `<joueur><licence>123456</licence><nom>PIERRE</nom><prenom>Jean</prenom><nclub>Caillouville</nclub><sexe>M</sexe><points>801</points></joueur>`)
+
+		band := models.Band{Name: "S", Day: 1, Color: models.BandColor_BLUE, SexAllowed: models.BandSex_ALL, MaxEntries: 1, MaxPoints: 999}
+		require.NoError(t, env.db.Create(&band).Error)
+		member := models.Member{FirstName: "Jean", LastName: "Pierre", Sex: "M", Points: 801, Category: "S", PermitID: "123456", UserID: env.user.ID, Manual: true, PendingValidation: true}
+		require.NoError(t, env.db.Create(&member).Error)
+
+		body, err := json.Marshal(map[string]interface{}{
+			"Registrations": []map[string]interface{}{
+				{"PermitID": "123456", "BandIDs": []uuid.UUID{band.ID}},
+			},
+		})
+		require.NoError(t, err)
+
+		res := performRequest("POST", "/api/club/registrations", bytes.NewBuffer(body), map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusConflict, res.Code)
+		var entries []models.Entry
+		require.NoError(t, env.db.Where(&models.Entry{MemberID: member.ID}).Find(&entries).Error)
+		require.Empty(t, entries)
+	})
+}
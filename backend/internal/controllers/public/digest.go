@@ -0,0 +1,215 @@
+package public
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/emails"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/SuperPingPong/tournoi/internal/outbox"
+	"github.com/SuperPingPong/tournoi/internal/pricing"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// entriesDigestDelay is how long a member must stay unchanged before its summary is sent
+	entriesDigestDelay    = 5 * time.Minute
+	entriesDigestInterval = time.Minute
+)
+
+// scheduleEntriesDigest (re)schedules the summary of the member's entries.
+// previousBandIDs are the bands of the member before the change, they are only used if no summary was sent yet.
+func scheduleEntriesDigest(tx *gorm.DB, memberID uuid.UUID, previousBandIDs []uuid.UUID) error {
+	dueAt := time.Now().Add(entriesDigestDelay)
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "member_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"due_at", "updated_at"}),
+	}).Create(&models.EntriesDigest{
+		MemberID:        memberID,
+		NotifiedBandIDs: bandIDsToStrings(previousBandIDs),
+		DueAt:           &dueAt,
+	}).Error
+}
+
+// markEntriesNotified records bandIDs as the last notified state of the member, cancelling any pending summary
+func markEntriesNotified(tx *gorm.DB, memberID uuid.UUID, bandIDs []uuid.UUID) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "member_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"notified_band_ids", "due_at", "updated_at"}),
+	}).Create(&models.EntriesDigest{
+		MemberID:        memberID,
+		NotifiedBandIDs: bandIDsToStrings(bandIDs),
+	}).Error
+}
+
+func bandIDsToStrings(bandIDs []uuid.UUID) pq.StringArray {
+	return lo.Map(bandIDs, func(bandID uuid.UUID, _ int) string {
+		return bandID.String()
+	})
+}
+
+// entriesSummary builds the summary email of entries, priced by priceMemberEntries, compared to the previously notified bands
+func entriesSummary(member models.Member, entries []ListMembersEntry, previousBands []models.Band, update bool) emails.RegisterConfirm {
+	current := lo.Map(entries, func(entry ListMembersEntry, _ int) emails.Entry {
+		return emails.Entry{
+			BandName:   entry.BandName,
+			BandDay:    entry.BandDay,
+			Rank:       entry.BandRank,
+			MaxEntries: entry.BandMaxEntries,
+			Price:      entry.BandPrice,
+			Adjustments: lo.Map(entry.Adjustments, func(adjustment pricing.Adjustment, _ int) emails.Adjustment {
+				return emails.Adjustment{Kind: adjustment.Kind, Amount: adjustment.Amount}
+			}),
+		}
+	})
+	days, total := emails.NewDays(current)
+
+	summary := emails.RegisterConfirm{
+		FirstName: member.FirstName,
+		LastName:  member.LastName,
+		Update:    update,
+		Days:      days,
+		Total:     total,
+	}
+	for i, entry := range entries {
+		if !lo.ContainsBy(previousBands, func(band models.Band) bool { return band.ID == entry.BandID }) {
+			summary.Added = append(summary.Added, current[i])
+		}
+	}
+	for _, band := range previousBands {
+		if !lo.ContainsBy(entries, func(entry ListMembersEntry) bool { return entry.BandID == band.ID }) {
+			summary.Removed = append(summary.Removed, emails.Entry{BandName: band.Name, BandDay: band.Day, Price: band.Price})
+		}
+	}
+	return summary
+}
+
+// FlushEntriesDigests queues the summaries which are due and returns how many digests were processed
+func (api *API) FlushEntriesDigests() (int, error) {
+	var processed int
+	err := api.db.Transaction(func(tx *gorm.DB) error {
+		var digests []models.EntriesDigest
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("due_at <= ?", time.Now()).
+			Order("due_at ASC").
+			Limit(50).
+			Find(&digests).Error; err != nil {
+			return fmt.Errorf("failed to list due digests: %w", err)
+		}
+
+		for _, digest := range digests {
+			if err := api.flushEntriesDigest(tx, digest); err != nil {
+				return fmt.Errorf("failed to flush digest of member %s: %w", digest.MemberID, err)
+			}
+		}
+		processed = len(digests)
+		return nil
+	})
+	if err == nil && processed > 0 {
+		api.outbox.Wake()
+	}
+	return processed, err
+}
+
+func (api *API) flushEntriesDigest(tx *gorm.DB, digest models.EntriesDigest) error {
+	var member models.Member
+	if err := tx.Unscoped().First(&member, digest.MemberID).Error; err != nil {
+		return fmt.Errorf("failed to get member: %w", err)
+	}
+	// Nothing to tell about a deleted member
+	if member.DeletedAt.Valid {
+		return tx.Delete(&digest).Error
+	}
+
+	entries, err := listMemberEntries(tx, member.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list member entries: %w", err)
+	}
+	rules, err := getPricingRules(tx)
+	if err != nil {
+		return err
+	}
+	priceMemberEntries(rules, member, entries)
+	var previousBands []models.Band
+	if len(digest.NotifiedBandIDs) > 0 {
+		if err = tx.Where("id IN ?", []string(digest.NotifiedBandIDs)).Find(&previousBands).Error; err != nil {
+			return fmt.Errorf("failed to list notified bands: %w", err)
+		}
+	}
+
+	summary := entriesSummary(member, entries, previousBands, member.HasBeenNotified)
+	// Changes may cancel each other out before the digest is due
+	if len(summary.Added) > 0 || len(summary.Removed) > 0 {
+		// Notify the owner of the member, who may not be the current user
+		var owner models.User
+		if err = tx.First(&owner, member.UserID).Error; err != nil {
+			return fmt.Errorf("failed to get member owner: %w", err)
+		}
+		// The badge is only useful to members who still play
+		summary.Badge = len(entries) > 0
+		message, err := api.renderEmail(owner.Email, owner.Language, summary)
+		if err != nil {
+			return fmt.Errorf("failed to build email: %w", err)
+		}
+		// The days the member plays, the attachment is skipped until the tournament dates are configured
+		if len(entries) > 0 {
+			attachment, err := memberCalendarAttachment(member, entries, owner.Language)
+			if err != nil && !errors.Is(err, tournamentDatesNotConfiguredError) {
+				return fmt.Errorf("failed to build calendar: %w", err)
+			}
+			if attachment != nil {
+				message.Attachments = append(message.Attachments, *attachment)
+			}
+		}
+		if summary.Badge {
+			attachment, err := api.memberBadgeAttachment(member)
+			if err != nil {
+				return fmt.Errorf("failed to build badge: %w", err)
+			}
+			message.Attachments = append(message.Attachments, *attachment)
+		}
+		key := fmt.Sprintf("entries-digest:%s:%d", member.ID, digest.DueAt.UnixNano())
+		if err = outbox.Enqueue(tx, key, message); err != nil {
+			return fmt.Errorf("failed to enqueue email: %w", err)
+		}
+		if err = tx.Model(models.Member{}).
+			Where("id", member.ID).
+			Updates(models.Member{HasBeenNotified: true}).Error; err != nil {
+			return fmt.Errorf("failed to update member: %w", err)
+		}
+	}
+
+	bandIDs := lo.Map(entries, func(entry ListMembersEntry, _ int) uuid.UUID {
+		return entry.BandID
+	})
+	return tx.Model(&digest).
+		Select("notified_band_ids", "due_at").
+		Updates(models.EntriesDigest{NotifiedBandIDs: bandIDsToStrings(bandIDs), DueAt: nil}).Error
+}
+
+// RunEntriesDigests flushes the due digests until ctx is done
+func (api *API) RunEntriesDigests(ctx context.Context) {
+	ticker := time.NewTicker(entriesDigestInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := api.FlushEntriesDigests(); err != nil {
+			log.Printf("entries digests: %s", err)
+			sentry.CaptureException(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package public
+
+import (
+	"testing"
+
+	"github.com/SuperPingPong/tournoi/internal/emails"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/SuperPingPong/tournoi/internal/pricing"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntriesSummary(t *testing.T) {
+	bands := []models.Band{
+		{ID: uuid.New(), Name: "A", Day: 1, Price: 9},
+		{ID: uuid.New(), Name: "B", Day: 1, Price: 10},
+		{ID: uuid.New(), Name: "E", Day: 2, Price: 9},
+	}
+	member := models.Member{FirstName: "John", LastName: "Doe"}
+	entries := []ListMembersEntry{
+		{BandID: bands[1].ID, BandName: "B", BandDay: 1, BandPrice: 10, BandMaxEntries: 2, BandRank: 1},
+		{BandID: bands[2].ID, BandName: "E", BandDay: 2, BandPrice: 9, BandMaxEntries: 2, BandRank: 3},
+	}
+
+	summary := entriesSummary(member, entries, bands[:2], true)
+
+	require.True(t, summary.Update)
+	// E is on the waiting list, it is not due yet
+	require.Equal(t, 10, summary.Total)
+	require.Len(t, summary.Days, 2)
+	require.Equal(t, 10, summary.Days[0].Price)
+	require.Equal(t, 0, summary.Days[1].Price)
+	require.True(t, summary.Waiting())
+	require.Equal(t, 1, summary.Days[1].Entries[0].WaitingListRank())
+	require.Len(t, summary.Added, 1)
+	require.Equal(t, "E", summary.Added[0].BandName)
+	require.Len(t, summary.Removed, 1)
+	require.Equal(t, "A", summary.Removed[0].BandName)
+
+	// Nothing changed
+	summary = entriesSummary(member, entries, bands[1:], true)
+	require.Empty(t, summary.Added)
+	require.Empty(t, summary.Removed)
+
+	// The adjustments of the pricing rules are part of the price
+	entries[0].Adjustments = []pricing.Adjustment{{Kind: pricing.Adjustment_CLUB, Amount: -2}}
+	summary = entriesSummary(member, entries, bands[1:], true)
+	require.Equal(t, 8, summary.Days[0].Price)
+	require.Equal(t, []emails.Adjustment{{Kind: pricing.Adjustment_CLUB, Amount: -2}}, summary.Days[0].Adjustments)
+	require.Equal(t, 8, summary.Total)
+}
@@ -0,0 +1,49 @@
+package public
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/SuperPingPong/tournoi/internal/emails"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+)
+
+func (api *API) ListEmailTemplates(ctx *gin.Context) {
+	templates := lo.Keys(emails.Samples())
+	sort.Strings(templates)
+
+	ctx.JSON(http.StatusOK, gin.H{"templates": templates, "languages": emails.Languages})
+}
+
+// PreviewEmailTemplate renders a template with sample data.
+// The format query parameter selects the HTML or text part, both are returned as JSON by default.
+func (api *API) PreviewEmailTemplate(ctx *gin.Context) {
+	sample, ok := emails.Samples()[ctx.Param("name")]
+	if !ok {
+		ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("email template %s not found", ctx.Param("name")))
+		return
+	}
+
+	language := ctx.DefaultQuery("lang", emails.DefaultLanguage)
+	if !lo.Contains(emails.Languages, language) {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid language: %s", language))
+		return
+	}
+
+	rendered, err := api.emails.Render(language, sample)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to render email: %w", err))
+		return
+	}
+
+	switch ctx.Query("format") {
+	case "html":
+		ctx.Data(http.StatusOK, "text/html; charset=utf-8", []byte(rendered.HTML))
+	case "text":
+		ctx.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(rendered.Text))
+	default:
+		ctx.JSON(http.StatusOK, rendered)
+	}
+}
@@ -0,0 +1,80 @@
+package public
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/SuperPingPong/tournoi/internal/emails"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewEmailTemplate(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		res := performRequest("GET", "/api/admin/email-templates/club_register_confirm/preview?lang=en", nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		var got emails.Rendered
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		require.Equal(t, "Club LOGNES EP registrations confirmed Lognes tournament", got.Subject)
+		require.Contains(t, got.HTML, "waiting list #3")
+		require.Contains(t, got.Text, "https://tournoi.example.com")
+
+		res = performRequest("GET", "/api/admin/email-templates/otp_code/preview?format=html", nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		require.Equal(t, "text/html; charset=utf-8", res.Header().Get("Content-Type"))
+		require.Contains(t, res.Body.String(), "123456")
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		res := performRequest("GET", "/api/admin/email-templates/unknown/preview", nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
+
+func TestUpdatePreferences(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		body, err := json.Marshal(map[string]string{"Language": "en"})
+		require.NoError(t, err)
+
+		res := performRequest("PUT", "/api/preferences", bytes.NewBuffer(body), map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		var user models.User
+		require.NoError(t, env.db.First(&user, env.user.ID).Error)
+		require.Equal(t, "en", user.Language)
+	})
+	t.Run("UnsupportedLanguage", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		body, err := json.Marshal(map[string]string{"Language": "de"})
+		require.NoError(t, err)
+
+		res := performRequest("PUT", "/api/preferences", bytes.NewBuffer(body), map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusBadRequest, res.Code)
+	})
+}
@@ -71,6 +71,7 @@ func (api *API) ListBandAvailabilities(ctx *gin.Context) {
 		return
 	}
 
+	impersonator := ExtractImpersonatorFromContext(ctx)
 	sessionID := uuid.New()
 	var entries []models.Entry
 	var bandAvailabilities []BandAvailability
@@ -104,6 +105,8 @@ func (api *API) ListBandAvailabilities(ctx *gin.Context) {
 				Confirmed: false,
 				SessionID: sessionID,
 				CreatedBy: uuid.NullUUID{UUID: user.ID, Valid: true},
+
+				CreatedByImpersonator: impersonator,
 			}).Error; err != nil {
 				return fmt.Errorf("failed to lock entries: %w", err)
 			}
@@ -127,6 +130,8 @@ type EntriesHistory struct {
 	EventType      string
 	EventBy        string
 	EventByIsAdmin bool `gorm:"column:event_by_is_admin"`
+	// Email of the admin who acted on behalf of EventBy, if any
+	EventByImpersonator string
 }
 
 func (api *API) GetMemberEntriesHistory(ctx *gin.Context) {
@@ -157,11 +162,14 @@ func (api *API) GetMemberEntriesHistory(ctx *gin.Context) {
             users.email AS event_by,
             bands.name AS band_name,
             bands.max_points AS band_max_points,
-            users.is_admin AS event_by_is_admin
+            users.is_admin AS event_by_is_admin,
+            COALESCE(impersonators.email, '') AS event_by_impersonator
         FROM
             entries
         JOIN
             users ON entries.created_by = users.id
+        LEFT JOIN
+            users AS impersonators ON entries.created_by_impersonator = impersonators.id
         JOIN
             bands ON entries.band_id = bands.id
         WHERE
@@ -176,11 +184,14 @@ func (api *API) GetMemberEntriesHistory(ctx *gin.Context) {
             users.email AS event_by,
             bands.name AS band_name,
             bands.max_points AS band_max_points,
-            users.is_admin AS event_by_is_admin
+            users.is_admin AS event_by_is_admin,
+            COALESCE(impersonators.email, '') AS event_by_impersonator
         FROM
             entries
         JOIN
             users ON entries.deleted_by = users.id
+        LEFT JOIN
+            users AS impersonators ON entries.deleted_by_impersonator = impersonators.id
         JOIN
             bands ON entries.band_id = bands.id
         WHERE
@@ -195,7 +206,29 @@ func (api *API) GetMemberEntriesHistory(ctx *gin.Context) {
 		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"history": history})
+	// Ownership changes of the member
+	transfers := []MemberTransferView{}
+	if err := api.db.Model(&models.MemberTransfer{}).
+		Select(`member_transfers.*,
+			from_users.email AS from_user_email,
+			to_users.email AS to_user_email`).
+		Joins("JOIN users AS from_users ON from_users.id = member_transfers.from_user_id").
+		Joins("JOIN users AS to_users ON to_users.id = member_transfers.to_user_id").
+		Where("member_transfers.member_id = ? AND member_transfers.status = ?", memberID, models.MemberTransferStatus_APPROVED).
+		Order("member_transfers.decided_at DESC").
+		Scan(&transfers).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get transfers: %w", err))
+		return
+	}
+
+	// Changes of the points, category or identity of the member
+	changes := []models.MemberChange{}
+	if err := api.db.Where("member_id = ?", memberID).Order("created_at DESC").Find(&changes).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get member changes: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"history": history, "transfers": transfers, "changes": changes})
 
 }
 
@@ -254,6 +287,10 @@ func (api *API) SetMemberEntries(ctx *gin.Context) {
 		return
 	}
 	fmt.Printf("member: %v\n", member)
+	if member.PendingValidation && len(input.BandIDs) > 0 {
+		ctx.AbortWithError(http.StatusConflict, memberPendingValidationError)
+		return
+	}
 
 	// List possible bands for the current member
 	var bands []models.Band
@@ -277,7 +314,15 @@ func (api *API) SetMemberEntries(ctx *gin.Context) {
 		return
 	}
 
+	impersonator := ExtractImpersonatorFromContext(ctx)
 	err = api.db.Transaction(func(tx *gorm.DB) error {
+		var previousBandIDs []uuid.UUID
+		if err = tx.Model(&models.Entry{}).
+			Where("member_id = ? AND confirmed IS TRUE", member.ID).
+			Pluck("band_id", &previousBandIDs).Error; err != nil {
+			return fmt.Errorf("failed to list member entries: %w", err)
+		}
+
 		// Delete the unwanted entries.
 		inputBandIDs := input.BandIDs
 		// We add uuid.Nil to input.BandIDs when it is empty since "band_id NOT IN (NULL)" doesn't match any entry
@@ -286,7 +331,11 @@ func (api *API) SetMemberEntries(ctx *gin.Context) {
 		}
 		if err = tx.
 			Where("member_id = ? AND band_id NOT IN ?", member.ID, inputBandIDs).
-			Updates(&models.Entry{DeletedAt: gorm.DeletedAt{Time: time.Now(), Valid: true}, DeletedBy: uuid.NullUUID{UUID: user.ID, Valid: true}}).Error; err != nil {
+			Updates(&models.Entry{
+				DeletedAt:             gorm.DeletedAt{Time: time.Now(), Valid: true},
+				DeletedBy:             uuid.NullUUID{UUID: user.ID, Valid: true},
+				DeletedByImpersonator: impersonator,
+			}).Error; err != nil {
 			return fmt.Errorf("failed to delete entry: %w", err)
 		}
 
@@ -332,10 +381,19 @@ func (api *API) SetMemberEntries(ctx *gin.Context) {
 
 		if err = tx.Model(models.Entry{}).
 			Where("id IN ?", entriesToConfirm).
-			Updates(models.Entry{Confirmed: true, ConfirmedBy: uuid.NullUUID{UUID: user.ID, Valid: true}}).Error; err != nil {
+			Updates(models.Entry{
+				Confirmed:               true,
+				ConfirmedBy:             uuid.NullUUID{UUID: user.ID, Valid: true},
+				ConfirmedByImpersonator: impersonator,
+			}).Error; err != nil {
 			return fmt.Errorf("failed to confirm entry: %w", err)
 		}
 
+		// The owner of the member gets a summary once the member has not been changed for a while
+		if err = scheduleEntriesDigest(tx, member.ID, previousBandIDs); err != nil {
+			return fmt.Errorf("failed to schedule entries digest: %w", err)
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -347,21 +405,6 @@ func (api *API) SetMemberEntries(ctx *gin.Context) {
 		return
 	}
 
-	// Only send email if it's the first registration
-	if !member.HasBeenNotified {
-		err = sendEmailHTML(user.Email, member.LastName, member.FirstName)
-		if err != nil {
-			ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to send email: %w", err))
-			return
-		}
-		if err = api.db.Model(models.Member{}).
-			Where("id", memberID).
-			Updates(models.Member{HasBeenNotified: true}).Error; err != nil {
-			ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to update member: %w", err))
-		}
-
-	}
-
 	ctx.Status(http.StatusOK)
 }
 
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
@@ -485,6 +486,44 @@ func TestSetMemberEntries(t *testing.T) {
 		require.True(t, deletedEntries[1].DeletedAt.Valid)
 		require.True(t, deletedEntries[1].DeletedBy.Valid)
 		require.Equal(t, env.user.ID, deletedEntries[1].DeletedBy.UUID)
+
+		// The owner is notified once the member has not been changed for a while
+		var digest models.EntriesDigest
+		require.NoError(t, env.db.Where(&models.EntriesDigest{MemberID: member.ID}).First(&digest).Error)
+		require.NotNil(t, digest.DueAt)
+		require.ElementsMatch(t, []string{bands[0].ID.String(), bands[1].ID.String()}, []string(digest.NotifiedBandIDs))
+
+		processed, err := env.api.FlushEntriesDigests()
+		require.NoError(t, err)
+		require.Equal(t, 0, processed)
+
+		require.NoError(t, env.db.Model(&digest).Update("due_at", time.Now().Add(-time.Second)).Error)
+		processed, err = env.api.FlushEntriesDigests()
+		require.NoError(t, err)
+		require.Equal(t, 1, processed)
+		_, err = env.api.outbox.ProcessBatch()
+		require.NoError(t, err)
+
+		sent := env.mailer.Sent()
+		require.Len(t, sent, 1)
+		require.Equal(t, env.user.Email, sent[0].To)
+		require.Equal(t, "Confirmation inscription Doe John Tournoi de Lognes", sent[0].Subject)
+		require.Contains(t, sent[0].HTML, "https://tournoi.example.com")
+		require.Contains(t, sent[0].Text, "Tableau T : rang 1")
+		require.Contains(t, sent[0].Text, "Tableau U : rang 1")
+		// Both bands are on the second day
+		require.Len(t, sent[0].Attachments, 2)
+		require.Equal(t, "tournoi.ics", sent[0].Attachments[0].Filename)
+		require.Equal(t, 1, strings.Count(string(sent[0].Attachments[0].Data), "BEGIN:VEVENT"))
+		require.Contains(t, string(sent[0].Attachments[0].Data), "DTSTART;VALUE=DATE:20240609")
+		require.Equal(t, "badge.png", sent[0].Attachments[1].Filename)
+		require.Equal(t, "image/png", sent[0].Attachments[1].ContentType)
+
+		require.NoError(t, env.db.First(&digest, digest.ID).Error)
+		require.Nil(t, digest.DueAt)
+		require.ElementsMatch(t, []string{bands[1].ID.String(), bands[2].ID.String()}, []string(digest.NotifiedBandIDs))
+		require.NoError(t, env.db.First(&member, member.ID).Error)
+		require.True(t, member.HasBeenNotified)
 	})
 	t.Run("RemoveEntry", func(t *testing.T) {
 		env := getTestEnv(t)
@@ -0,0 +1,132 @@
+package public
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/SuperPingPong/tournoi/internal/spreadsheet"
+	"github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const registrationsSheetName = "Base inscrits"
+
+type exportEntry struct {
+	MemberID       uuid.UUID
+	PermitID       string
+	LastName       string
+	FirstName      string
+	ClubName       string
+	Points         float64
+	Category       string
+	Email          string
+	BandID         uuid.UUID
+	BandRank       int
+	BandMaxEntries int
+}
+
+// exportEntriesQuery lists the confirmed entries in the order they were made, with their rank in their band
+const exportEntriesQuery = `
+	SELECT
+	  members.id AS member_id,
+	  members.permit_id,
+	  members.last_name,
+	  members.first_name,
+	  members.club_name,
+	  members.points,
+	  members.category,
+	  users.email,
+	  entries.band_id,
+	  ROW_NUMBER() OVER (PARTITION BY entries.band_id ORDER BY entries.created_at ASC) AS band_rank,
+	  bands.max_entries AS band_max_entries
+	FROM entries
+	JOIN members ON members.id = entries.member_id AND members.deleted_at IS NULL
+	JOIN users ON users.id = members.user_id
+	JOIN bands ON bands.id = entries.band_id
+	WHERE entries.confirmed IS TRUE AND entries.deleted_at IS NULL
+	ORDER BY entries.created_at ASC
+`
+
+// registrationsSheet builds the "Base inscrits" sheet: one row per member, in the order of their first entry,
+// with one column per band marked 1 for the main list and Ln for the n-th place of the waiting list
+func registrationsSheet(db *gorm.DB) (spreadsheet.Sheet, error) {
+	sheet := spreadsheet.Sheet{Name: registrationsSheetName}
+
+	var bands []models.Band
+	if err := db.Order("day ASC, created_at ASC").Find(&bands).Error; err != nil {
+		return sheet, fmt.Errorf("failed to list bands: %w", err)
+	}
+	var entries []exportEntry
+	if err := db.Raw(exportEntriesQuery).Scan(&entries).Error; err != nil {
+		return sheet, fmt.Errorf("failed to list entries: %w", err)
+	}
+
+	header := []interface{}{"N°", "Licence", "Nom", "Prénom", "Club", "Points", "Catégorie"}
+	bandColumns := map[uuid.UUID]int{}
+	for _, band := range bands {
+		bandColumns[band.ID] = len(header)
+		header = append(header, band.Name)
+	}
+	emailColumn := len(header)
+	header = append(header, "Email")
+	sheet.Rows = append(sheet.Rows, header)
+
+	memberRows := map[uuid.UUID]int{}
+	for _, entry := range entries {
+		i, ok := memberRows[entry.MemberID]
+		if !ok {
+			row := make([]interface{}, len(header))
+			row[0] = len(memberRows) + 1
+			row[1] = entry.PermitID
+			row[2] = entry.LastName
+			row[3] = entry.FirstName
+			row[4] = entry.ClubName
+			row[5] = int(math.Round(entry.Points))
+			row[6] = entry.Category
+			row[emailColumn] = entry.Email
+
+			i = len(sheet.Rows)
+			memberRows[entry.MemberID] = i
+			sheet.Rows = append(sheet.Rows, row)
+		}
+
+		if entry.BandRank <= entry.BandMaxEntries {
+			sheet.Rows[i][bandColumns[entry.BandID]] = 1
+		} else {
+			sheet.Rows[i][bandColumns[entry.BandID]] = fmt.Sprintf("L%d", entry.BandRank-entry.BandMaxEntries)
+		}
+	}
+
+	return sheet, nil
+}
+
+// ExportRegistrations downloads the registrations as a csv, xlsx (by default) or ods spreadsheet
+func (api *API) ExportRegistrations(ctx *gin.Context) {
+	format := ctx.DefaultQuery("format", spreadsheet.Format_XLSX)
+	if format != spreadsheet.Format_CSV && format != spreadsheet.Format_XLSX && format != spreadsheet.Format_ODS {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid format: %s", format))
+		return
+	}
+
+	sheet, err := registrationsSheet(api.db)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	filename := fmt.Sprintf("%s-tournoi-de-lognes.%s", time.Now().Format("2006-01-02-15-04-05"), format)
+	ctx.Header("Content-Type", spreadsheet.ContentType(format))
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	ctx.Status(http.StatusOK)
+	if err = spreadsheet.Write(ctx.Writer, format, sheet); err != nil {
+		// The response is already started, the error can only be reported
+		log.Printf("export: %s", err)
+		sentry.CaptureException(err)
+	}
+}
@@ -0,0 +1,66 @@
+package public
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportRegistrations(t *testing.T) {
+	env := getTestEnv(t)
+	defer env.teardown()
+
+	bands := []models.Band{
+		{Name: "B", Day: 2, Color: models.BandColor_BLUE, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 1},
+		{Name: "A", Day: 1, Color: models.BandColor_PINK, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 10},
+	}
+	require.NoError(t, env.db.Create(&bands).Error)
+	members := []models.Member{
+		{FirstName: "Jean", LastName: "Pierre", Sex: "M", Points: 580.4, Category: "S", PermitID: "123456", ClubName: "Lognes", UserID: env.user.ID},
+		{FirstName: "Chloé", LastName: "Lefèvre", Sex: "F", Points: 642, Category: "V1", PermitID: "7512345", ClubName: "Créteil", UserID: env.user.ID},
+	}
+	require.NoError(t, env.db.Create(&members).Error)
+	now := time.Now()
+	entries := []models.Entry{
+		{MemberID: members[0].ID, BandID: bands[0].ID, Confirmed: true, CreatedAt: now.Add(-3 * time.Minute)},
+		{MemberID: members[1].ID, BandID: bands[0].ID, Confirmed: true, CreatedAt: now.Add(-2 * time.Minute)},
+		{MemberID: members[1].ID, BandID: bands[1].ID, Confirmed: true, CreatedAt: now.Add(-time.Minute)},
+		{MemberID: members[0].ID, BandID: bands[1].ID, Confirmed: false, CreatedAt: now},
+	}
+	require.NoError(t, env.db.Create(&entries).Error)
+
+	sheet, err := registrationsSheet(env.db)
+	require.NoError(t, err)
+	require.Equal(t, [][]interface{}{
+		{"N°", "Licence", "Nom", "Prénom", "Club", "Points", "Catégorie", "A", "B", "Email"},
+		{1, "123456", "Pierre", "Jean", "Lognes", 580, "S", nil, 1, "test@example.com"},
+		{2, "7512345", "Lefèvre", "Chloé", "Créteil", 642, "V1", 1, "L1", "test@example.com"},
+	}, sheet.Rows)
+
+	res := performRequest("GET", "/api/admin/export?format=csv", nil, map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusForbidden, res.Code)
+
+	res = performRequest("GET", "/api/admin/export?format=pdf", nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = performRequest("GET", "/api/admin/export?format=csv", nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Equal(t, "text/csv; charset=utf-8", res.Header().Get("Content-Type"))
+	require.Regexp(t, `attachment; filename=".*-tournoi-de-lognes\.csv"`, res.Header().Get("Content-Disposition"))
+	require.Contains(t, res.Body.String(), "2;7512345;Lefèvre;Chloé;Créteil;642;V1;1;L1;test@example.com\n")
+
+	res = performRequest("GET", "/api/admin/export", nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", res.Header().Get("Content-Type"))
+}
@@ -1,91 +1,67 @@
 package public
 
 import (
-	"encoding/json"
-	"encoding/xml"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 
+	"github.com/SuperPingPong/tournoi/internal/directory"
+	"github.com/SuperPingPong/tournoi/internal/fftt"
+	"github.com/SuperPingPong/tournoi/internal/models"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
+	"github.com/samber/lo"
 )
 
+// HTTPClient is the client of the FFTT calls, the tests mock it
+//
 //go:generate mockery --name HTTPClient
-type HTTPClient interface {
-	Do(req *http.Request) (*http.Response, error)
-}
-
-type FFTTPlayer struct {
-	LastName   string  `json:"nom"`
-	FirstName  string  `json:"prenom"`
-	PermitID   string  `json:"licence"`
-	Sex        string  `json:"sexe"`
-	Points     float64 `json:"point"`
-	Category   string  `json:"cat,omitempty"`
-	ClubName   string  `json:"nomclub"`
-	PermitType string  `json:"type,omitempty"`
-}
-
-func (api *API) GetFFTTPlayerData(permitID string) (*FFTTPlayer, error) {
-	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://fftt.dafunker.com/v1/joueur/%s", permitID), nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build request: %w", err)
+type HTTPClient = fftt.HTTPClient
+
+// ffttErrorStatus maps the errors of the FFTT client to the status of the response
+func ffttErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, fftt.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, fftt.ErrUpstreamDown):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
 	}
-
-	resp, err := api.httpClient.Do(req)
-	if err != nil || resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch player %s from FFTT: %w", permitID, err)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse response for player %s from FFTT: %w", permitID, err)
-	}
-
-	var data FFTTPlayer
-	err = json.Unmarshal(body, &data)
-	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response for player %s from FFTT: %w", permitID, err)
-	}
-
-	return &data, nil
 }
 
 func (api *API) GetFFTTPlayer(ctx *gin.Context) {
 	permitID := ctx.Param("id")
 
-	player, err := api.GetFFTTPlayerData(permitID)
+	player, err := api.fftt.GetPlayer(ctx.Request.Context(), permitID)
 	if err != nil {
-		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get FFTT player: %w", err))
-	}
-	if len(player.LastName) == 0 && len(player.FirstName) == 0 {
-		ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("FFTT player %s not found", permitID))
+		if errors.Is(err, fftt.ErrNotFound) {
+			ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("FFTT player %s not found", permitID))
+			return
+		}
+		ctx.AbortWithError(ffttErrorStatus(err), fmt.Errorf("failed to get FFTT player: %w", err))
 		return
 	}
 
-	ctx.JSON(http.StatusOK, &player)
-}
-
-type PlayerXML struct {
-	LastName  string  `xml:"nom"`
-	FirstName string  `xml:"prenom"`
-	ClubName  string  `xml:"nclub"`
-	Points    float64 `xml:"points"`
-	PermitID  string  `xml:"licence"`
-	Sex       string  `xml:"sexe"`
-}
+	api.rememberPlayers(*player)
 
-type PlayersXML struct {
-	Players []PlayerXML `xml:"joueur"`
+	ctx.JSON(http.StatusOK, player)
 }
 
 type SearchFFTTPlayersInput struct {
-	Surname string
-	Name    string
+	Surname  string
+	Name     string
+	Page     int `binding:"omitempty,min=1"`
+	PageSize int `binding:"omitempty,min=1,max=50"`
 }
 
+const (
+	PlayersSource_DIRECTORY string = "directory"
+	PlayersSource_FFTT             = "fftt"
+)
+
+// SearchFFTTPlayers searches the local player directory when it is enabled, the FFTT being called when it has no match
 func (api *API) SearchFFTTPlayers(ctx *gin.Context) {
 	var input SearchFFTTPlayersInput
 	err := ctx.ShouldBindBodyWith(&input, binding.JSON)
@@ -93,66 +69,47 @@ func (api *API) SearchFFTTPlayers(ctx *gin.Context) {
 		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
 		return
 	}
-
-	req, err := http.NewRequest(http.MethodGet, "https://fftt.dafunker.com/v1//proxy/xml_liste_joueur_o.php", nil)
-	if err != nil {
-		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to build request: %w", err))
-		return
-	}
-
-	queryParams := map[string]string{
-		"nom":    input.Surname,
-		"prenom": input.Name,
+	if input.Page == 0 {
+		input.Page = 1
 	}
-	q := req.URL.Query()
-	for key, value := range queryParams {
-		q.Add(key, value)
-	}
-	req.URL.RawQuery = q.Encode()
-	if err != nil {
-		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("failed to build request query parameters: %w", err))
-		return
-	}
-
-	resp, err := api.httpClient.Do(req)
-	if err != nil || resp.StatusCode != http.StatusOK {
-		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to search FFTT player: %w", err))
-		return
+	if input.PageSize == 0 {
+		input.PageSize = 10
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to parse search response from FFTT: %w", err))
-		return
+	if api.playerDirectory {
+		query := strings.TrimSpace(input.Surname + " " + input.Name)
+		var total int64
+		err = api.db.Model(&models.Player{}).Scopes(directory.SearchScope(query)).Count(&total).Error
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to count directory players: %w", err))
+			return
+		}
+		if total > 0 {
+			var players []models.Player
+			err = api.db.Scopes(directory.SearchScope(query), directory.RankScope(query), Paginate(input.Page, input.PageSize)).Find(&players).Error
+			if err != nil {
+				ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to search directory players: %w", err))
+				return
+			}
+			ctx.JSON(http.StatusOK, gin.H{
+				"players": lo.Map(players, func(player models.Player, _ int) fftt.Player { return directory.ToFFTT(player) }),
+				"total":   total,
+				"source":  PlayersSource_DIRECTORY,
+			})
+			return
+		}
 	}
 
-	var data PlayersXML
-	xmlString := strings.Replace(string(body), "encoding=\"ISO-8859-1\"", "encoding=\"UTF-8\"", 1)
-	xmlBytes := []byte(xmlString)
-	err = xml.Unmarshal(xmlBytes, &data)
+	players, err := api.fftt.SearchPlayers(ctx.Request.Context(), input.Surname, input.Name)
 	if err != nil {
-		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to unmarshal search response from FFTT: %w", err))
+		ctx.AbortWithError(ffttErrorStatus(err), err)
 		return
 	}
+	api.rememberPlayers(players...)
 
-	var ffttPlayers []FFTTPlayer
-	for _, player := range data.Players {
-		if len(ffttPlayers) == 10 {
-			break
-		}
-		if player.ClubName == "" {
-			continue
-		}
-
-		ffttPlayers = append(ffttPlayers, FFTTPlayer{
-			LastName:  strings.TrimSpace(player.LastName),
-			FirstName: strings.TrimSpace(player.FirstName),
-			PermitID:  player.PermitID,
-			Points:    player.Points,
-			ClubName:  player.ClubName,
-			Sex:       player.Sex,
-		})
-	}
-
-	ctx.JSON(http.StatusOK, gin.H{"players": ffttPlayers})
+	ctx.JSON(http.StatusOK, gin.H{
+		"players": lo.Subset(players, (input.Page-1)*input.PageSize, uint(input.PageSize)),
+		"total":   len(players),
+		"source":  PlayersSource_FFTT,
+	})
 }
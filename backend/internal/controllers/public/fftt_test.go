@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/SuperPingPong/tournoi/internal/fftt"
 	"github.com/stretchr/testify/require"
 )
 
@@ -28,7 +29,7 @@ func TestGetFFTTPlayer(t *testing.T) {
 		expectedFFTTReq, err := http.NewRequest(http.MethodGet, "https://fftt.dafunker.com/v1/joueur/"+permitID, nil)
 		mockFFTTRes := fmt.Sprintf(`{"nom":"%s","prenom":"%s","licence":"%s","sexe":"%s","point":%f,"cat":"%s","nomclub":"%s","type":"%s"}`, lastName, firstName, permitID, sex, point, category, clubName, permitType)
 		r := io.NopCloser(bytes.NewReader([]byte(mockFFTTRes)))
-		env.api.httpClient.(*MockHTTPClient).EXPECT().Do(expectedFFTTReq).Return(&http.Response{
+		env.httpClient.EXPECT().Do(expectedFFTTReq).Return(&http.Response{
 			StatusCode: http.StatusOK,
 			Body:       r,
 		}, nil)
@@ -37,7 +38,7 @@ func TestGetFFTTPlayer(t *testing.T) {
 			"Authorization": "Bearer " + env.jwt,
 		}, env.api.router)
 
-		var player FFTTPlayer
+		var player fftt.Player
 		require.Equal(t, http.StatusOK, res.Code)
 		err = json.NewDecoder(res.Body).Decode(&player)
 		require.NoError(t, err)
@@ -59,7 +60,7 @@ func TestGetFFTTPlayer(t *testing.T) {
 		expectedFFTTReq, err := http.NewRequest(http.MethodGet, "https://fftt.dafunker.com/v1/joueur/"+permitID, nil)
 		mockFFTTRes := fmt.Sprintf(`{"nom":"","prenom":"","licence":"%s","sexe":"","point":%d,"cat":"","nomclub":"","type":""}`, permitID, 0)
 		r := io.NopCloser(bytes.NewReader([]byte(mockFFTTRes)))
-		env.api.httpClient.(*MockHTTPClient).EXPECT().Do(expectedFFTTReq).Return(&http.Response{
+		env.httpClient.EXPECT().Do(expectedFFTTReq).Return(&http.Response{
 			StatusCode: http.StatusOK,
 			Body:       r,
 		}, nil)
@@ -74,4 +75,23 @@ func TestGetFFTTPlayer(t *testing.T) {
 		require.Equal(t, http.StatusNotFound, res.Code)
 		require.Equal(t, fmt.Sprintf("FFTT player %s not found", permitID), actual["error"])
 	})
+	t.Run("UpstreamDown", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		permitID := "123456"
+
+		expectedFFTTReq, err := http.NewRequest(http.MethodGet, "https://fftt.dafunker.com/v1/joueur/"+permitID, nil)
+		require.NoError(t, err)
+		env.httpClient.EXPECT().Do(expectedFFTTReq).Return(&http.Response{
+			StatusCode: http.StatusBadGateway,
+			Body:       io.NopCloser(bytes.NewReader(nil)),
+		}, nil)
+
+		res := performRequest("GET", "/api/players/"+permitID, nil, map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusServiceUnavailable, res.Code)
+	})
 }
@@ -0,0 +1,217 @@
+package public
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"gorm.io/gorm"
+)
+
+const (
+	GirpeList_MAIN    string = "main"
+	GirpeList_WAITING        = "waiting"
+)
+
+// girpeHeader are the columns of the registrations imported by GIRPE
+var girpeHeader = []string{"Licence", "Nom", "Prénom", "Club", "N° club", "Points", "Sexe", "Catégorie", "Tableau"}
+
+var unsafeFilenameRegexp = regexp.MustCompile(`[^A-Za-z0-9-]+`)
+
+type girpeEntry struct {
+	PermitID       string
+	LastName       string
+	FirstName      string
+	ClubName       string
+	ClubNumber     string
+	Points         float64
+	Sex            string
+	Category       string
+	BandID         uuid.UUID
+	BandName       string
+	BandRank       int
+	BandMaxEntries int
+	// Status and Promoted are the check in of the entry, see holdsPlace
+	Status   string
+	Promoted bool
+}
+
+// girpeEntriesQuery lists the confirmed entries of each band in rank order, with their check in
+const girpeEntriesQuery = `
+	SELECT * FROM (
+	  SELECT
+	    members.permit_id,
+	    members.last_name,
+	    members.first_name,
+	    members.club_name,
+	    members.club_number,
+	    members.points,
+	    members.sex,
+	    members.category,
+	    bands.id AS band_id,
+	    bands.name AS band_name,
+	    ROW_NUMBER() OVER (PARTITION BY entries.band_id ORDER BY entries.created_at ASC) AS band_rank,
+	    bands.max_entries AS band_max_entries,
+	    COALESCE(check_ins.status, '') AS status,
+	    COALESCE(check_ins.promoted, FALSE) AS promoted
+	  FROM entries
+	  JOIN members ON members.id = entries.member_id AND members.deleted_at IS NULL
+	  JOIN bands ON bands.id = entries.band_id
+	  LEFT JOIN check_ins ON check_ins.entry_id = entries.id
+	  WHERE entries.confirmed IS TRUE AND entries.deleted_at IS NULL
+	) AS ranked_entries
+	ORDER BY band_rank ASC
+`
+
+func listGirpeEntries(db *gorm.DB) ([]girpeEntry, error) {
+	var entries []girpeEntry
+	if err := db.Raw(girpeEntriesQuery).Scan(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list entries: %w", err)
+	}
+	return entries, nil
+}
+
+// girpeList keeps the entries of band in the main draw or in the waiting list, in rank order.
+// Once the check in started, the scratched and absent players leave the main draw and the promoted ones join it.
+func girpeList(entries []girpeEntry, band models.Band, list string) []girpeEntry {
+	return lo.Filter(entries, func(entry girpeEntry, _ int) bool {
+		if entry.BandID != band.ID {
+			return false
+		}
+		waiting := entry.BandRank > entry.BandMaxEntries
+		if list == GirpeList_WAITING {
+			return waiting && !entry.Promoted
+		}
+		return holdsPlace(CheckInEntry{Waiting: waiting, Status: entry.Status, Promoted: entry.Promoted})
+	})
+}
+
+// writeGirpeCSV writes the entries delimited by semicolons and encoded in Windows-1252, like the files GIRPE imports
+func writeGirpeCSV(w io.Writer, entries []girpeEntry) error {
+	writer := csv.NewWriter(encoding.ReplaceUnsupported(charmap.Windows1252.NewEncoder()).Writer(w))
+	writer.Comma = ';'
+	writer.UseCRLF = true
+
+	if err := writer.Write(girpeHeader); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := writer.Write([]string{
+			entry.PermitID,
+			entry.LastName,
+			entry.FirstName,
+			entry.ClubName,
+			entry.ClubNumber,
+			strconv.Itoa(int(math.Round(entry.Points))),
+			entry.Sex,
+			entry.Category,
+			entry.BandName,
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func girpeFilename(band models.Band, list string) string {
+	name := "tableau-" + unsafeFilenameRegexp.ReplaceAllString(band.Name, "_")
+	if list == GirpeList_WAITING {
+		name += "-attente"
+	}
+	return name + ".csv"
+}
+
+// ExportGirpe downloads the entries in the import format of GIRPE.
+// With band_id, the main draw (or the waiting list with list=waiting) of the band is returned as a CSV,
+// otherwise a zip holds both lists of every band.
+func (api *API) ExportGirpe(ctx *gin.Context) {
+	list := ctx.DefaultQuery("list", GirpeList_MAIN)
+	if list != GirpeList_MAIN && list != GirpeList_WAITING {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid list: %s", list))
+		return
+	}
+
+	if value := ctx.Query("band_id"); value != "" {
+		bandID, err := uuid.Parse(value)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid band id: %s", value))
+			return
+		}
+		var band models.Band
+		if err = api.db.First(&band, bandID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("band %s not found", bandID))
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get band: %w", err))
+			return
+		}
+		entries, err := listGirpeEntries(api.db)
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+
+		ctx.Header("Content-Type", "text/csv; charset=windows-1252")
+		ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, girpeFilename(band, list)))
+		ctx.Status(http.StatusOK)
+		if err = writeGirpeCSV(ctx.Writer, girpeList(entries, band, list)); err != nil {
+			log.Printf("girpe export: %s", err)
+			sentry.CaptureException(err)
+		}
+		return
+	}
+
+	var bands []models.Band
+	if err := api.db.Order("day ASC, created_at ASC").Find(&bands).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list bands: %w", err))
+		return
+	}
+	entries, err := listGirpeEntries(api.db)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	filename := fmt.Sprintf("%s-girpe.zip", time.Now().Format("2006-01-02-15-04-05"))
+	ctx.Header("Content-Type", "application/zip")
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	ctx.Status(http.StatusOK)
+	if err = writeGirpeZip(ctx.Writer, bands, entries); err != nil {
+		log.Printf("girpe export: %s", err)
+		sentry.CaptureException(err)
+	}
+}
+
+// writeGirpeZip writes the main draw and the waiting list of every band, as separate files
+func writeGirpeZip(w io.Writer, bands []models.Band, entries []girpeEntry) error {
+	archive := zip.NewWriter(w)
+	for _, band := range bands {
+		for _, list := range []string{GirpeList_MAIN, GirpeList_WAITING} {
+			writer, err := archive.Create(girpeFilename(band, list))
+			if err != nil {
+				return err
+			}
+			if err = writeGirpeCSV(writer, girpeList(entries, band, list)); err != nil {
+				return err
+			}
+		}
+	}
+	return archive.Close()
+}
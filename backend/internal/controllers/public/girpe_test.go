@@ -0,0 +1,99 @@
+package public
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestWriteGirpeCSV(t *testing.T) {
+	band := models.Band{ID: uuid.New(), Name: "A 500/899"}
+	entries := []girpeEntry{
+		{PermitID: "7512345", LastName: "LEFÈVRE", FirstName: "Chloé", ClubName: "US Créteil", ClubNumber: "08940001", Points: 641.6, Sex: "F", Category: "V1", BandID: band.ID, BandName: band.Name, BandRank: 1, BandMaxEntries: 1},
+		{PermitID: "123456", LastName: "PIERRE", FirstName: "Jean", Sex: "M", BandID: band.ID, BandName: band.Name, BandRank: 2, BandMaxEntries: 1},
+		{PermitID: "654321", LastName: "AUTRE", FirstName: "Tableau", BandID: uuid.New(), BandRank: 1, BandMaxEntries: 1},
+	}
+
+	require.Equal(t, []string{"7512345"}, permitIDs(girpeList(entries, band, GirpeList_MAIN)))
+	require.Equal(t, []string{"123456"}, permitIDs(girpeList(entries, band, GirpeList_WAITING)))
+	require.Equal(t, "tableau-A_500_899-attente.csv", girpeFilename(band, GirpeList_WAITING))
+
+	// After the deadline, the scratched player leaves the draw and the promoted one takes its place
+	checkedIn := []girpeEntry{
+		{PermitID: "7512345", BandID: band.ID, BandRank: 1, BandMaxEntries: 1, Status: models.CheckInStatus_SCRATCHED},
+		{PermitID: "123456", BandID: band.ID, BandRank: 2, BandMaxEntries: 1, Status: models.CheckInStatus_PRESENT, Promoted: true},
+		{PermitID: "234567", BandID: band.ID, BandRank: 3, BandMaxEntries: 1, Status: models.CheckInStatus_PRESENT},
+	}
+	require.Equal(t, []string{"123456"}, permitIDs(girpeList(checkedIn, band, GirpeList_MAIN)))
+	require.Equal(t, []string{"234567"}, permitIDs(girpeList(checkedIn, band, GirpeList_WAITING)))
+
+	var buffer bytes.Buffer
+	require.NoError(t, writeGirpeCSV(&buffer, girpeList(entries, band, GirpeList_MAIN)))
+	content, err := charmap.Windows1252.NewDecoder().String(buffer.String())
+	require.NoError(t, err)
+	require.Equal(t, "Licence;Nom;Prénom;Club;N° club;Points;Sexe;Catégorie;Tableau\r\n"+
+		"7512345;LEFÈVRE;Chloé;US Créteil;08940001;642;F;V1;A 500/899\r\n", content)
+}
+
+func permitIDs(entries []girpeEntry) []string {
+	ids := []string{}
+	for _, entry := range entries {
+		ids = append(ids, entry.PermitID)
+	}
+	return ids
+}
+
+func TestExportGirpe(t *testing.T) {
+	env := getTestEnv(t)
+	defer env.teardown()
+
+	band := models.Band{Name: "A", Day: 1, Color: models.BandColor_PINK, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 1}
+	require.NoError(t, env.db.Create(&band).Error)
+	members := []models.Member{
+		{FirstName: "Jean", LastName: "Pierre", Sex: "M", Points: 580, Category: "S", PermitID: "123456", UserID: env.user.ID},
+		{FirstName: "Chloé", LastName: "Lefèvre", Sex: "F", Points: 642, Category: "V1", PermitID: "7512345", UserID: env.user.ID},
+	}
+	require.NoError(t, env.db.Create(&members).Error)
+	now := time.Now()
+	require.NoError(t, env.db.Create(&[]models.Entry{
+		{MemberID: members[1].ID, BandID: band.ID, Confirmed: true, CreatedAt: now.Add(-time.Minute)},
+		{MemberID: members[0].ID, BandID: band.ID, Confirmed: true, CreatedAt: now},
+	}).Error)
+
+	res := performRequest("GET", fmt.Sprintf("/api/admin/export/girpe?band_id=%s&list=waiting", band.ID), nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Equal(t, `attachment; filename="tableau-A-attente.csv"`, res.Header().Get("Content-Disposition"))
+	require.Contains(t, res.Body.String(), "123456;Pierre;Jean;")
+	require.NotContains(t, res.Body.String(), "7512345")
+
+	res = performRequest("GET", fmt.Sprintf("/api/admin/export/girpe?band_id=%s", uuid.New()), nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusNotFound, res.Code)
+
+	res = performRequest("GET", "/api/admin/export/girpe", nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	archive, err := zip.NewReader(bytes.NewReader(res.Body.Bytes()), int64(res.Body.Len()))
+	require.NoError(t, err)
+	require.Len(t, archive.File, 2)
+	require.Equal(t, "tableau-A.csv", archive.File[0].Name)
+	reader, err := archive.File[0].Open()
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "7512345;")
+	require.NotContains(t, string(content), "123456;")
+}
@@ -0,0 +1,144 @@
+package public
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/SuperPingPong/tournoi/internal/auth"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ImpersonateInput struct {
+	UserID uuid.UUID `binding:"required"`
+}
+
+// Impersonate issues a short-lived token letting the current admin act as another user
+func (api *API) Impersonate(ctx *gin.Context) {
+	admin, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var input ImpersonateInput
+	err = ctx.ShouldBindBodyWith(&input, binding.JSON)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
+		return
+	}
+
+	var user models.User
+	err = api.db.First(&user, input.UserID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("user %s not found", input.UserID))
+			return
+		}
+
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get user: %w", err))
+		return
+	}
+
+	if user.IsAdmin {
+		ctx.AbortWithError(http.StatusForbidden, fmt.Errorf("admin %s can't be impersonated", user.ID))
+		return
+	}
+
+	token, expire, err := auth.ImpersonationToken(api.authMiddleware, admin, &user)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to generate token: %w", err))
+		return
+	}
+
+	if err = api.db.Create(&models.AuditLog{
+		Action:         models.AuditAction_IMPERSONATE,
+		UserID:         user.ID,
+		ImpersonatorID: uuid.NullUUID{UUID: admin.ID, Valid: true},
+		Method:         ctx.Request.Method,
+		Path:           ctx.Request.URL.Path,
+		Status:         http.StatusOK,
+	}).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to create audit log: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"token": token, "expire": expire, "user": user})
+}
+
+// AuditImpersonation records every request made with an impersonation token
+func (api *API) AuditImpersonation() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Next()
+
+		impersonator := ExtractImpersonatorFromContext(ctx)
+		if !impersonator.Valid {
+			return
+		}
+
+		user, err := ExtractUserFromContext(ctx)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+
+		if err = api.db.Create(&models.AuditLog{
+			Action:         models.AuditAction_REQUEST,
+			UserID:         user.ID,
+			ImpersonatorID: impersonator,
+			Method:         ctx.Request.Method,
+			Path:           ctx.Request.URL.Path,
+			Status:         ctx.Writer.Status(),
+		}).Error; err != nil {
+			ctx.Error(fmt.Errorf("failed to create audit log: %w", err))
+		}
+	}
+}
+
+type AuditLogEntry struct {
+	models.AuditLog
+	UserEmail         string
+	ImpersonatorEmail string
+}
+
+func (api *API) ListAuditLogs(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid page: %s", ctx.Query("page")))
+		return
+	}
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "50"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid page size: %s", ctx.Query("page_size")))
+		return
+	}
+
+	query := api.db.Model(&models.AuditLog{})
+	if userID := ctx.Query("user_id"); userID != "" {
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid user id: %s", userID))
+			return
+		}
+		query = query.Where("audit_logs.user_id = ?", id)
+	}
+
+	var logs []AuditLogEntry
+	if err = query.
+		Scopes(Paginate(page, pageSize)).
+		Select("audit_logs.*, users.email AS user_email, impersonators.email AS impersonator_email").
+		Joins("JOIN users ON users.id = audit_logs.user_id").
+		Joins("LEFT JOIN users AS impersonators ON impersonators.id = audit_logs.impersonator_id").
+		Order("audit_logs.created_at DESC").
+		Scan(&logs).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list audit logs: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"logs": logs})
+}
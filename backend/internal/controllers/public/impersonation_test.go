@@ -0,0 +1,110 @@
+package public
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpersonate(t *testing.T) {
+	type impersonateResponse struct {
+		Token string
+	}
+	t.Run("Success", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		bands := []models.Band{
+			{
+				Name:       "S",
+				Day:        1,
+				SexAllowed: models.BandSex_ALL,
+				MaxEntries: 3,
+				MaxPoints:  999,
+			},
+		}
+		require.NoError(t, env.db.Create(&bands).Error)
+
+		member := models.Member{
+			FirstName: "John",
+			LastName:  "Doe",
+			Sex:       "M",
+			PermitID:  "000000",
+			Points:    500,
+			UserID:    env.user.ID,
+		}
+		require.NoError(t, env.db.Create(&member).Error)
+
+		body, err := json.Marshal(map[string]interface{}{"UserID": env.user.ID})
+		require.NoError(t, err)
+
+		res := performRequest("POST", "/api/admin/impersonate", bytes.NewBuffer(body), map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		var got impersonateResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		require.NotEmpty(t, got.Token)
+
+		// Act as the user
+		res = performRequest("GET", fmt.Sprintf("/api/members/%s/band-availabilities", member.ID), nil, map[string]string{
+			"Authorization": "Bearer " + got.Token,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		require.Equal(t, "admin@example.com", res.Header().Get("X-Impersonated-By"))
+
+		var admin models.User
+		require.NoError(t, env.db.Where(&models.User{Email: "admin@example.com"}).First(&admin).Error)
+
+		var entries []models.Entry
+		require.NoError(t, env.db.Where(&models.Entry{MemberID: member.ID}).Find(&entries).Error)
+		require.Len(t, entries, 1)
+		require.Equal(t, env.user.ID, entries[0].CreatedBy.UUID)
+		require.True(t, entries[0].CreatedByImpersonator.Valid)
+		require.Equal(t, admin.ID, entries[0].CreatedByImpersonator.UUID)
+
+		var logs []models.AuditLog
+		require.NoError(t, env.db.Where(&models.AuditLog{UserID: env.user.ID}).Order("created_at ASC").Find(&logs).Error)
+		require.Len(t, logs, 2)
+		require.Equal(t, models.AuditAction_IMPERSONATE, logs[0].Action)
+		require.Equal(t, models.AuditAction_REQUEST, logs[1].Action)
+		require.Equal(t, admin.ID, logs[1].ImpersonatorID.UUID)
+		require.Equal(t, http.StatusOK, logs[1].Status)
+	})
+	t.Run("NotAdmin", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		body, err := json.Marshal(map[string]interface{}{"UserID": env.user.ID})
+		require.NoError(t, err)
+
+		res := performRequest("POST", "/api/admin/impersonate", bytes.NewBuffer(body), map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusForbidden, res.Code)
+	})
+	t.Run("AdminTarget", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		var admin models.User
+		require.NoError(t, env.db.Where(&models.User{Email: "admin@example.com"}).First(&admin).Error)
+
+		body, err := json.Marshal(map[string]interface{}{"UserID": admin.ID})
+		require.NoError(t, err)
+
+		res := performRequest("POST", "/api/admin/impersonate", bytes.NewBuffer(body), map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusForbidden, res.Code)
+	})
+}
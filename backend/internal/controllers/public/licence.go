@@ -0,0 +1,175 @@
+package public
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/fftt"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// acceptedMedicalCertificates are the statuses of the FFTT meaning that a medical certificate (A) or a health questionnaire (Q) was presented
+var acceptedMedicalCertificates = []string{"A", "Q"}
+
+// getLicenceRules returns the licence rules of the tournament, which accept any licence until an admin sets them
+func getLicenceRules(db *gorm.DB) (models.LicenceRules, error) {
+	rules := models.LicenceRules{ID: models.LicenceRulesID}
+	err := db.First(&rules, models.LicenceRulesID).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return rules, fmt.Errorf("failed to get licence rules: %w", err)
+	}
+	return rules, nil
+}
+
+// seasonStart returns the first day of the FFTT season of date, seasons start on July 1st
+func seasonStart(date time.Time) time.Time {
+	year := date.Year()
+	if date.Month() < time.July {
+		year--
+	}
+	return time.Date(year, time.July, 1, 0, 0, 0, 0, date.Location())
+}
+
+// tournamentSeasonStart returns the first day of the season of the tournament, the current season when its date is not set
+func tournamentSeasonStart() (time.Time, error) {
+	date, err := tournamentStartDate()
+	if errors.Is(err, tournamentDatesNotConfiguredError) {
+		date = time.Now()
+	} else if err != nil {
+		return time.Time{}, err
+	}
+	return seasonStart(date), nil
+}
+
+// licenceIssue tells why rules reject the licence of player for the season starting at season, an empty string if they accept it
+func licenceIssue(rules models.LicenceRules, player fftt.Player, season time.Time) string {
+	if len(rules.AllowedPermitTypes) > 0 && !lo.Contains(rules.AllowedPermitTypes, player.PermitType) {
+		return models.LicenceIssue_PERMIT_TYPE
+	}
+	if rules.RequireValidation {
+		validation, err := time.ParseInLocation("02/01/2006", player.Validation, season.Location())
+		if err != nil || validation.Before(season) {
+			return models.LicenceIssue_NOT_VALIDATED
+		}
+	}
+	// The certificate is only checked when the FFTT provider reports it
+	if rules.RequireMedicalCertificate && player.MedicalCertificate != "" && !lo.Contains(acceptedMedicalCertificates, player.MedicalCertificate) {
+		return models.LicenceIssue_MEDICAL_CERTIFICATE
+	}
+	return ""
+}
+
+// checkLicence applies the licence rules of the tournament to player
+func checkLicence(db *gorm.DB, player fftt.Player) (string, error) {
+	rules, err := getLicenceRules(db)
+	if err != nil {
+		return "", err
+	}
+	season, err := tournamentSeasonStart()
+	if err != nil {
+		return "", err
+	}
+	return licenceIssue(rules, player, season), nil
+}
+
+// abortOnLicenceIssue aborts the request when the licence of player is not accepted, and reports whether it did
+func abortOnLicenceIssue(ctx *gin.Context, db *gorm.DB, player fftt.Player) bool {
+	issue, err := checkLicence(db, player)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return true
+	}
+	if issue != "" {
+		ctx.AbortWithError(http.StatusUnprocessableEntity, fmt.Errorf("licence of player %s is not accepted: %s", player.PermitID, issue))
+		return true
+	}
+	return false
+}
+
+func (api *API) GetLicenceRules(ctx *gin.Context) {
+	rules, err := getLicenceRules(api.db)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &rules)
+}
+
+type UpdateLicenceRulesInput struct {
+	AllowedPermitTypes        []string `binding:"dive,oneof=T P"`
+	RequireValidation         bool
+	RequireMedicalCertificate bool
+}
+
+// UpdateLicenceRules replaces the licence rules, the members are checked against them by the next points synchronisation
+func (api *API) UpdateLicenceRules(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var input UpdateLicenceRulesInput
+	if err = ctx.ShouldBindBodyWith(&input, binding.JSON); err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
+		return
+	}
+
+	rules := models.LicenceRules{
+		ID:                        models.LicenceRulesID,
+		AllowedPermitTypes:        lo.Uniq(input.AllowedPermitTypes),
+		RequireValidation:         input.RequireValidation,
+		RequireMedicalCertificate: input.RequireMedicalCertificate,
+		UpdatedBy:                 uuid.NullUUID{UUID: user.ID, Valid: true},
+	}
+	if err = api.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&rules).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to update licence rules: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &rules)
+}
+
+// checkMemberLicence records the licence issue of member, found by the points synchronisation
+func checkMemberLicence(tx *gorm.DB, member models.Member, player *fftt.Player, rules models.LicenceRules, season time.Time) error {
+	return tx.Model(&member).Updates(map[string]interface{}{
+		"licence_issue":      licenceIssue(rules, *player, season),
+		"licence_checked_at": time.Now(),
+	}).Error
+}
+
+type LicenceIssueView struct {
+	ID               uuid.UUID
+	PermitID         string
+	FirstName        string
+	LastName         string
+	PermitType       string
+	LicenceIssue     string
+	LicenceCheckedAt *time.Time
+	OwnerEmail       string
+}
+
+// ListLicenceIssues lists the members whose licence is not accepted anymore, as of the last points synchronisation
+func (api *API) ListLicenceIssues(ctx *gin.Context) {
+	members := []LicenceIssueView{}
+	if err := api.db.Model(&models.Member{}).
+		Select("members.id, members.permit_id, members.first_name, members.last_name, members.permit_type, members.licence_issue, members.licence_checked_at, users.email AS owner_email").
+		Joins("JOIN users ON users.id = members.user_id").
+		Where("members.licence_issue <> ''").
+		Order("members.last_name, members.first_name").
+		Scan(&members).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list licence issues: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"members": members})
+}
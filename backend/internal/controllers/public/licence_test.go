@@ -0,0 +1,92 @@
+package public
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/fftt"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeasonStart(t *testing.T) {
+	require.Equal(t, time.Date(2023, time.July, 1, 0, 0, 0, 0, time.UTC), seasonStart(time.Date(2024, time.June, 8, 0, 0, 0, 0, time.UTC)))
+	require.Equal(t, time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC), seasonStart(time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestLicenceIssue(t *testing.T) {
+	season := time.Date(2023, time.July, 1, 0, 0, 0, 0, time.UTC)
+	player := fftt.Player{PermitType: models.PermitType_TRADITIONAL, Validation: "03/09/2023", MedicalCertificate: "A"}
+
+	require.Empty(t, licenceIssue(models.LicenceRules{}, fftt.Player{}, season))
+	require.Empty(t, licenceIssue(models.LicenceRules{
+		AllowedPermitTypes:        pq.StringArray{models.PermitType_TRADITIONAL},
+		RequireValidation:         true,
+		RequireMedicalCertificate: true,
+	}, player, season))
+
+	promotional := player
+	promotional.PermitType = models.PermitType_PROMOTIONAL
+	require.Equal(t, models.LicenceIssue_PERMIT_TYPE, licenceIssue(models.LicenceRules{AllowedPermitTypes: pq.StringArray{models.PermitType_TRADITIONAL}}, promotional, season))
+
+	lastSeason := player
+	lastSeason.Validation = "28/09/2022"
+	require.Equal(t, models.LicenceIssue_NOT_VALIDATED, licenceIssue(models.LicenceRules{RequireValidation: true}, lastSeason, season))
+	notValidated := player
+	notValidated.Validation = ""
+	require.Equal(t, models.LicenceIssue_NOT_VALIDATED, licenceIssue(models.LicenceRules{RequireValidation: true}, notValidated, season))
+
+	noCertificate := player
+	noCertificate.MedicalCertificate = "N"
+	require.Equal(t, models.LicenceIssue_MEDICAL_CERTIFICATE, licenceIssue(models.LicenceRules{RequireMedicalCertificate: true}, noCertificate, season))
+	// Providers which do not report the certificate are not rejected
+	unknownCertificate := player
+	unknownCertificate.MedicalCertificate = ""
+	require.Empty(t, licenceIssue(models.LicenceRules{RequireMedicalCertificate: true}, unknownCertificate, season))
+}
+
+func TestLicenceRules(t *testing.T) {
+	env := getTestEnv(t)
+	defer env.teardown()
+
+	res := performRequest("PUT", "/api/admin/licence-rules", strings.NewReader(`{"AllowedPermitTypes":["X"]}`), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	res = performRequest("PUT", "/api/admin/licence-rules", strings.NewReader(`{"AllowedPermitTypes":["T"],"RequireValidation":true}`), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+
+	// TOURNAMENT_START_DATE is 2024-06-08, the licences have to be validated since 2023-07-01
+	mockFFTTPlayer(env, "777777", `{"nom":"MARTIN","prenom":"Léa","licence":"777777","sexe":"F","point":500,"cat":"C2","type":"P","validation":"12/10/2023"}`)
+	res = performRequest("POST", "/api/members", strings.NewReader(`{"PermitID":"777777"}`), map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusUnprocessableEntity, res.Code)
+
+	mockFFTTPlayer(env, "123456", `{"nom":"DUPRE","prenom":"Hervé","licence":"123456","sexe":"M","point":1234,"cat":"V1","type":"T","validation":"03/09/2023"}`)
+	res = performRequest("POST", "/api/members", strings.NewReader(`{"PermitID":"123456"}`), map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusCreated, res.Code)
+
+	// The licence is not validated for the next season yet
+	t.Setenv("TOURNAMENT_START_DATE", "2024-09-14")
+	mockFFTTPlayer(env, "123456", `{"nom":"DUPRE","prenom":"Hervé","licence":"123456","sexe":"M","point":1234,"cat":"V1","type":"T","validation":"03/09/2023"}`)
+	res = performRequest("POST", "/api/admin/points-sync", nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Contains(t, res.Body.String(), `"LicenceIssues":1`)
+
+	res = performRequest("GET", "/api/admin/licence-issues", nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Contains(t, res.Body.String(), models.LicenceIssue_NOT_VALIDATED)
+}
@@ -0,0 +1,26 @@
+package public
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/SuperPingPong/tournoi/internal/mailer"
+	"github.com/gin-gonic/gin"
+)
+
+// GetMailerTokenHealth tells whether the OAuth token of the mailer is still usable
+func (api *API) GetMailerTokenHealth(ctx *gin.Context) {
+	reporter, ok := api.mailer.(mailer.TokenHealthReporter)
+	if !ok {
+		ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("the mailer does not use an OAuth token"))
+		return
+	}
+
+	health, err := reporter.TokenHealth()
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get token health: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, health)
+}
@@ -0,0 +1,80 @@
+package public
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/mailer"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+type oauthMailer struct {
+	*mailer.MemoryMailer
+	store *mailer.DBTokenStore
+}
+
+func (m oauthMailer) TokenHealth() (*mailer.TokenHealth, error) {
+	return m.store.Health()
+}
+
+func TestGetMailerTokenHealth(t *testing.T) {
+	t.Run("Revoked", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		store, err := mailer.NewDBTokenStore(env.db, mailer.Backend_GMAIL, make([]byte, 32))
+		require.NoError(t, err)
+		require.NoError(t, store.Save(&oauth2.Token{AccessToken: "a1", RefreshToken: "r1", Expiry: time.Now().Add(time.Hour)}))
+		env.api.mailer = oauthMailer{MemoryMailer: env.mailer, store: store}
+
+		token, revoked, err := store.Load()
+		require.NoError(t, err)
+		require.False(t, revoked)
+		require.Equal(t, "r1", token.RefreshToken)
+
+		res := performRequest("GET", "/api/admin/mailer/token", nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+		require.Equal(t, http.StatusOK, res.Code)
+		var health mailer.TokenHealth
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&health))
+		require.Equal(t, mailer.TokenStatus_VALID, health.Status)
+
+		marked, err := store.MarkRevoked(errors.New(`oauth2: "invalid_grant"`))
+		require.NoError(t, err)
+		require.True(t, marked)
+		marked, err = store.MarkRevoked(errors.New(`oauth2: "invalid_grant"`))
+		require.NoError(t, err)
+		require.False(t, marked)
+
+		res = performRequest("GET", "/api/admin/mailer/token", nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+		require.Equal(t, http.StatusOK, res.Code)
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&health))
+		require.Equal(t, mailer.TokenStatus_REVOKED, health.Status)
+		require.Equal(t, `oauth2: "invalid_grant"`, health.LastError)
+	})
+	t.Run("NoOAuthToken", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		res := performRequest("GET", "/api/admin/mailer/token", nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+		require.Equal(t, http.StatusNotFound, res.Code)
+	})
+	t.Run("NotAdmin", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		res := performRequest("GET", "/api/admin/mailer/token", nil, map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+		require.Equal(t, http.StatusForbidden, res.Code)
+	})
+}
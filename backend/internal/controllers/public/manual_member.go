@@ -0,0 +1,246 @@
+package public
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/fftt"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var memberPendingValidationError = errors.New("member is pending validation by an admin")
+
+type CreateManualMemberInput struct {
+	// PermitID is empty for the players without any licence, like foreign players
+	PermitID  string
+	FirstName string  `binding:"required"`
+	LastName  string  `binding:"required"`
+	Sex       string  `binding:"required,oneof=M F"`
+	Points    float64 `binding:"min=0"`
+	Category  string  `binding:"required"`
+	ClubName  string
+	// Reason explains why the player is not in the FFTT database
+	Reason string `binding:"required"`
+}
+
+// CreateManualMember creates a member from declared data, for the players the FFTT does not know.
+// The members declared by users are pending until an admin validates them, those declared by admins are validated right away.
+func (api *API) CreateManualMember(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var input CreateManualMemberInput
+	if err = ctx.ShouldBindBodyWith(&input, binding.JSON); err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
+		return
+	}
+
+	permitID := strings.TrimSpace(input.PermitID)
+	if strings.HasPrefix(permitID, models.ManualPermitPrefix) {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: permit %s is reserved", permitID))
+		return
+	}
+	if permitID != "" {
+		_, err = api.fftt.GetPlayer(ctx.Request.Context(), permitID)
+		if err == nil {
+			ctx.AbortWithError(http.StatusConflict, fmt.Errorf("player %s is known by the FFTT, the member has to be created from its permit", permitID))
+			return
+		}
+		if !errors.Is(err, fftt.ErrNotFound) {
+			ctx.AbortWithError(ffttErrorStatus(err), fmt.Errorf("failed to check member in FFTT: %w", err))
+			return
+		}
+	} else {
+		permitID = models.ManualPermitPrefix + strings.ToUpper(uuid.NewString()[:8])
+	}
+
+	member := models.Member{
+		UserID:            user.ID,
+		PermitID:          permitID,
+		FirstName:         strings.TrimSpace(input.FirstName),
+		LastName:          strings.TrimSpace(input.LastName),
+		Sex:               input.Sex,
+		Points:            input.Points,
+		Category:          input.Category,
+		ClubName:          strings.TrimSpace(input.ClubName),
+		Manual:            true,
+		ManualReason:      input.Reason,
+		PendingValidation: !user.IsAdmin,
+	}
+	if user.IsAdmin {
+		now := time.Now()
+		member.ValidatedBy = uuid.NullUUID{UUID: user.ID, Valid: true}
+		member.ValidatedAt = &now
+	}
+	err = api.db.Create(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			ctx.AbortWithError(http.StatusConflict, fmt.Errorf("member with permit %s already exists", permitID))
+			return
+		}
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to create member: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, &member)
+}
+
+// ValidateManualMember accepts the data declared for a pending member, which can then confirm its entries
+func (api *API) ValidateManualMember(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid member id: %s", ctx.Param("id")))
+		return
+	}
+
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	now := time.Now()
+	result := api.db.Model(&models.Member{}).
+		Where("id = ? AND pending_validation IS TRUE", id).
+		Updates(map[string]interface{}{
+			"pending_validation": false,
+			"validated_by":       uuid.NullUUID{UUID: user.ID, Valid: true},
+			"validated_at":       now,
+		})
+	if result.Error != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to validate member: %w", result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("pending member %s not found", id))
+		return
+	}
+
+	var member models.Member
+	if err = api.db.First(&member, id).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get member: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &member)
+}
+
+// reconcileMember replaces the declared data of a manual member with its FFTT record, which also validates it.
+// Its entries are checked again, the declared points may have been underestimated.
+func reconcileMember(tx *gorm.DB, member *models.Member, player *fftt.Player, change models.MemberChange) ([]models.MemberChange, []models.EligibilityIssue, error) {
+	updated := *member
+	updated.PermitID = player.PermitID
+	updated.FirstName = player.FirstName
+	updated.LastName = player.LastName
+	updated.Sex = player.Sex
+	updated.Points = player.Points
+	updated.Category = player.Category
+	updated.ClubName = player.ClubName
+	updated.PermitType = player.PermitType
+
+	change.Source = models.MemberChangeSource_RECONCILE
+	changes, err := applyMemberChanges(tx, member, updated, change)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	if err = tx.Model(member).Updates(map[string]interface{}{
+		"club_number":        player.ClubNumber,
+		"manual":             false,
+		"pending_validation": false,
+		"reconciled_at":      now,
+	}).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to reconcile member: %w", err)
+	}
+	member.ClubNumber = player.ClubNumber
+	member.Manual = false
+	member.PendingValidation = false
+	member.ReconciledAt = &now
+
+	issues, err := checkMemberEntries(tx, *member)
+	if err != nil {
+		return nil, nil, err
+	}
+	return changes, issues, nil
+}
+
+type ReconcileManualMemberInput struct {
+	// PermitID is the licence of the player, by default the permit declared with the member
+	PermitID string
+}
+
+// ReconcileManualMember links a manual member to its FFTT record, typically once a fresh licence was published
+func (api *API) ReconcileManualMember(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid member id: %s", ctx.Param("id")))
+		return
+	}
+
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var input ReconcileManualMemberInput
+	if err = ctx.ShouldBindBodyWith(&input, binding.JSON); err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
+		return
+	}
+
+	var member models.Member
+	err = api.db.Where("manual IS TRUE").First(&member, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("manual member %s not found", id))
+			return
+		}
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get member: %w", err))
+		return
+	}
+
+	permitID := strings.TrimSpace(input.PermitID)
+	if permitID == "" {
+		permitID = member.PermitID
+	}
+	if strings.HasPrefix(permitID, models.ManualPermitPrefix) {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: the permit of member %s is required", id))
+		return
+	}
+
+	player, err := api.freshFFTTPlayer(ctx.Request.Context(), permitID)
+	if err != nil {
+		ctx.AbortWithError(ffttErrorStatus(err), fmt.Errorf("failed to get member data from FFTT: %w", err))
+		return
+	}
+
+	change := models.MemberChange{ChangedBy: uuid.NullUUID{UUID: user.ID, Valid: true}}
+	var changes []models.MemberChange
+	var issues []models.EligibilityIssue
+	err = api.db.Transaction(func(tx *gorm.DB) error {
+		changes, issues, err = reconcileMember(tx, &member, player, change)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			ctx.AbortWithError(http.StatusConflict, fmt.Errorf("member with permit %s already exists", permitID))
+			return
+		}
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to reconcile member: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"member": member, "changes": changes, "issues": issues})
+}
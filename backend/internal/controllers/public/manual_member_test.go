@@ -0,0 +1,158 @@
+package public
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func mockFFTTPlayer(env testEnv, permitID string, body string) {
+	expectedFFTTReq, _ := http.NewRequest(http.MethodGet, "https://fftt.dafunker.com/v1/joueur/"+permitID, nil)
+	env.httpClient.EXPECT().Do(expectedFFTTReq).Return(&http.Response{
+		StatusCode: http.StatusOK,
+		Body:       io.NopCloser(bytes.NewReader([]byte(body))),
+	}, nil).Once()
+}
+
+func TestCreateManualMember(t *testing.T) {
+	t.Run("KnownByFFTT", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		mockFFTTPlayer(env, "123456", `{"nom":"Pierre","prenom":"Jean","licence":"123456","sexe":"M","point":642,"cat":"S"}`)
+
+		res := performRequest("POST", "/api/members/manual", strings.NewReader(`{"PermitID":"123456","FirstName":"Jean","LastName":"Pierre","Sex":"M","Points":500,"Category":"S","Reason":"New licence"}`), map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+		require.Equal(t, http.StatusConflict, res.Code)
+	})
+	t.Run("ForeignPlayer", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		res := performRequest("POST", "/api/members/manual", strings.NewReader(`{"FirstName":"Hans","LastName":"Müller","Sex":"M","Points":900,"Category":"S","ClubName":"TTC Köln","Reason":"German player"}`), map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+		require.Equal(t, http.StatusCreated, res.Code)
+
+		var member models.Member
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&member))
+		require.True(t, strings.HasPrefix(member.PermitID, models.ManualPermitPrefix))
+		require.True(t, member.Manual)
+		require.True(t, member.PendingValidation)
+
+		// Pending members can not confirm entries
+		band := models.Band{Name: "A", Day: 1, Color: models.BandColor_BLUE, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 10}
+		require.NoError(t, env.db.Create(&band).Error)
+		res = performRequest("POST", fmt.Sprintf("/api/members/%s/set-entries", member.ID), strings.NewReader(fmt.Sprintf(`{"BandIDs":["%s"],"SessionID":"%s"}`, band.ID, uuid.New())), map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+		require.Equal(t, http.StatusConflict, res.Code)
+
+		res = performRequest("GET", "/api/members?pending_validation=true", nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+		require.Equal(t, http.StatusOK, res.Code)
+		var members ListMembersMembers
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&members))
+		require.Equal(t, 1, members.Total)
+		require.True(t, members.Members[0].PendingValidation)
+
+		res = performRequest("POST", fmt.Sprintf("/api/admin/members/%s/validate", member.ID), nil, map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+		require.Equal(t, http.StatusForbidden, res.Code)
+
+		res = performRequest("POST", fmt.Sprintf("/api/admin/members/%s/validate", member.ID), nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+		require.Equal(t, http.StatusOK, res.Code)
+		require.NoError(t, env.db.First(&member, member.ID).Error)
+		require.False(t, member.PendingValidation)
+		require.NotNil(t, member.ValidatedAt)
+
+		res = performRequest("POST", fmt.Sprintf("/api/admin/members/%s/validate", member.ID), nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+		require.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
+
+func TestReconcileManualMember(t *testing.T) {
+	t.Run("PointsSync", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		mockFFTTPlayer(env, "7512345", `{"nom":"","prenom":"","licence":"7512345"}`)
+		res := performRequest("POST", "/api/members/manual", strings.NewReader(`{"PermitID":"7512345","FirstName":"chloe","LastName":"lefevre","Sex":"F","Points":500,"Category":"S","Reason":"Licence of the week"}`), map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+		require.Equal(t, http.StatusCreated, res.Code)
+		var member models.Member
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&member))
+
+		// The declared points are too low for the band
+		band := models.Band{Name: "A", Day: 1, Color: models.BandColor_BLUE, SexAllowed: models.BandSex_ALL, MaxPoints: 599, MaxEntries: 10}
+		require.NoError(t, env.db.Create(&band).Error)
+		entry := models.Entry{MemberID: member.ID, BandID: band.ID, Confirmed: true}
+		require.NoError(t, env.db.Create(&entry).Error)
+
+		mockFFTTPlayer(env, "7512345", `{"nom":"LEFEVRE","prenom":"Chloé","licence":"7512345","sexe":"F","point":642,"cat":"S","nomclub":"US Créteil","numclub":"08940001"}`)
+		res = performRequest("POST", "/api/admin/points-sync", nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+		require.Equal(t, http.StatusOK, res.Code)
+		var report PointsSyncReport
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&report))
+		require.Equal(t, PointsSyncReport{Members: 1, Reconciled: 1, OpenIssues: 1}, report)
+
+		require.NoError(t, env.db.First(&member, member.ID).Error)
+		require.False(t, member.Manual)
+		require.False(t, member.PendingValidation)
+		require.NotNil(t, member.ReconciledAt)
+		require.Equal(t, "Chloé", member.FirstName)
+		require.Equal(t, 642.0, member.Points)
+		require.Equal(t, "08940001", member.ClubNumber)
+
+		var changes []models.MemberChange
+		require.NoError(t, env.db.Where("member_id = ? AND field = ?", member.ID, "Points").Find(&changes).Error)
+		require.Len(t, changes, 1)
+		require.Equal(t, models.MemberChangeSource_RECONCILE, changes[0].Source)
+	})
+	t.Run("PlaceholderPermit", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		res := performRequest("POST", "/api/members/manual", strings.NewReader(`{"FirstName":"Chloé","LastName":"Lefèvre","Sex":"F","Points":500,"Category":"S","Reason":"Promotional licence"}`), map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+		require.Equal(t, http.StatusCreated, res.Code)
+		var member models.Member
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&member))
+		// Admins declare validated members
+		require.False(t, member.PendingValidation)
+
+		res = performRequest("POST", fmt.Sprintf("/api/admin/members/%s/reconcile", member.ID), strings.NewReader(`{}`), map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+		require.Equal(t, http.StatusBadRequest, res.Code)
+
+		mockFFTTPlayer(env, "7512345", `{"nom":"LEFEVRE","prenom":"Chloé","licence":"7512345","sexe":"F","point":520,"cat":"S","nomclub":"US Créteil"}`)
+		res = performRequest("POST", fmt.Sprintf("/api/admin/members/%s/reconcile", member.ID), strings.NewReader(`{"PermitID":"7512345"}`), map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		require.NoError(t, env.db.First(&member, member.ID).Error)
+		require.Equal(t, "7512345", member.PermitID)
+		require.False(t, member.Manual)
+	})
+}
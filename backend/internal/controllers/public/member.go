@@ -8,7 +8,9 @@ import (
 	"time"
 
 	"github.com/SuperPingPong/tournoi/internal/auth"
+	"github.com/SuperPingPong/tournoi/internal/fftt"
 	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/SuperPingPong/tournoi/internal/pricing"
 	jwt "github.com/appleboy/gin-jwt/v2"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -19,10 +21,22 @@ import (
 type ListMembersEntry struct {
 	BandID         uuid.UUID
 	BandName       string
+	BandDay        int
 	BandPrice      int
+	BandStartTime  string
 	BandMaxEntries int
 	BandRank       int
 	CreatedAt      time.Time
+	// CheckInStatus is empty until the player is checked in, Promoted is set on the waiting list entries given a place
+	CheckInStatus string
+	Promoted      bool
+	// Price is what the member pays for the band once the pricing rules are applied
+	Price       int
+	Adjustments []pricing.Adjustment
+}
+
+func (e ListMembersEntry) due() bool {
+	return entryDue(e.CheckInStatus, e.BandRank > e.BandMaxEntries, e.Promoted)
 }
 
 type ListMembersUser struct {
@@ -40,8 +54,12 @@ type ListMembersMember struct {
 	Category   string
 	ClubName   string
 	PermitType string
-	Entries    []ListMembersEntry
-	User       ListMembersUser
+	// Manual members were declared without an FFTT record, see CreateManualMember
+	Manual            bool
+	PendingValidation bool
+	Entries           []ListMembersEntry
+	Price             pricing.Breakdown
+	User              ListMembersUser
 }
 
 type ListMembersMembers struct {
@@ -85,6 +103,7 @@ func (api *API) ListMembers(ctx *gin.Context) {
 		Scopes(FilterByUserID(user)).
 		Scopes(searchMembersScope(ctx.Query("search"), *user)).
 		Scopes(filterByPermitID(ctx.Query("permit_id"))).
+		Scopes(filterByPendingValidation(ctx.Query("pending_validation"))).
 		Joins("JOIN users ON users.id = members.user_id").
 		Select("COUNT(*) AS total_count").
 		Count(&totalCount).Error; err != nil {
@@ -98,6 +117,7 @@ func (api *API) ListMembers(ctx *gin.Context) {
 		Scopes(FilterByUserID(user)).
 		Scopes(searchMembersScope(ctx.Query("search"), *user)).
 		Scopes(filterByPermitID(ctx.Query("permit_id"))).
+		Scopes(filterByPendingValidation(ctx.Query("pending_validation"))).
 		Scopes(Paginate(page, pageSize)).
 		Joins("JOIN users ON users.id = members.user_id").
 		Select("members.*").
@@ -114,21 +134,82 @@ func (api *API) ListMembers(ctx *gin.Context) {
 		Total:   int(totalCount),
 	}
 
+	rules, err := getPricingRules(api.db)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list members: %w", err))
+		return
+	}
 	for _, member := range members {
-		var memberEntries []ListMembersEntry
-		query := `
+		listMember, err := buildListMembersMember(api.db, rules, member)
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list members: %w", err))
+			return
+		}
+
+		// disable search by email if not admin
+		if user.IsAdmin {
+			if err := api.db.Model(&models.User{}).
+				Select("users.id AS user_id, users.email AS user_email").
+				Joins("JOIN members ON members.user_id = users.id").
+				Where("members.id = ?", member.ID.String()).
+				Scan(&listMember.User).Error; err != nil {
+				ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list members: %w", err))
+				return
+			}
+		}
+		result.Members = append(result.Members, listMember)
+	}
+
+	ctx.JSON(http.StatusOK, &result)
+}
+
+// buildListMembersMember lists the entries of member priced by rules, the listings load the rules once for all their members
+func buildListMembersMember(db *gorm.DB, rules pricing.Rules, member models.Member) (ListMembersMember, error) {
+	memberEntries, err := listMemberEntries(db, member.ID)
+	if err != nil {
+		return ListMembersMember{}, err
+	}
+	price := priceMemberEntries(rules, member, memberEntries)
+
+	return ListMembersMember{
+		ID:                member.ID,
+		PermitID:          member.PermitID,
+		FirstName:         member.FirstName,
+		LastName:          member.LastName,
+		Sex:               member.Sex,
+		Points:            member.Points,
+		Category:          member.Category,
+		ClubName:          member.ClubName,
+		PermitType:        member.PermitType,
+		Manual:            member.Manual,
+		PendingValidation: member.PendingValidation,
+		Entries:           memberEntries,
+		Price:             price,
+	}, nil
+}
+
+// listMemberEntries returns the confirmed entries of a member along with their rank in each band
+func listMemberEntries(db *gorm.DB, memberID uuid.UUID) ([]ListMembersEntry, error) {
+	var memberEntries []ListMembersEntry
+	query := `
             SELECT
               subquery.band_id,
               subquery.band_name,
+              subquery.band_day,
               subquery.band_price,
+              bands.start_time AS band_start_time,
               subquery.created_at,
               subquery.entry_index AS band_rank,
-              bands.max_entries AS band_max_entries
+              bands.max_entries AS band_max_entries,
+              COALESCE(check_ins.status, '') AS check_in_status,
+              COALESCE(check_ins.promoted, FALSE) AS promoted
             FROM (
               SELECT
+                entries.id AS entry_id,
                 entries.band_id,
                 bands.created_at AS band_created_at,
                 bands.name AS band_name,
+                bands.day AS band_day,
                 bands.price AS band_price,
                 entries.created_at,
                 ROW_NUMBER() OVER (PARTITION BY entries.band_id ORDER BY entries.created_at ASC) AS entry_index,
@@ -142,44 +223,17 @@ func (api *API) ListMembers(ctx *gin.Context) {
             ) AS subquery
             JOIN
               bands ON bands.id = subquery.band_id
+            LEFT JOIN
+              check_ins ON check_ins.entry_id = subquery.entry_id
             WHERE
               subquery.member_id = ?
             ORDER BY
               subquery.band_created_at ASC;
         `
-		if err := api.db.Raw(query, member.ID.String()).Scan(&memberEntries).Error; err != nil {
-			ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list members: %w", err))
-			return
-		}
-
-		var memberUser ListMembersUser
-		// disable search by email if not admin
-		if user.IsAdmin {
-			if err := api.db.Model(&models.User{}).
-				Select("users.id AS user_id, users.email AS user_email").
-				Joins("JOIN members ON members.user_id = users.id").
-				Where("members.id = ?", member.ID.String()).
-				Scan(&memberUser).Error; err != nil {
-				ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list members: %w", err))
-				return
-			}
-		}
-		result.Members = append(result.Members, ListMembersMember{
-			ID:         member.ID,
-			PermitID:   member.PermitID,
-			FirstName:  member.FirstName,
-			LastName:   member.LastName,
-			Sex:        member.Sex,
-			Points:     member.Points,
-			Category:   member.Category,
-			ClubName:   member.ClubName,
-			PermitType: member.PermitType,
-			Entries:    memberEntries,
-			User:       memberUser,
-		})
+	if err := db.Raw(query, memberID.String()).Scan(&memberEntries).Error; err != nil {
+		return nil, err
 	}
-
-	ctx.JSON(http.StatusOK, &result)
+	return memberEntries, nil
 }
 
 func searchMembersScope(search string, user models.User) func(db *gorm.DB) *gorm.DB {
@@ -209,6 +263,16 @@ func filterByPermitID(permitID string) func(db *gorm.DB) *gorm.DB {
 	}
 }
 
+// filterByPendingValidation keeps the manual members waiting for a validation when pending_validation is true
+func filterByPendingValidation(pendingValidation string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if pendingValidation != "true" {
+			return db
+		}
+		return db.Where("members.pending_validation IS TRUE")
+	}
+}
+
 func (api *API) GetMember(ctx *gin.Context) {
 	claims := jwt.ExtractClaims(ctx)
 	userID := uuid.MustParse(claims[auth.IdentityKey].(string))
@@ -245,6 +309,42 @@ func (api *API) GetMember(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, &member)
 }
 
+func (api *API) getMember(ctx *gin.Context) (models.Member, bool) {
+	var member models.Member
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid member id: %s", ctx.Param("id")))
+		return member, false
+	}
+	if err = api.db.First(&member, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("member %s not found", id))
+			return member, false
+		}
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get member: %w", err))
+		return member, false
+	}
+	return member, true
+}
+
+// getOwnedMember returns the member of the :id parameter, which has to be managed by the current user unless it is an admin
+func (api *API) getOwnedMember(ctx *gin.Context) (models.Member, bool) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return models.Member{}, false
+	}
+	member, ok := api.getMember(ctx)
+	if !ok {
+		return member, false
+	}
+	if member.UserID != user.ID && !user.IsAdmin {
+		ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("member %s not found", member.ID))
+		return member, false
+	}
+	return member, true
+}
+
 type CreateMemberInput struct {
 	PermitID string `binding:"required,min=2"`
 }
@@ -261,9 +361,13 @@ func (api *API) CreateMember(ctx *gin.Context) {
 		return
 	}
 
-	data, err := api.GetFFTTPlayerData(input.PermitID)
+	data, err := api.fftt.GetPlayer(ctx.Request.Context(), input.PermitID)
 	if err != nil {
-		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get member data from FFTT: %w", err))
+		ctx.AbortWithError(ffttErrorStatus(err), fmt.Errorf("failed to get member data from FFTT: %w", err))
+		return
+	}
+	api.rememberPlayers(*data)
+	if abortOnLicenceIssue(ctx, api.db, *data) {
 		return
 	}
 
@@ -276,6 +380,7 @@ func (api *API) CreateMember(ctx *gin.Context) {
 		Points:     data.Points,
 		Category:   data.Category,
 		ClubName:   data.ClubName,
+		ClubNumber: data.ClubNumber,
 		PermitType: data.PermitType,
 	}
 	err = api.db.Create(&member).Error
@@ -292,7 +397,174 @@ func (api *API) CreateMember(ctx *gin.Context) {
 }
 
 type UpdateMemberInput struct {
-	PermitID string `binding:"required,min=2"`
+	// Refresh reloads the member from the FFTT, it is the only change allowed to owners
+	Refresh bool
+
+	// The overrides are reserved to admins, and require a Reason
+	Points   *float64 `binding:"omitempty,min=0"`
+	Sex      *string  `binding:"omitempty,oneof=M F"`
+	Category *string
+	Reason   string
+}
+
+// memberFields are the fields of a member recorded in its history when they change
+var memberFields = []struct {
+	name   string
+	column string
+	value  func(member models.Member) string
+}{
+	{"PermitID", "permit_id", func(member models.Member) string { return member.PermitID }},
+	{"FirstName", "first_name", func(member models.Member) string { return member.FirstName }},
+	{"LastName", "last_name", func(member models.Member) string { return member.LastName }},
+	{"Sex", "sex", func(member models.Member) string { return member.Sex }},
+	{"Points", "points", func(member models.Member) string { return strconv.FormatFloat(member.Points, 'f', -1, 64) }},
+	{"Category", "category", func(member models.Member) string { return member.Category }},
+	{"ClubName", "club_name", func(member models.Member) string { return member.ClubName }},
+	{"ClubNumber", "club_number", func(member models.Member) string { return member.ClubNumber }},
+	{"PermitType", "permit_type", func(member models.Member) string { return member.PermitType }},
+}
+
+// applyMemberChanges saves the fields of updated which differ from member, recording a copy of change for each of them
+func applyMemberChanges(tx *gorm.DB, member *models.Member, updated models.Member, change models.MemberChange) ([]models.MemberChange, error) {
+	changes := []models.MemberChange{}
+	var columns []string
+	for _, field := range memberFields {
+		previousValue, value := field.value(*member), field.value(updated)
+		if previousValue == value {
+			continue
+		}
+
+		fieldChange := change
+		fieldChange.MemberID = member.ID
+		fieldChange.Field = field.name
+		fieldChange.PreviousValue = previousValue
+		fieldChange.Value = value
+		changes = append(changes, fieldChange)
+		columns = append(columns, field.column)
+	}
+	if len(changes) == 0 {
+		return changes, nil
+	}
+
+	if err := tx.Create(&changes).Error; err != nil {
+		return nil, fmt.Errorf("failed to create member changes: %w", err)
+	}
+	if err := tx.Model(member).Select(columns).Updates(&updated).Error; err != nil {
+		return nil, fmt.Errorf("failed to update member: %w", err)
+	}
+	*member = updated
+	return changes, nil
+}
+
+// UpdateMember refreshes a member from the FFTT and, for admins, overrides some of its fields.
+// The entries of the member are checked again, those it can not play anymore are reported as eligibility issues.
+func (api *API) UpdateMember(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var input UpdateMemberInput
+	if err = ctx.ShouldBindBodyWith(&input, binding.JSON); err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
+		return
+	}
+	override := input.Points != nil || input.Sex != nil || input.Category != nil
+	if !input.Refresh && !override {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: nothing to update"))
+		return
+	}
+	if override && !user.IsAdmin {
+		ctx.AbortWithError(http.StatusForbidden, fmt.Errorf("user %s should be admin to override member fields", user.ID))
+		return
+	}
+	if override && input.Reason == "" {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: a reason is required to override member fields"))
+		return
+	}
+
+	member, ok := api.getOwnedMember(ctx)
+	if !ok {
+		return
+	}
+
+	// The FFTT is called before the transaction, not to hold it during the call
+	var player *fftt.Player
+	if input.Refresh {
+		player, err = api.freshFFTTPlayer(ctx.Request.Context(), member.PermitID)
+		if err != nil {
+			ctx.AbortWithError(ffttErrorStatus(err), fmt.Errorf("failed to get member data from FFTT: %w", err))
+			return
+		}
+	}
+
+	change := models.MemberChange{
+		ChangedBy:             uuid.NullUUID{UUID: user.ID, Valid: true},
+		ChangedByImpersonator: ExtractImpersonatorFromContext(ctx),
+	}
+	changes := []models.MemberChange{}
+	var issues []models.EligibilityIssue
+	err = api.db.Transaction(func(tx *gorm.DB) error {
+		if player != nil && member.Manual {
+			reconcileChanges, _, err := reconcileMember(tx, &member, player, change)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, reconcileChanges...)
+		} else if player != nil {
+			updated := member
+			updated.FirstName = player.FirstName
+			updated.LastName = player.LastName
+			updated.Sex = player.Sex
+			updated.Points = player.Points
+			updated.Category = player.Category
+			updated.ClubName = player.ClubName
+			// Some FFTT sources do not return the club number, the known one is kept then
+			if player.ClubNumber != "" {
+				updated.ClubNumber = player.ClubNumber
+			}
+			updated.PermitType = player.PermitType
+
+			change.Source = models.MemberChangeSource_REFRESH
+			refreshChanges, err := applyMemberChanges(tx, &member, updated, change)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, refreshChanges...)
+		}
+
+		if override {
+			updated := member
+			if input.Points != nil {
+				updated.Points = *input.Points
+			}
+			if input.Sex != nil {
+				updated.Sex = *input.Sex
+			}
+			if input.Category != nil {
+				updated.Category = *input.Category
+			}
+
+			change.Source = models.MemberChangeSource_OVERRIDE
+			change.Reason = input.Reason
+			overrideChanges, err := applyMemberChanges(tx, &member, updated, change)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, overrideChanges...)
+		}
+
+		var err error
+		issues, err = checkMemberEntries(tx, member)
+		return err
+	})
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to update member: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"member": member, "changes": changes, "issues": issues})
 }
 
 func (api *API) DeleteMember(ctx *gin.Context) {
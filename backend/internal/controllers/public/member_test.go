@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
@@ -510,7 +511,7 @@ func TestCreateMember(t *testing.T) {
 		expectedFFTTReq, err := http.NewRequest(http.MethodGet, "https://fftt.dafunker.com/v1/joueur/"+permitID, nil)
 		mockFFTTRes := fmt.Sprintf(`{"nom":"%s","prenom":"%s","licence":"%s","sexe":"%s","point":%f,"cat":"%s","nomclub":"%s","type":"%s"}`, lastName, firstName, permitID, sex, point, category, clubName, permitType)
 		r := io.NopCloser(bytes.NewReader([]byte(mockFFTTRes)))
-		env.api.httpClient.(*MockHTTPClient).EXPECT().Do(expectedFFTTReq).Return(&http.Response{
+		env.httpClient.EXPECT().Do(expectedFFTTReq).Return(&http.Response{
 			StatusCode: http.StatusOK,
 			Body:       r,
 		}, nil)
@@ -574,3 +575,106 @@ func TestCreateMember(t *testing.T) {
 		require.Equal(t, "invalid input: EOF", actual["error"])
 	})
 }
+
+func TestUpdateMember(t *testing.T) {
+	setup := func(t *testing.T, env testEnv) (models.Member, models.Band) {
+		band := models.Band{Name: "A", Day: 1, Color: models.BandColor_BLUE, SexAllowed: models.BandSex_ALL, MaxPoints: 599, MaxEntries: 10}
+		require.NoError(t, env.db.Create(&band).Error)
+
+		member := models.Member{FirstName: "Jean", LastName: "Pierre", Sex: "M", Points: 580, Category: "S", ClubName: "Caillouville", PermitType: "T", PermitID: "123456", UserID: env.user.ID}
+		require.NoError(t, env.db.Create(&member).Error)
+		require.NoError(t, env.db.Create(&models.Entry{MemberID: member.ID, BandID: band.ID, Confirmed: true}).Error)
+		return member, band
+	}
+	update := func(env testEnv, jwt string, memberID uuid.UUID, input map[string]interface{}) *httptest.ResponseRecorder {
+		body, err := json.Marshal(input)
+		require.NoError(t, err)
+		return performRequest("PUT", fmt.Sprintf("/api/members/%s", memberID), bytes.NewBuffer(body), map[string]string{
+			"Authorization": "Bearer " + jwt,
+		}, env.api.router)
+	}
+	t.Run("Refresh", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		member, _ := setup(t, env)
+
+		expectedFFTTReq, err := http.NewRequest(http.MethodGet, "https://fftt.dafunker.com/v1/joueur/123456", nil)
+		require.NoError(t, err)
+		env.httpClient.EXPECT().Do(expectedFFTTReq).Return(&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader([]byte(`{"nom":"Pierre","prenom":"Jean","licence":"123456","sexe":"M","point":612,"cat":"S","nomclub":"Caillouville","numclub":"08950103","type":"T"}`))),
+		}, nil)
+
+		res := update(env, env.jwt, member.ID, map[string]interface{}{"Refresh": true})
+
+		require.Equal(t, http.StatusOK, res.Code)
+		var got struct {
+			Member  models.Member
+			Changes []models.MemberChange
+			Issues  []models.EligibilityIssue
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		require.Equal(t, 612.0, got.Member.Points)
+		require.Equal(t, "08950103", got.Member.ClubNumber)
+		// The points and the club number
+		require.Len(t, got.Changes, 2)
+		require.Equal(t, models.MemberChangeSource_REFRESH, got.Changes[0].Source)
+		require.Equal(t, env.user.ID, got.Changes[0].ChangedBy.UUID)
+		require.Len(t, got.Issues, 1)
+		require.Equal(t, models.EligibilityReason_MAX_POINTS, got.Issues[0].Reason)
+	})
+	t.Run("Override", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		member, _ := setup(t, env)
+
+		res := update(env, env.adminJWT, member.ID, map[string]interface{}{"Sex": "F", "Category": "V1", "Reason": "Erreur de saisie FFTT"})
+
+		require.Equal(t, http.StatusOK, res.Code)
+		require.NoError(t, env.db.First(&member, member.ID).Error)
+		require.Equal(t, "F", member.Sex)
+		require.Equal(t, "V1", member.Category)
+		require.Equal(t, 580.0, member.Points)
+
+		var changes []models.MemberChange
+		require.NoError(t, env.db.Where("member_id = ?", member.ID).Order("field ASC").Find(&changes).Error)
+		require.Len(t, changes, 2)
+		require.Equal(t, "Category", changes[0].Field)
+		require.Equal(t, "S", changes[0].PreviousValue)
+		require.Equal(t, "Sex", changes[1].Field)
+		require.Equal(t, "Erreur de saisie FFTT", changes[1].Reason)
+		require.Equal(t, models.MemberChangeSource_OVERRIDE, changes[1].Source)
+	})
+	t.Run("OverrideWithoutReason", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		member, _ := setup(t, env)
+
+		res := update(env, env.adminJWT, member.ID, map[string]interface{}{"Points": 500})
+		require.Equal(t, http.StatusBadRequest, res.Code)
+	})
+	t.Run("OverrideNotAdmin", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		member, _ := setup(t, env)
+
+		res := update(env, env.jwt, member.ID, map[string]interface{}{"Points": 500, "Reason": "Je le vaux bien"})
+		require.Equal(t, http.StatusForbidden, res.Code)
+	})
+	t.Run("OtherUser", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		otherUser := models.User{Email: "hdupont@example.com"}
+		require.NoError(t, env.db.Create(&otherUser).Error)
+		member := models.Member{FirstName: "Hervé", LastName: "Dupont", Sex: "M", PermitID: "000001", UserID: otherUser.ID}
+		require.NoError(t, env.db.Create(&member).Error)
+
+		res := update(env, env.jwt, member.ID, map[string]interface{}{"Refresh": true})
+		require.Equal(t, http.StatusNotFound, res.Code)
+	})
+}
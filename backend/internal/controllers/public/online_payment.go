@@ -0,0 +1,485 @@
+package public
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/SuperPingPong/tournoi/internal/payments"
+	"github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// checkoutExpiration is how long the entries of an unpaid checkout are not offered in another checkout
+	checkoutExpiration     = time.Hour
+	onlinePaymentsInterval = time.Minute
+	maxWebhookSize         = 1 << 20
+)
+
+var onlinePaymentsDisabledError = errors.New("online payments are disabled, see PAYMENT_PROVIDER")
+
+// checkoutLines returns the entries to pay online: the entries of the main draw within the balance of their day,
+// and the entries of the waiting list whose payment is held until promotion. Entries already covered are skipped.
+func checkoutLines(ledger []LedgerDay, covered map[uuid.UUID]bool) []models.OnlinePaymentLine {
+	var lines []models.OnlinePaymentLine
+	for _, day := range ledger {
+		remaining := day.Balance
+		for _, entry := range day.Entries {
+			// Free entries are not paid
+			if covered[entry.EntryID] || entry.Status == models.CheckInStatus_SCRATCHED || entry.Price == 0 {
+				continue
+			}
+			if !entry.Waiting || entry.Promoted {
+				// Part of the day was paid on site
+				if entry.Price > remaining {
+					continue
+				}
+				remaining -= entry.Price
+			}
+			lines = append(lines, models.OnlinePaymentLine{
+				EntryID: entry.EntryID,
+				Day:     day.Day,
+				Amount:  entry.Price,
+				Status:  models.OnlinePaymentLineStatus_PENDING,
+			})
+		}
+	}
+	return lines
+}
+
+// coveredEntries returns the entries already paid online, or being paid
+func coveredEntries(db *gorm.DB, entryIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	var lines []models.OnlinePaymentLine
+	if err := db.
+		Where("entry_id IN ?", entryIDs).
+		Where("status IN ? OR (status = ? AND created_at > ?)",
+			[]string{models.OnlinePaymentLineStatus_HELD, models.OnlinePaymentLineStatus_SETTLED},
+			models.OnlinePaymentLineStatus_PENDING, time.Now().Add(-checkoutExpiration)).
+		Find(&lines).Error; err != nil {
+		return nil, fmt.Errorf("failed to list online payment lines: %w", err)
+	}
+	return lo.SliceToMap(lines, func(line models.OnlinePaymentLine) (uuid.UUID, bool) {
+		return line.EntryID, true
+	}), nil
+}
+
+// checkoutURL returns EXTERNAL_URL with ?payment=<result>, the frontend tells the player how the payment went
+func checkoutURL(result string) string {
+	externalURL, err := url.Parse(os.Getenv("EXTERNAL_URL"))
+	if err != nil {
+		externalURL = &url.URL{}
+	}
+	externalURL.RawQuery = url.Values{"payment": {result}}.Encode()
+	return externalURL.String()
+}
+
+// CreateCheckout opens a checkout of the payment provider for the entries of a member which are not paid yet
+func (api *API) CreateCheckout(ctx *gin.Context) {
+	if api.payments == nil {
+		ctx.AbortWithError(http.StatusServiceUnavailable, onlinePaymentsDisabledError)
+		return
+	}
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	member, ok := api.getOwnedMember(ctx)
+	if !ok {
+		return
+	}
+
+	// The payment is saved before the checkout is opened, so that no checkout of the provider is left without payment
+	var payment models.OnlinePayment
+	err = api.db.Transaction(func(tx *gorm.DB) error {
+		// The same entries must not be paid by two checkouts
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Member{}, member.ID).Error; err != nil {
+			return fmt.Errorf("failed to lock member: %w", err)
+		}
+		ledger, err := memberLedger(tx, member)
+		if err != nil {
+			return err
+		}
+		entryIDs := lo.FlatMap(ledger, func(day LedgerDay, _ int) []uuid.UUID {
+			return lo.Map(day.Entries, func(entry DueEntry, _ int) uuid.UUID { return entry.EntryID })
+		})
+		covered, err := coveredEntries(tx, entryIDs)
+		if err != nil {
+			return err
+		}
+		lines := checkoutLines(ledger, covered)
+		if len(lines) == 0 {
+			return nil
+		}
+
+		payment = models.OnlinePayment{
+			MemberID: member.ID,
+			UserID:   user.ID,
+			Provider: api.payments.Name(),
+			Amount:   lo.SumBy(lines, func(line models.OnlinePaymentLine) int { return line.Amount }),
+			Status:   models.OnlinePaymentStatus_PENDING,
+			Lines:    lines,
+		}
+		if err = tx.Create(&payment).Error; err != nil {
+			return fmt.Errorf("failed to create online payment: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if len(payment.Lines) == 0 {
+		ctx.AbortWithError(http.StatusConflict, fmt.Errorf("nothing to pay online for member %s", member.ID))
+		return
+	}
+
+	checkout, err := api.payments.CreateCheckout(ctx, payments.CheckoutRequest{
+		Reference:   payment.ID.String(),
+		Description: fmt.Sprintf("Tournoi de Lognes - %s %s", member.FirstName, member.LastName),
+		Email:       user.Email,
+		Amount:      payment.Amount * 100,
+		SuccessURL:  checkoutURL("success"),
+		CancelURL:   checkoutURL("cancel"),
+	})
+	if err != nil {
+		// The entries can be paid by another checkout right away
+		if err := api.db.Transaction(func(tx *gorm.DB) error { return failOnlinePayment(tx, payment) }); err != nil {
+			log.Printf("online payments: %s", err)
+			sentry.CaptureException(err)
+		}
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to create checkout: %w", err))
+		return
+	}
+	payment.CheckoutID = checkout.ID
+	payment.CheckoutURL = checkout.URL
+	if err = api.db.Model(&models.OnlinePayment{ID: payment.ID}).Updates(models.OnlinePayment{
+		CheckoutID:  checkout.ID,
+		CheckoutURL: checkout.URL,
+	}).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to update online payment: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, &payment)
+}
+
+// failOnlinePayment marks a checkout and its lines as failed, their entries can be paid again
+func failOnlinePayment(tx *gorm.DB, payment models.OnlinePayment) error {
+	if err := tx.Model(&models.OnlinePayment{}).
+		Where("id = ?", payment.ID).
+		Update("status", models.OnlinePaymentStatus_FAILED).Error; err != nil {
+		return fmt.Errorf("failed to update online payment: %w", err)
+	}
+	if err := tx.Model(models.OnlinePaymentLine{}).
+		Where("online_payment_id = ?", payment.ID).
+		Update("status", models.OnlinePaymentLineStatus_FAILED).Error; err != nil {
+		return fmt.Errorf("failed to update online payment lines: %w", err)
+	}
+	return nil
+}
+
+// ListOnlinePayments lists the checkouts of a member with the entries they pay
+func (api *API) ListOnlinePayments(ctx *gin.Context) {
+	member, ok := api.getOwnedMember(ctx)
+	if !ok {
+		return
+	}
+
+	onlinePayments := []models.OnlinePayment{}
+	if err := api.db.Preload("Lines").
+		Where("member_id = ?", member.ID).
+		Order("created_at DESC").
+		Find(&onlinePayments).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list online payments: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"payments": onlinePayments})
+}
+
+// PaymentWebhook receives the signed notifications of the payment provider
+func (api *API) PaymentWebhook(ctx *gin.Context) {
+	if api.payments == nil {
+		ctx.AbortWithError(http.StatusServiceUnavailable, onlinePaymentsDisabledError)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(ctx.Request.Body, maxWebhookSize))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("failed to read webhook: %w", err))
+		return
+	}
+	event, err := api.payments.ParseWebhook(ctx.Request.Header, body)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err)
+		return
+	}
+
+	var notFound bool
+	err = api.db.Transaction(func(tx *gorm.DB) error {
+		var payment models.OnlinePayment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("provider = ? AND checkout_id = ?", api.payments.Name(), event.CheckoutID).
+			First(&payment).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				notFound = true
+				return nil
+			}
+			return fmt.Errorf("failed to get online payment: %w", err)
+		}
+		// Providers send the same event again until they get an answer
+		if payment.Status != models.OnlinePaymentStatus_PENDING {
+			return nil
+		}
+
+		switch event.Type {
+		case payments.EventType_PAID:
+			if event.Amount != payment.Amount*100 {
+				return fmt.Errorf("paid %d cents instead of %d € for checkout %s", event.Amount, payment.Amount, payment.CheckoutID)
+			}
+			if err := tx.Model(&payment).Updates(models.OnlinePayment{
+				Status:            models.OnlinePaymentStatus_PAID,
+				ProviderPaymentID: event.PaymentID,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to update online payment: %w", err)
+			}
+			// Every entry is held until it is known to be in the main draw
+			if err := tx.Model(models.OnlinePaymentLine{}).
+				Where("online_payment_id = ?", payment.ID).
+				Update("status", models.OnlinePaymentLineStatus_HELD).Error; err != nil {
+				return fmt.Errorf("failed to update online payment lines: %w", err)
+			}
+		case payments.EventType_FAILED:
+			return failOnlinePayment(tx, payment)
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if notFound {
+		ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("checkout %s not found", event.CheckoutID))
+		return
+	}
+
+	// Settle the payment right away rather than at the next run
+	if event.Type == payments.EventType_PAID {
+		if _, err = api.SettleOnlinePayments(ctx); err != nil {
+			log.Printf("online payments: %s", err)
+			sentry.CaptureException(err)
+		}
+	}
+	ctx.Status(http.StatusOK)
+}
+
+// onlineRefundDeadline returns the deadline of automatic refunds from ONLINE_REFUND_DEADLINE formatted as 2006-01-02 15:04,
+// the start of the tournament by default. Without deadline, only the held payments are refunded.
+func onlineRefundDeadline() (time.Time, error) {
+	value := os.Getenv("ONLINE_REFUND_DEADLINE")
+	if value == "" {
+		startDate, err := tournamentStartDate()
+		if errors.Is(err, tournamentDatesNotConfiguredError) {
+			return time.Time{}, nil
+		}
+		return startDate, err
+	}
+
+	location, err := time.LoadLocation(tournamentTimezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to load timezone: %w", err)
+	}
+	deadline, err := time.ParseInLocation("2006-01-02 15:04", value, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid ONLINE_REFUND_DEADLINE: %w", err)
+	}
+	return deadline, nil
+}
+
+// heldDueLinesQuery lists the held lines whose entry is now in the main draw
+const heldDueLinesQuery = `
+	SELECT online_payment_lines.id
+	FROM online_payment_lines
+	JOIN (` + rankedEntriesQuery + `) AS ranked ON ranked.id = online_payment_lines.entry_id
+	JOIN bands ON bands.id = ranked.band_id
+	LEFT JOIN check_ins ON check_ins.entry_id = ranked.id
+	WHERE online_payment_lines.status = 'held'
+	  AND (ranked.rank <= bands.max_entries OR check_ins.promoted IS TRUE)
+`
+
+// withdrawnLinesQuery lists the lines to refund: the held lines of withdrawn entries,
+// and the settled lines of the entries withdrawn before the deadline
+const withdrawnLinesQuery = `
+	SELECT online_payment_lines.id
+	FROM online_payment_lines
+	JOIN entries ON entries.id = online_payment_lines.entry_id
+	WHERE entries.deleted_at IS NOT NULL
+	  AND (online_payment_lines.status = 'held' OR (online_payment_lines.status = 'settled' AND entries.deleted_at < ?))
+`
+
+// unpromotedLinesQuery lists the held lines of the waiting list entries which did not get a place,
+// once the check in of their band is closed or, when the parameter is true, once the refund deadline passed
+const unpromotedLinesQuery = `
+	SELECT online_payment_lines.id
+	FROM online_payment_lines
+	JOIN (` + rankedEntriesQuery + `) AS ranked ON ranked.id = online_payment_lines.entry_id
+	JOIN bands ON bands.id = ranked.band_id
+	LEFT JOIN check_ins ON check_ins.entry_id = ranked.id
+	WHERE online_payment_lines.status = 'held'
+	  AND ranked.rank > bands.max_entries AND check_ins.promoted IS NOT TRUE
+	  AND (bands.check_in_closed_at IS NOT NULL OR ?)
+`
+
+// SettleOnlinePayments records in the ledgers the held payments of the entries now in the main draw,
+// refunds the payments of withdrawn entries and of the waiting list which did not get a place,
+// and returns how many lines were processed
+func (api *API) SettleOnlinePayments(ctx context.Context) (int, error) {
+	if api.payments == nil {
+		return 0, nil
+	}
+	deadline, err := onlineRefundDeadline()
+	if err != nil {
+		return 0, err
+	}
+
+	var settleIDs []uuid.UUID
+	if err = api.db.Raw(heldDueLinesQuery).Scan(&settleIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to list held payments: %w", err)
+	}
+	var refundIDs []uuid.UUID
+	if err = api.db.Raw(withdrawnLinesQuery, deadline).Scan(&refundIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to list payments to refund: %w", err)
+	}
+	var unpromotedIDs []uuid.UUID
+	deadlinePassed := !deadline.IsZero() && time.Now().After(deadline)
+	if err = api.db.Raw(unpromotedLinesQuery, deadlinePassed).Scan(&unpromotedIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to list held payments to refund: %w", err)
+	}
+	refundIDs = append(refundIDs, unpromotedIDs...)
+
+	var processed int
+	for _, id := range settleIDs {
+		if err = api.settleOnlinePaymentLine(id); err != nil {
+			return processed, fmt.Errorf("failed to settle online payment line %s: %w", id, err)
+		}
+		processed++
+	}
+	for _, id := range refundIDs {
+		if err = api.refundOnlinePaymentLine(ctx, id); err != nil {
+			return processed, fmt.Errorf("failed to refund online payment line %s: %w", id, err)
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// lockOnlinePaymentLine locks the line if its status is one of statuses, it returns false otherwise
+func lockOnlinePaymentLine(tx *gorm.DB, id uuid.UUID, statuses ...string) (models.OnlinePaymentLine, bool, error) {
+	var line models.OnlinePaymentLine
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("status IN ?", statuses).
+		First(&line, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return line, false, nil
+	}
+	if err != nil {
+		return line, false, fmt.Errorf("failed to lock online payment line: %w", err)
+	}
+	return line, true, nil
+}
+
+func (api *API) settleOnlinePaymentLine(id uuid.UUID) error {
+	return api.db.Transaction(func(tx *gorm.DB) error {
+		line, ok, err := lockOnlinePaymentLine(tx, id, models.OnlinePaymentLineStatus_HELD)
+		if err != nil || !ok {
+			return err
+		}
+		var payment models.OnlinePayment
+		if err = tx.First(&payment, line.OnlinePaymentID).Error; err != nil {
+			return fmt.Errorf("failed to get online payment: %w", err)
+		}
+
+		if err = tx.Create(&models.Payment{
+			MemberID:            payment.MemberID,
+			Day:                 line.Day,
+			Kind:                models.PaymentKind_PAYMENT,
+			Method:              models.PaymentMethod_ONLINE,
+			Amount:              line.Amount,
+			OnlinePaymentLineID: uuid.NullUUID{UUID: line.ID, Valid: true},
+		}).Error; err != nil {
+			return fmt.Errorf("failed to create payment: %w", err)
+		}
+		return tx.Model(&line).Update("status", models.OnlinePaymentLineStatus_SETTLED).Error
+	})
+}
+
+func (api *API) refundOnlinePaymentLine(ctx context.Context, id uuid.UUID) error {
+	return api.db.Transaction(func(tx *gorm.DB) error {
+		line, ok, err := lockOnlinePaymentLine(tx, id, models.OnlinePaymentLineStatus_HELD, models.OnlinePaymentLineStatus_SETTLED)
+		if err != nil || !ok {
+			return err
+		}
+		var payment models.OnlinePayment
+		if err = tx.First(&payment, line.OnlinePaymentID).Error; err != nil {
+			return fmt.Errorf("failed to get online payment: %w", err)
+		}
+
+		// The line is the idempotency key, should the transaction fail after the refund
+		if err = api.payments.Refund(ctx, payment.ProviderPaymentID, line.Amount*100, line.ID.String()); err != nil {
+			return fmt.Errorf("failed to refund: %w", err)
+		}
+		// Held payments were never recorded in the ledger
+		if line.Status == models.OnlinePaymentLineStatus_SETTLED {
+			if err = tx.Create(&models.Payment{
+				MemberID:            payment.MemberID,
+				Day:                 line.Day,
+				Kind:                models.PaymentKind_REFUND,
+				Method:              models.PaymentMethod_ONLINE,
+				Amount:              line.Amount,
+				Comment:             "Withdrawal refund",
+				OnlinePaymentLineID: uuid.NullUUID{UUID: line.ID, Valid: true},
+			}).Error; err != nil {
+				return fmt.Errorf("failed to create refund: %w", err)
+			}
+		}
+		now := time.Now()
+		return tx.Model(&line).Updates(models.OnlinePaymentLine{
+			Status:     models.OnlinePaymentLineStatus_REFUNDED,
+			RefundedAt: &now,
+		}).Error
+	})
+}
+
+// RunOnlinePayments settles and refunds the online payments until ctx is done
+func (api *API) RunOnlinePayments(ctx context.Context) {
+	if api.payments == nil {
+		return
+	}
+	ticker := time.NewTicker(onlinePaymentsInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := api.SettleOnlinePayments(ctx); err != nil {
+			log.Printf("online payments: %s", err)
+			sentry.CaptureException(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package public
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/SuperPingPong/tournoi/internal/payments"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckoutLines(t *testing.T) {
+	entries := []DueEntry{
+		{EntryID: uuid.New(), BandDay: 1, Price: 9},
+		{EntryID: uuid.New(), BandDay: 1, Price: 8},
+		{EntryID: uuid.New(), BandDay: 1, Price: 7, Waiting: true},
+		{EntryID: uuid.New(), BandDay: 2, Price: 10},
+		{EntryID: uuid.New(), BandDay: 2, Price: 10, Status: models.CheckInStatus_SCRATCHED},
+		{EntryID: uuid.New(), BandDay: 2, Price: 9},
+	}
+	// 9 € of day 1 were paid on site
+	ledger := buildLedger(entries, []PaymentLine{{Payment: models.Payment{Day: 1, Kind: models.PaymentKind_PAYMENT, Amount: 9}}})
+	covered := map[uuid.UUID]bool{entries[3].EntryID: true}
+
+	lines := checkoutLines(ledger, covered)
+	require.Len(t, lines, 3)
+	// The entries of the main draw are paid within the balance of their day
+	require.Equal(t, entries[1].EntryID, lines[0].EntryID)
+	require.Equal(t, 8, lines[0].Amount)
+	// The waiting list is paid in full, it is held until promotion
+	require.Equal(t, entries[2].EntryID, lines[1].EntryID)
+	require.Equal(t, 7, lines[1].Amount)
+	require.Equal(t, entries[5].EntryID, lines[2].EntryID)
+	require.Equal(t, 2, lines[2].Day)
+	for _, line := range lines {
+		require.Equal(t, models.OnlinePaymentLineStatus_PENDING, line.Status)
+	}
+
+	require.Empty(t, checkoutLines(nil, nil))
+}
+
+func TestOnlinePayments(t *testing.T) {
+	env := getTestEnv(t)
+	defer env.teardown()
+	t.Setenv("ONLINE_REFUND_DEADLINE", "2099-01-01 00:00")
+
+	res := performRequest("POST", fmt.Sprintf("/api/members/%s/checkout", uuid.New()), nil, map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusServiceUnavailable, res.Code)
+
+	provider := payments.NewFakeProvider([]byte("secret"))
+	env.api.payments = provider
+
+	bands := []models.Band{
+		{Name: "A", Day: 1, Color: models.BandColor_PINK, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 2, Price: 9},
+		{Name: "W", Day: 1, Color: models.BandColor_BLUE, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 1, Price: 8},
+	}
+	require.NoError(t, env.db.Create(&bands).Error)
+	other := models.Member{FirstName: "Paul", LastName: "Martin", Sex: "M", Points: 700, Category: "S", PermitID: "123450", UserID: env.user.ID}
+	require.NoError(t, env.db.Create(&other).Error)
+	member := models.Member{FirstName: "Jean", LastName: "Pierre", Sex: "M", Points: 580, Category: "S", PermitID: "123451", UserID: env.user.ID}
+	require.NoError(t, env.db.Create(&member).Error)
+	now := time.Now()
+	otherEntry := models.Entry{MemberID: other.ID, BandID: bands[1].ID, Confirmed: true, CreatedAt: now}
+	require.NoError(t, env.db.Create(&otherEntry).Error)
+	var entries []models.Entry
+	for _, band := range bands {
+		entry := models.Entry{MemberID: member.ID, BandID: band.ID, Confirmed: true, CreatedAt: now.Add(time.Minute)}
+		require.NoError(t, env.db.Create(&entry).Error)
+		entries = append(entries, entry)
+	}
+
+	url := fmt.Sprintf("/api/members/%s/checkout", member.ID)
+	res = performRequest("POST", url, nil, map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusCreated, res.Code)
+	var checkout models.OnlinePayment
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&checkout))
+	require.Equal(t, 17, checkout.Amount)
+	require.Len(t, checkout.Lines, 2)
+	require.Equal(t, "https://tournoi.example.com?payment=success", checkout.CheckoutURL)
+
+	// The entries are being paid
+	res = performRequest("POST", url, nil, map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusConflict, res.Code)
+
+	header, body, err := provider.Pay(checkout.CheckoutID)
+	require.NoError(t, err)
+	res = performRequest("POST", "/api/payments/webhook", bytes.NewReader(body), map[string]string{
+		payments.FakeSignatureHeader: "00",
+	}, env.api.router)
+	require.Equal(t, http.StatusBadRequest, res.Code)
+	// Webhooks may be sent twice
+	for i := 0; i < 2; i++ {
+		res = performRequest("POST", "/api/payments/webhook", bytes.NewReader(body), map[string]string{
+			payments.FakeSignatureHeader: header.Get(payments.FakeSignatureHeader),
+		}, env.api.router)
+		require.Equal(t, http.StatusOK, res.Code)
+	}
+
+	lineStatus := func(entryID uuid.UUID) string {
+		var line models.OnlinePaymentLine
+		require.NoError(t, env.db.Where("entry_id = ?", entryID).First(&line).Error)
+		return line.Status
+	}
+	ledgerOf := func() []LedgerDay {
+		ledger, err := memberLedger(env.db, member)
+		require.NoError(t, err)
+		return ledger
+	}
+	require.Equal(t, models.OnlinePaymentLineStatus_SETTLED, lineStatus(entries[0].ID))
+	// The waiting list entry is held
+	require.Equal(t, models.OnlinePaymentLineStatus_HELD, lineStatus(entries[1].ID))
+	require.Equal(t, 9, ledgerOf()[0].Paid)
+	require.Equal(t, 0, ledgerOf()[0].Balance)
+
+	// The entry is promoted when the other member withdraws
+	require.NoError(t, env.db.Delete(&otherEntry).Error)
+	processed, err := env.api.SettleOnlinePayments(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, processed)
+	require.Equal(t, models.OnlinePaymentLineStatus_SETTLED, lineStatus(entries[1].ID))
+	require.Equal(t, 17, ledgerOf()[0].Paid)
+
+	// Withdrawing before the deadline is refunded
+	require.NoError(t, env.db.Delete(&entries[0]).Error)
+	processed, err = env.api.SettleOnlinePayments(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, processed)
+	require.Equal(t, models.OnlinePaymentLineStatus_REFUNDED, lineStatus(entries[0].ID))
+	refunds := provider.Refunds()
+	require.Len(t, refunds, 1)
+	for _, refund := range refunds {
+		require.Equal(t, 900, refund.Amount)
+		require.Equal(t, "fake_pi_"+checkout.CheckoutID, refund.PaymentID)
+	}
+	require.Equal(t, 9, ledgerOf()[0].Refunded)
+	require.Equal(t, 0, ledgerOf()[0].Balance)
+
+	// But not after
+	t.Setenv("ONLINE_REFUND_DEADLINE", "2000-01-01 00:00")
+	require.NoError(t, env.db.Delete(&entries[1]).Error)
+	processed, err = env.api.SettleOnlinePayments(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0, processed)
+	require.Equal(t, models.OnlinePaymentLineStatus_SETTLED, lineStatus(entries[1].ID))
+
+	res = performRequest("GET", fmt.Sprintf("/api/members/%s/online-payments", member.ID), nil, map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	var list struct {
+		Payments []models.OnlinePayment
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&list))
+	require.Len(t, list.Payments, 1)
+	require.Equal(t, models.OnlinePaymentStatus_PAID, list.Payments[0].Status)
+	require.Len(t, list.Payments[0].Lines, 2)
+}
+
+func TestRefundWaitingList(t *testing.T) {
+	env := getTestEnv(t)
+	defer env.teardown()
+	t.Setenv("ONLINE_REFUND_DEADLINE", "2099-01-01 00:00")
+
+	provider := payments.NewFakeProvider([]byte("secret"))
+	env.api.payments = provider
+
+	band := models.Band{Name: "W", Day: 1, Color: models.BandColor_BLUE, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 1, Price: 8}
+	require.NoError(t, env.db.Create(&band).Error)
+	other := models.Member{FirstName: "Paul", LastName: "Martin", Sex: "M", Points: 700, Category: "S", PermitID: "123450", UserID: env.user.ID}
+	require.NoError(t, env.db.Create(&other).Error)
+	member := models.Member{FirstName: "Jean", LastName: "Pierre", Sex: "M", Points: 580, Category: "S", PermitID: "123451", UserID: env.user.ID}
+	require.NoError(t, env.db.Create(&member).Error)
+	now := time.Now()
+	require.NoError(t, env.db.Create(&models.Entry{MemberID: other.ID, BandID: band.ID, Confirmed: true, CreatedAt: now}).Error)
+	entry := models.Entry{MemberID: member.ID, BandID: band.ID, Confirmed: true, CreatedAt: now.Add(time.Minute)}
+	require.NoError(t, env.db.Create(&entry).Error)
+
+	res := performRequest("POST", fmt.Sprintf("/api/members/%s/checkout", member.ID), nil, map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusCreated, res.Code)
+	var checkout models.OnlinePayment
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&checkout))
+	header, body, err := provider.Pay(checkout.CheckoutID)
+	require.NoError(t, err)
+	res = performRequest("POST", "/api/payments/webhook", bytes.NewReader(body), map[string]string{
+		payments.FakeSignatureHeader: header.Get(payments.FakeSignatureHeader),
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+
+	lineStatus := func() string {
+		var line models.OnlinePaymentLine
+		require.NoError(t, env.db.Where("entry_id = ?", entry.ID).First(&line).Error)
+		return line.Status
+	}
+	require.Equal(t, models.OnlinePaymentLineStatus_HELD, lineStatus())
+
+	// The payment stays held while the entry may still get a place
+	processed, err := env.api.SettleOnlinePayments(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 0, processed)
+
+	// The other player is present, the waiting list does not get a place at the deadline
+	res = performRequest("POST", fmt.Sprintf("/api/admin/check-in/members/%s", other.ID), strings.NewReader(`{"Day":1,"Status":"present"}`), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	res = performRequest("POST", fmt.Sprintf("/api/admin/check-in/bands/%s/deadline", band.ID), nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+
+	processed, err = env.api.SettleOnlinePayments(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, processed)
+	require.Equal(t, models.OnlinePaymentLineStatus_REFUNDED, lineStatus())
+	require.Len(t, provider.Refunds(), 1)
+	// Held payments were never in the ledger
+	var count int64
+	require.NoError(t, env.db.Model(&models.Payment{}).Where("member_id = ?", member.ID).Count(&count).Error)
+	require.Zero(t, count)
+}
+
+// unavailableProvider fails to open checkouts
+type unavailableProvider struct {
+	*payments.FakeProvider
+}
+
+func (unavailableProvider) CreateCheckout(context.Context, payments.CheckoutRequest) (payments.Checkout, error) {
+	return payments.Checkout{}, errors.New("service unavailable")
+}
+
+func TestCreateCheckoutFailure(t *testing.T) {
+	env := getTestEnv(t)
+	defer env.teardown()
+
+	provider := payments.NewFakeProvider([]byte("secret"))
+	env.api.payments = unavailableProvider{provider}
+
+	band := models.Band{Name: "A", Day: 1, Color: models.BandColor_PINK, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 2, Price: 9}
+	require.NoError(t, env.db.Create(&band).Error)
+	member := models.Member{FirstName: "Jean", LastName: "Pierre", Sex: "M", Points: 580, Category: "S", PermitID: "123451", UserID: env.user.ID}
+	require.NoError(t, env.db.Create(&member).Error)
+	require.NoError(t, env.db.Create(&models.Entry{MemberID: member.ID, BandID: band.ID, Confirmed: true, CreatedAt: time.Now()}).Error)
+
+	url := fmt.Sprintf("/api/members/%s/checkout", member.ID)
+	res := performRequest("POST", url, nil, map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusInternalServerError, res.Code)
+	var payment models.OnlinePayment
+	require.NoError(t, env.db.Preload("Lines").Where("member_id = ?", member.ID).First(&payment).Error)
+	require.Equal(t, models.OnlinePaymentStatus_FAILED, payment.Status)
+	require.Empty(t, payment.CheckoutID)
+	require.Equal(t, models.OnlinePaymentLineStatus_FAILED, payment.Lines[0].Status)
+
+	// The entries are not held by the failed checkout
+	env.api.payments = provider
+	res = performRequest("POST", url, nil, map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusCreated, res.Code)
+	var checkout models.OnlinePayment
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&checkout))
+	require.Equal(t, "fake_cs_1", checkout.CheckoutID)
+	require.NoError(t, env.db.First(&payment, checkout.ID).Error)
+	require.Equal(t, checkout.CheckoutURL, payment.CheckoutURL)
+}
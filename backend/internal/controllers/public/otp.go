@@ -12,7 +12,6 @@ import (
 	"github.com/gin-gonic/gin/binding"
 
 	"github.com/gin-gonic/gin"
-	_ "golang.org/x/oauth2/google"
 )
 
 const otpExpirationDelay = 10 * time.Minute
@@ -67,7 +66,10 @@ func (api *API) SendOTP(ctx *gin.Context) {
 		return
 	}
 
-	err = sendEmailHTMLOTP(input.Email, password)
+	// The user may not exist yet, in which case the email is sent in the default language
+	var user models.User
+	api.db.Where("email = ?", input.Email).Limit(1).Find(&user)
+	err = api.sendEmailOTP(input.Email, user.Language, password)
 	if err != nil {
 		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to send email: %w", err))
 		return
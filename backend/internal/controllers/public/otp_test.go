@@ -0,0 +1,67 @@
+package public
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendOTP(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		body, err := json.Marshal(map[string]string{"Email": "JDoe@example.com"})
+		require.NoError(t, err)
+
+		res := performRequest("POST", "/api/otp", bytes.NewBuffer(body), nil, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		var otp models.OTP
+		require.NoError(t, env.db.Where(&models.OTP{Email: "jdoe@example.com"}).First(&otp).Error)
+
+		sent := env.mailer.Sent()
+		require.Len(t, sent, 1)
+		require.Equal(t, "jdoe@example.com", sent[0].To)
+		require.Equal(t, "OTP "+otp.Secret, sent[0].Subject)
+		require.Contains(t, sent[0].HTML, otp.Secret)
+
+		// A valid OTP is not sent again
+		res = performRequest("POST", "/api/otp", bytes.NewBuffer(body), nil, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		require.Len(t, env.mailer.Sent(), 1)
+	})
+	t.Run("UserLanguage", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		require.NoError(t, env.db.Model(env.user).Updates(models.User{Language: "en"}).Error)
+
+		body, err := json.Marshal(map[string]string{"Email": env.user.Email})
+		require.NoError(t, err)
+
+		res := performRequest("POST", "/api/otp", bytes.NewBuffer(body), nil, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		sent := env.mailer.Sent()
+		require.Len(t, sent, 1)
+		require.Contains(t, sent[0].Text, "Here is your login code")
+	})
+	t.Run("InvalidEmail", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		body, err := json.Marshal(map[string]string{"Email": "jdoe"})
+		require.NoError(t, err)
+
+		res := performRequest("POST", "/api/otp", bytes.NewBuffer(body), nil, env.api.router)
+
+		require.Equal(t, http.StatusBadRequest, res.Code)
+		require.Empty(t, env.mailer.Sent())
+	})
+}
@@ -0,0 +1,71 @@
+package public
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/SuperPingPong/tournoi/internal/outbox"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ListOutboxMessages lists the queued emails, the dead ones by default
+func (api *API) ListOutboxMessages(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.DefaultQuery("page", "1"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid page: %s", ctx.Query("page")))
+		return
+	}
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("page_size", "50"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid page size: %s", ctx.Query("page_size")))
+		return
+	}
+
+	status := ctx.DefaultQuery("status", models.OutboxStatus_DEAD)
+	messages := []models.OutboxMessage{}
+	if err = api.db.
+		Scopes(Paginate(page, pageSize)).
+		Omit("html", "text", "attachments").
+		Where("status = ?", status).
+		Order("created_at DESC").
+		Find(&messages).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list outbox messages: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"messages": messages})
+}
+
+// ResendOutboxMessage puts a message back in the queue, typically once the cause of its failure is fixed
+func (api *API) ResendOutboxMessage(ctx *gin.Context) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid message id: %s", ctx.Param("id")))
+		return
+	}
+
+	var message models.OutboxMessage
+	err = api.db.First(&message, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("message %s not found", id))
+			return
+		}
+
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get message: %w", err))
+		return
+	}
+
+	if err = outbox.Resend(api.db, &message); err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to resend message: %w", err))
+		return
+	}
+	api.outbox.Wake()
+
+	ctx.Status(http.StatusNoContent)
+}
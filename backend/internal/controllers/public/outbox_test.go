@@ -0,0 +1,69 @@
+package public
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResendOutboxMessage(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		message := models.OutboxMessage{
+			Key:           "register-confirm:test",
+			To:            env.user.Email,
+			Subject:       "Confirmation inscription Doe John Tournoi de Lognes",
+			HTML:          "<p>Confirmation</p>",
+			Status:        models.OutboxStatus_DEAD,
+			Attempts:      8,
+			NextAttemptAt: time.Now(),
+			LastError:     "connection refused",
+		}
+		require.NoError(t, env.db.Create(&message).Error)
+
+		res := performRequest("GET", "/api/admin/outbox", nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		var got struct {
+			Messages []models.OutboxMessage
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		require.Len(t, got.Messages, 1)
+		require.Equal(t, message.ID, got.Messages[0].ID)
+		require.Equal(t, "connection refused", got.Messages[0].LastError)
+
+		res = performRequest("POST", fmt.Sprintf("/api/admin/outbox/%s/resend", message.ID), nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusNoContent, res.Code)
+		require.NoError(t, env.db.First(&message, message.ID).Error)
+		require.Equal(t, models.OutboxStatus_PENDING, message.Status)
+		require.Equal(t, 0, message.Attempts)
+
+		_, err := env.api.outbox.ProcessBatch()
+		require.NoError(t, err)
+		require.NoError(t, env.db.First(&message, message.ID).Error)
+		require.Equal(t, models.OutboxStatus_SENT, message.Status)
+		require.Len(t, env.mailer.Sent(), 1)
+	})
+	t.Run("NotAdmin", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		res := performRequest("GET", "/api/admin/outbox", nil, map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusForbidden, res.Code)
+	})
+}
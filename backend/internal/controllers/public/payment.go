@@ -0,0 +1,370 @@
+package public
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/SuperPingPong/tournoi/internal/pricing"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DueEntry is a confirmed entry of a member with its rank and check in, which tell whether it has to be paid
+type DueEntry struct {
+	EntryID       uuid.UUID
+	BandID        uuid.UUID
+	BandName      string
+	BandDay       int
+	BandStartTime string
+	// BandPrice is the price of the band and Price what the member pays for it once the pricing rules are applied, in euros
+	BandPrice   int
+	Adjustments []pricing.Adjustment
+	Price       int
+	CreatedAt   time.Time
+	Rank        int
+	Waiting     bool
+	// Status is the check in of the entry, empty when the player is not checked yet
+	Status   string
+	Promoted bool
+}
+
+// rankedEntriesQuery ranks the confirmed entries of every band
+const rankedEntriesQuery = `
+	SELECT
+	  entries.id,
+	  entries.band_id,
+	  entries.member_id,
+	  entries.created_at,
+	  ROW_NUMBER() OVER (PARTITION BY entries.band_id ORDER BY entries.created_at ASC) AS rank
+	FROM entries
+	JOIN members ON members.id = entries.member_id AND members.deleted_at IS NULL
+	WHERE entries.confirmed IS TRUE AND entries.deleted_at IS NULL
+`
+
+// dueEntriesQuery lists the confirmed entries of a member, of a day or of all days when the day is 0,
+// with their rank and check in
+const dueEntriesQuery = `
+	SELECT
+	  ranked.id AS entry_id,
+	  bands.id AS band_id,
+	  bands.name AS band_name,
+	  bands.day AS band_day,
+	  COALESCE(bands.start_time, '') AS band_start_time,
+	  bands.price AS band_price,
+	  ranked.created_at,
+	  ranked.rank,
+	  ranked.rank > bands.max_entries AS waiting,
+	  COALESCE(check_ins.status, '') AS status,
+	  COALESCE(check_ins.promoted, FALSE) AS promoted
+	FROM (` + rankedEntriesQuery + `) AS ranked
+	JOIN bands ON bands.id = ranked.band_id
+	LEFT JOIN check_ins ON check_ins.entry_id = ranked.id
+	WHERE ranked.member_id = @member AND (@day = 0 OR bands.day = @day)
+	ORDER BY band_day ASC, band_start_time ASC, bands.created_at ASC
+`
+
+// listDueEntries lists the entries of member with their price
+func listDueEntries(db *gorm.DB, member models.Member, day int) ([]DueEntry, error) {
+	entries := []DueEntry{}
+	if err := db.Raw(dueEntriesQuery, map[string]interface{}{"member": member.ID, "day": day}).Scan(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list entries of member %s: %w", member.ID, err)
+	}
+	rules, err := getPricingRules(db)
+	if err != nil {
+		return nil, err
+	}
+	priceDueEntries(rules, member, entries)
+	return entries, nil
+}
+
+// entryDue tells whether an entry has to be paid: the scratched entries are not played, the waiting list is only paid once promoted
+func entryDue(status string, waiting bool, promoted bool) bool {
+	return status != models.CheckInStatus_SCRATCHED && (!waiting || promoted)
+}
+
+func (e DueEntry) due() bool {
+	return entryDue(e.Status, e.Waiting, e.Promoted)
+}
+
+// amountDue is the price of the bands the member plays
+func amountDue(entries []DueEntry) int {
+	return lo.SumBy(entries, func(entry DueEntry) int {
+		if !entry.due() {
+			return 0
+		}
+		return entry.Price
+	})
+}
+
+// PaymentLine is a payment of the ledger with the email of its cashier, empty for online payments
+type PaymentLine struct {
+	models.Payment
+	CashierEmail string
+}
+
+// LedgerDay is what a member owes and paid for a day of the tournament
+type LedgerDay struct {
+	Day      int
+	Entries  []DueEntry
+	Payments []PaymentLine
+	Due      int
+	Paid     int
+	Refunded int
+	// Balance is what the member still owes, negative when the member has to be refunded
+	Balance int
+}
+
+func listPaymentLines(db *gorm.DB, memberID uuid.UUID) ([]PaymentLine, error) {
+	payments := []PaymentLine{}
+	if err := db.Table("payments").
+		Select("payments.*, COALESCE(users.email, '') AS cashier_email").
+		Joins("LEFT JOIN users ON users.id = payments.cashier_id").
+		Where("payments.member_id = ?", memberID).
+		Order("payments.created_at ASC").
+		Scan(&payments).Error; err != nil {
+		return nil, fmt.Errorf("failed to list payments of member %s: %w", memberID, err)
+	}
+	return payments, nil
+}
+
+// buildLedger groups the entries and payments of a member by day, in the order of the days
+func buildLedger(entries []DueEntry, payments []PaymentLine) []LedgerDay {
+	days := map[int]*LedgerDay{}
+	ledgerDay := func(day int) *LedgerDay {
+		if _, ok := days[day]; !ok {
+			days[day] = &LedgerDay{Day: day, Entries: []DueEntry{}, Payments: []PaymentLine{}}
+		}
+		return days[day]
+	}
+	for _, entry := range entries {
+		ledgerDay(entry.BandDay).Entries = append(ledgerDay(entry.BandDay).Entries, entry)
+	}
+	for _, payment := range payments {
+		day := ledgerDay(payment.Day)
+		day.Payments = append(day.Payments, payment)
+		if payment.Kind == models.PaymentKind_REFUND {
+			day.Refunded += payment.Amount
+		} else {
+			day.Paid += payment.Amount
+		}
+	}
+
+	ledger := make([]LedgerDay, 0, len(days))
+	for _, day := range days {
+		day.Due = amountDue(day.Entries)
+		day.Balance = day.Due - day.Paid + day.Refunded
+		ledger = append(ledger, *day)
+	}
+	sort.Slice(ledger, func(i, j int) bool {
+		return ledger[i].Day < ledger[j].Day
+	})
+	return ledger
+}
+
+func memberLedger(db *gorm.DB, member models.Member) ([]LedgerDay, error) {
+	entries, err := listDueEntries(db, member, 0)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := listPaymentLines(db, member.ID)
+	if err != nil {
+		return nil, err
+	}
+	return buildLedger(entries, payments), nil
+}
+
+func ledgerResponse(member models.Member, ledger []LedgerDay) gin.H {
+	return gin.H{
+		"member": gin.H{
+			"ID":        member.ID,
+			"PermitID":  member.PermitID,
+			"FirstName": member.FirstName,
+			"LastName":  member.LastName,
+			"ClubName":  member.ClubName,
+		},
+		"days":     ledger,
+		"due":      lo.SumBy(ledger, func(day LedgerDay) int { return day.Due }),
+		"paid":     lo.SumBy(ledger, func(day LedgerDay) int { return day.Paid }),
+		"refunded": lo.SumBy(ledger, func(day LedgerDay) int { return day.Refunded }),
+		"balance":  lo.SumBy(ledger, func(day LedgerDay) int { return day.Balance }),
+	}
+}
+
+// GetMemberLedger returns, for every day, the amount due by a member, its payments and its balance
+func (api *API) GetMemberLedger(ctx *gin.Context) {
+	member, ok := api.getMember(ctx)
+	if !ok {
+		return
+	}
+
+	ledger, err := memberLedger(api.db, member)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, ledgerResponse(member, ledger))
+}
+
+type RecordPaymentInput struct {
+	Day int `binding:"required,min=1"`
+	// Kind is a payment by default
+	Kind    string `binding:"omitempty,oneof=payment refund"`
+	Method  string `binding:"required,oneof=cash cheque"`
+	Amount  int    `binding:"required,min=1"`
+	Comment string
+}
+
+// RecordPayment adds a payment, which may be partial, or a refund to the ledger of a member.
+// A payment cannot exceed the balance of the day and a refund what was paid for the day.
+func (api *API) RecordPayment(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	member, ok := api.getMember(ctx)
+	if !ok {
+		return
+	}
+	var input RecordPaymentInput
+	if err = ctx.ShouldBindBodyWith(&input, binding.JSON); err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
+		return
+	}
+	if input.Kind == "" {
+		input.Kind = models.PaymentKind_PAYMENT
+	}
+
+	var ledger []LedgerDay
+	var conflict error
+	err = api.db.Transaction(func(tx *gorm.DB) error {
+		// Two cashiers must not collect the same balance
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Member{}, member.ID).Error; err != nil {
+			return fmt.Errorf("failed to lock member: %w", err)
+		}
+		current, err := memberLedger(tx, member)
+		if err != nil {
+			return err
+		}
+		day, _ := lo.Find(current, func(day LedgerDay) bool { return day.Day == input.Day })
+		if input.Kind == models.PaymentKind_PAYMENT && input.Amount > day.Balance {
+			conflict = fmt.Errorf("payment of %d € exceeds the balance of %d € of day %d", input.Amount, day.Balance, input.Day)
+			return nil
+		}
+		if input.Kind == models.PaymentKind_REFUND && input.Amount > day.Paid-day.Refunded {
+			conflict = fmt.Errorf("refund of %d € exceeds the %d € paid for day %d", input.Amount, day.Paid-day.Refunded, input.Day)
+			return nil
+		}
+
+		if err = tx.Create(&models.Payment{
+			MemberID:  member.ID,
+			Day:       input.Day,
+			Kind:      input.Kind,
+			Method:    input.Method,
+			Amount:    input.Amount,
+			Comment:   input.Comment,
+			CashierID: uuid.NullUUID{UUID: user.ID, Valid: true},
+		}).Error; err != nil {
+			return fmt.Errorf("failed to create payment: %w", err)
+		}
+		ledger, err = memberLedger(tx, member)
+		return err
+	})
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if conflict != nil {
+		ctx.AbortWithError(http.StatusConflict, conflict)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, ledgerResponse(member, ledger))
+}
+
+// CashReportLine is what a cashier collected and gave back with a payment method
+type CashReportLine struct {
+	CashierID    uuid.UUID
+	CashierEmail string
+	Method       string
+	Payments     int
+	Refunds      int
+	Collected    int
+	Refunded     int
+	// Net is what the cash register should hold
+	Net int
+}
+
+const cashReportQuery = `
+	SELECT
+	  payments.cashier_id,
+	  COALESCE(users.email, '') AS cashier_email,
+	  payments.method,
+	  COUNT(*) FILTER (WHERE payments.kind = 'payment') AS payments,
+	  COUNT(*) FILTER (WHERE payments.kind = 'refund') AS refunds,
+	  COALESCE(SUM(payments.amount) FILTER (WHERE payments.kind = 'payment'), 0) AS collected,
+	  COALESCE(SUM(payments.amount) FILTER (WHERE payments.kind = 'refund'), 0) AS refunded
+	FROM payments
+	LEFT JOIN users ON users.id = payments.cashier_id
+	WHERE payments.created_at >= ? AND payments.created_at < ? AND payments.method <> 'online'
+	GROUP BY payments.cashier_id, users.email, payments.method
+	ORDER BY cashier_email ASC, payments.method ASC
+`
+
+// cashReportTotals completes the net of the lines and sums them by payment method
+func cashReportTotals(lines []CashReportLine) map[string]CashReportLine {
+	totals := map[string]CashReportLine{}
+	for i := range lines {
+		lines[i].Net = lines[i].Collected - lines[i].Refunded
+		total := totals[lines[i].Method]
+		total.Method = lines[i].Method
+		total.Payments += lines[i].Payments
+		total.Refunds += lines[i].Refunds
+		total.Collected += lines[i].Collected
+		total.Refunded += lines[i].Refunded
+		total.Net += lines[i].Net
+		totals[lines[i].Method] = total
+	}
+	return totals
+}
+
+// GetCashReport reconciles the cash registers: what each cashier collected and gave back by payment method,
+// on the day of ?date=2006-01-02, today by default. Online payments are not in the registers.
+func (api *API) GetCashReport(ctx *gin.Context) {
+	location, err := time.LoadLocation(tournamentTimezone)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to load timezone: %w", err))
+		return
+	}
+	now := time.Now().In(location)
+	date := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	if value := ctx.Query("date"); value != "" {
+		date, err = time.ParseInLocation("2006-01-02", value, location)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid date: %s", value))
+			return
+		}
+	}
+
+	lines := []CashReportLine{}
+	if err = api.db.Raw(cashReportQuery, date, date.AddDate(0, 0, 1)).Scan(&lines).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to build cash report: %w", err))
+		return
+	}
+	totals := cashReportTotals(lines)
+
+	ctx.JSON(http.StatusOK, gin.H{
+		"date":   date.Format("2006-01-02"),
+		"lines":  lines,
+		"totals": totals,
+		"net":    lo.SumBy(lines, func(line CashReportLine) int { return line.Net }),
+	})
+}
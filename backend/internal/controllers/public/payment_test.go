@@ -0,0 +1,165 @@
+package public
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmountDue(t *testing.T) {
+	entries := []DueEntry{
+		{Price: 9},
+		{Price: 10, Status: models.CheckInStatus_SCRATCHED},
+		{Price: 8, Waiting: true},
+		{Price: 7, Waiting: true, Promoted: true, Status: models.CheckInStatus_PRESENT},
+	}
+	require.Equal(t, 16, amountDue(entries))
+	require.Equal(t, 0, amountDue(nil))
+}
+
+func TestBuildLedger(t *testing.T) {
+	entries := []DueEntry{
+		{BandName: "A", BandDay: 1, Price: 9},
+		{BandName: "B", BandDay: 1, Price: 8, Waiting: true},
+		{BandName: "C", BandDay: 2, Price: 10},
+	}
+	payments := []PaymentLine{
+		{Payment: models.Payment{Day: 1, Kind: models.PaymentKind_PAYMENT, Method: models.PaymentMethod_CASH, Amount: 5}},
+		{Payment: models.Payment{Day: 2, Kind: models.PaymentKind_PAYMENT, Method: models.PaymentMethod_CHEQUE, Amount: 10}},
+		{Payment: models.Payment{Day: 2, Kind: models.PaymentKind_REFUND, Method: models.PaymentMethod_CASH, Amount: 10}},
+		// The member withdrew from the bands of day 3 after paying
+		{Payment: models.Payment{Day: 3, Kind: models.PaymentKind_PAYMENT, Method: models.PaymentMethod_CASH, Amount: 9}},
+	}
+
+	ledger := buildLedger(entries, payments)
+	require.Len(t, ledger, 3)
+	require.Equal(t, 1, ledger[0].Day)
+	require.Len(t, ledger[0].Entries, 2)
+	require.Equal(t, 9, ledger[0].Due)
+	require.Equal(t, 5, ledger[0].Paid)
+	require.Equal(t, 4, ledger[0].Balance)
+	require.Equal(t, 10, ledger[1].Due)
+	require.Equal(t, 10, ledger[1].Refunded)
+	require.Equal(t, 10, ledger[1].Balance)
+	require.Empty(t, ledger[2].Entries)
+	require.Equal(t, -9, ledger[2].Balance)
+
+	require.Empty(t, buildLedger(nil, nil))
+}
+
+func TestCashReportTotals(t *testing.T) {
+	lines := []CashReportLine{
+		{CashierEmail: "a@example.com", Method: models.PaymentMethod_CASH, Payments: 3, Collected: 27, Refunds: 1, Refunded: 9},
+		{CashierEmail: "a@example.com", Method: models.PaymentMethod_CHEQUE, Payments: 1, Collected: 18},
+		{CashierEmail: "b@example.com", Method: models.PaymentMethod_CASH, Payments: 2, Collected: 17},
+	}
+
+	totals := cashReportTotals(lines)
+	require.Equal(t, 18, lines[0].Net)
+	require.Equal(t, 17, lines[2].Net)
+	require.Len(t, totals, 2)
+	require.Equal(t, CashReportLine{Method: models.PaymentMethod_CASH, Payments: 5, Refunds: 1, Collected: 44, Refunded: 9, Net: 35}, totals[models.PaymentMethod_CASH])
+	require.Equal(t, 18, totals[models.PaymentMethod_CHEQUE].Net)
+}
+
+func TestPayments(t *testing.T) {
+	env := getTestEnv(t)
+	defer env.teardown()
+
+	bands := []models.Band{
+		{Name: "A", Day: 1, Color: models.BandColor_PINK, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 2, Price: 9},
+		{Name: "B", Day: 1, Color: models.BandColor_BLUE, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 2, Price: 8},
+		{Name: "C", Day: 2, Color: models.BandColor_GREEN, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 2, Price: 10},
+	}
+	require.NoError(t, env.db.Create(&bands).Error)
+	member := models.Member{FirstName: "Jean", LastName: "Pierre", Sex: "M", Points: 580, Category: "S", PermitID: "123451", UserID: env.user.ID}
+	require.NoError(t, env.db.Create(&member).Error)
+	for _, band := range bands {
+		require.NoError(t, env.db.Create(&models.Entry{MemberID: member.ID, BandID: band.ID, Confirmed: true, CreatedAt: time.Now()}).Error)
+	}
+
+	url := fmt.Sprintf("/api/admin/payments/members/%s", member.ID)
+	res := performRequest("POST", url, strings.NewReader(`{"Day":1,"Method":"cash","Amount":10}`), map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusForbidden, res.Code)
+
+	res = performRequest("POST", url, strings.NewReader(`{"Day":1,"Method":"card","Amount":10}`), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	// Partial payment
+	res = performRequest("POST", url, strings.NewReader(`{"Day":1,"Method":"cash","Amount":10}`), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusCreated, res.Code)
+	res = performRequest("POST", url, strings.NewReader(`{"Day":1,"Method":"cheque","Amount":8}`), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusConflict, res.Code)
+	res = performRequest("POST", url, strings.NewReader(`{"Day":1,"Method":"cheque","Amount":7}`), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusCreated, res.Code)
+
+	// The member withdraws from band B
+	require.NoError(t, env.db.Where("member_id = ? AND band_id = ?", member.ID, bands[1].ID).Delete(&models.Entry{}).Error)
+	res = performRequest("POST", url, strings.NewReader(`{"Day":1,"Kind":"refund","Method":"cash","Amount":20}`), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusConflict, res.Code)
+	res = performRequest("POST", url, strings.NewReader(`{"Day":1,"Kind":"refund","Method":"cash","Amount":8,"Comment":"Forfait tableau B"}`), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusCreated, res.Code)
+
+	res = performRequest("GET", url, nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	var ledger struct {
+		Days     []LedgerDay
+		Due      int
+		Paid     int
+		Refunded int
+		Balance  int
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&ledger))
+	require.Len(t, ledger.Days, 2)
+	require.Equal(t, 0, ledger.Days[0].Balance)
+	require.Len(t, ledger.Days[0].Payments, 3)
+	require.Equal(t, "admin@example.com", ledger.Days[0].Payments[0].CashierEmail)
+	require.Equal(t, 19, ledger.Due)
+	require.Equal(t, 17, ledger.Paid)
+	require.Equal(t, 8, ledger.Refunded)
+	require.Equal(t, 10, ledger.Balance)
+
+	res = performRequest("GET", "/api/admin/payments/report", nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	var report struct {
+		Lines  []CashReportLine
+		Totals map[string]CashReportLine
+		Net    int
+	}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&report))
+	require.Len(t, report.Lines, 2)
+	require.Equal(t, 2, report.Totals[models.PaymentMethod_CASH].Net)
+	require.Equal(t, 7, report.Totals[models.PaymentMethod_CHEQUE].Net)
+	require.Equal(t, 9, report.Net)
+
+	res = performRequest("GET", "/api/admin/payments/report?date=2024-06-08", nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&report))
+	require.Empty(t, report.Lines)
+}
@@ -0,0 +1,44 @@
+package public
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/SuperPingPong/tournoi/internal/directory"
+	"github.com/SuperPingPong/tournoi/internal/fftt"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/gin-gonic/gin"
+)
+
+// rememberPlayers stores the players fetched from the FFTT in the directory, failures only being logged as the lookup succeeded
+func (api *API) rememberPlayers(players ...fftt.Player) {
+	if !api.playerDirectory {
+		return
+	}
+	if _, err := directory.Upsert(api.db, players, models.PlayerSource_LOOKUP); err != nil {
+		log.Printf("player directory: %s", err)
+	}
+}
+
+// ImportPlayers fills the player directory from the CSV export of the FFTT sent as request body
+func (api *API) ImportPlayers(ctx *gin.Context) {
+	players, err := directory.ParseCSV(ctx.Request.Body)
+	if err != nil {
+		if errors.Is(err, directory.ErrInvalidCSV) {
+			ctx.AbortWithError(http.StatusBadRequest, err)
+			return
+		}
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	imported, err := directory.Upsert(api.db, players, models.PlayerSource_IMPORT)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to import players: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"imported": imported})
+}
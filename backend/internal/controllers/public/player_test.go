@@ -0,0 +1,129 @@
+package public
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/SuperPingPong/tournoi/internal/fftt"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+const testPlayersCSV = "Licence;Nom;Prénom;Sexe;Points;Cat;Club;N° Club\n" +
+	"7512345;LEFÈVRE;Chloé;F;1234;S;US Créteil;08940001\n" +
+	"7512346;DUPONT;Jean;M;850;V1;US Créteil;08940001\n"
+
+type searchPlayersResponse struct {
+	Players []fftt.Player `json:"players"`
+	Total   int64         `json:"total"`
+	Source  string        `json:"source"`
+}
+
+func TestImportPlayers(t *testing.T) {
+	t.Setenv("PLAYER_DIRECTORY", "true")
+	env := getTestEnv(t)
+	defer env.teardown()
+
+	res := performRequest("POST", "/api/admin/players/import", strings.NewReader(testPlayersCSV), map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+		"Content-Type":  "text/csv",
+	}, env.api.router)
+	require.Equal(t, http.StatusForbidden, res.Code)
+
+	res = performRequest("POST", "/api/admin/players/import", strings.NewReader("licence;nom\n1;A\n"), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+		"Content-Type":  "text/csv",
+	}, env.api.router)
+	require.Equal(t, http.StatusBadRequest, res.Code)
+
+	// Importing twice updates the players
+	for i := 0; i < 2; i++ {
+		res = performRequest("POST", "/api/admin/players/import", strings.NewReader(testPlayersCSV), map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+			"Content-Type":  "text/csv",
+		}, env.api.router)
+		require.Equal(t, http.StatusOK, res.Code)
+	}
+
+	var players []models.Player
+	require.NoError(t, env.db.Order("permit_id").Find(&players).Error)
+	require.Len(t, players, 2)
+	require.Equal(t, "lefevre chloe us creteil 7512345", players[0].Search)
+	require.Equal(t, models.PlayerSource_IMPORT, players[0].Source)
+	require.Equal(t, 1234.0, players[0].Points)
+}
+
+func TestSearchFFTTPlayersDirectory(t *testing.T) {
+	t.Setenv("PLAYER_DIRECTORY", "true")
+	env := getTestEnv(t)
+	defer env.teardown()
+
+	res := performRequest("POST", "/api/admin/players/import", strings.NewReader(testPlayersCSV), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+		"Content-Type":  "text/csv",
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+
+	t.Run("AccentInsensitive", func(t *testing.T) {
+		res := performRequest("POST", "/api/players", strings.NewReader(`{"Surname":"lefevre","Name":"CHLOE"}`), nil, env.api.router)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var response searchPlayersResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		require.Equal(t, PlayersSource_DIRECTORY, response.Source)
+		require.EqualValues(t, 1, response.Total)
+		require.Equal(t, "7512345", response.Players[0].PermitID)
+		require.Equal(t, "Chloé", response.Players[0].FirstName)
+	})
+
+	t.Run("Typo", func(t *testing.T) {
+		res := performRequest("POST", "/api/players", strings.NewReader(`{"Surname":"dupond"}`), nil, env.api.router)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var response searchPlayersResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		require.Equal(t, PlayersSource_DIRECTORY, response.Source)
+		require.Equal(t, "7512346", response.Players[0].PermitID)
+	})
+
+	t.Run("Paging", func(t *testing.T) {
+		res := performRequest("POST", "/api/players", strings.NewReader(`{"Surname":"creteil","Page":2,"PageSize":1}`), nil, env.api.router)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var response searchPlayersResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		require.EqualValues(t, 2, response.Total)
+		require.Len(t, response.Players, 1)
+		require.Equal(t, "7512345", response.Players[0].PermitID)
+	})
+
+	t.Run("FallbackToFFTT", func(t *testing.T) {
+		expectedFFTTReq, err := http.NewRequest(http.MethodGet, "https://fftt.dafunker.com/v1//proxy/xml_liste_joueur_o.php?nom=MARTIN&prenom=", nil)
+		require.NoError(t, err)
+		mockFFTTRes := `<?xml version="1.0" encoding="ISO-8859-1"?><liste><joueur><licence>7512347</licence><nom>MARTIN</nom><prenom>Paul</prenom><nclub></nclub><sexe>M</sexe><points>600</points></joueur></liste>`
+		env.httpClient.EXPECT().Do(expectedFFTTReq).Return(&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader([]byte(mockFFTTRes))),
+		}, nil).Once()
+
+		res := performRequest("POST", "/api/players", strings.NewReader(`{"Surname":"MARTIN"}`), nil, env.api.router)
+		require.Equal(t, http.StatusOK, res.Code)
+
+		var response searchPlayersResponse
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		require.Equal(t, PlayersSource_FFTT, response.Source)
+		// Players without club are not dropped anymore
+		require.Len(t, response.Players, 1)
+
+		// The next search is answered by the directory
+		res = performRequest("POST", "/api/players", strings.NewReader(`{"Surname":"Martin"}`), nil, env.api.router)
+		require.Equal(t, http.StatusOK, res.Code)
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&response))
+		require.Equal(t, PlayersSource_DIRECTORY, response.Source)
+		require.Equal(t, "7512347", response.Players[0].PermitID)
+	})
+}
@@ -0,0 +1,476 @@
+package public
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/fftt"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultPointsSyncInterval = 24 * time.Hour
+
+// maxUpstreamDownInARow is how many members in a row the FFTT may fail for, after its retries, before the synchronisation is given up
+const maxUpstreamDownInARow = 3
+
+const (
+	EligibilityPolicy_KEEP     string = "keep"
+	EligibilityPolicy_MOVE            = "move"
+	EligibilityPolicy_WITHDRAW        = "withdraw"
+)
+
+type PointsSyncReport struct {
+	Members int
+	Updated int
+	// Failed counts the members the FFTT did not return, or which could not be updated
+	Failed int
+	// Reconciled counts the manual members whose FFTT record appeared
+	Reconciled int
+	// OpenIssues counts the entries waiting for a decision of an admin
+	OpenIssues int64
+	// LicenceIssues counts the members whose licence is not accepted by the licence rules
+	LicenceIssues int64
+}
+
+// ineligibilityReason tells why member can not play band anymore, an empty string if it still can
+func ineligibilityReason(member models.Member, band models.Band) string {
+	if band.SexAllowed != models.BandSex_ALL && band.SexAllowed != member.Sex {
+		return models.EligibilityReason_SEX
+	}
+	if member.Points > band.MaxPoints {
+		return models.EligibilityReason_MAX_POINTS
+	}
+	// Same rule as possibleBandsScope, bands without categories are open to all of them
+	if band.OnlyCategories != nil && !lo.Contains(band.OnlyCategories, member.Category) {
+		return models.EligibilityReason_CATEGORY
+	}
+	return ""
+}
+
+// SyncPoints refreshes the points and category of every member from the FFTT, and reports the entries they no longer fit
+func (api *API) SyncPoints(ctx context.Context) (PointsSyncReport, error) {
+	var report PointsSyncReport
+
+	var members []models.Member
+	if err := api.db.Order("created_at ASC").Find(&members).Error; err != nil {
+		return report, fmt.Errorf("failed to list members: %w", err)
+	}
+	report.Members = len(members)
+
+	rules, err := getLicenceRules(api.db)
+	if err != nil {
+		return report, err
+	}
+	season, err := tournamentSeasonStart()
+	if err != nil {
+		return report, err
+	}
+
+	var upstreamDown int
+	for _, member := range members {
+		// The members declared without any licence can only be reconciled by an admin
+		if strings.HasPrefix(member.PermitID, models.ManualPermitPrefix) {
+			continue
+		}
+
+		player, err := api.freshFFTTPlayer(ctx, member.PermitID)
+		if errors.Is(err, fftt.ErrUpstreamDown) {
+			upstreamDown++
+			if upstreamDown >= maxUpstreamDownInARow {
+				return report, fmt.Errorf("failed to sync points: %w", err)
+			}
+		} else {
+			upstreamDown = 0
+		}
+		if err != nil {
+			// The licence of a manual member is usually not published yet
+			if member.Manual && errors.Is(err, fftt.ErrNotFound) {
+				continue
+			}
+			log.Printf("points sync: %s", err)
+			report.Failed++
+			continue
+		}
+
+		if member.Manual {
+			err = api.db.Transaction(func(tx *gorm.DB) error {
+				if _, _, err := reconcileMember(tx, &member, player, models.MemberChange{}); err != nil {
+					return err
+				}
+				return checkMemberLicence(tx, member, player, rules, season)
+			})
+			if err != nil {
+				log.Printf("points sync: failed to reconcile member %s: %s", member.ID, err)
+				report.Failed++
+				continue
+			}
+			report.Reconciled++
+			continue
+		}
+
+		var updated bool
+		err = api.db.Transaction(func(tx *gorm.DB) error {
+			if updated, err = syncMemberPoints(tx, member, player); err != nil {
+				return err
+			}
+			return checkMemberLicence(tx, member, player, rules, season)
+		})
+		if err != nil {
+			log.Printf("points sync: failed to sync member %s: %s", member.ID, err)
+			report.Failed++
+			continue
+		}
+		if updated {
+			report.Updated++
+		}
+	}
+
+	if err := api.db.Model(&models.EligibilityIssue{}).
+		Where("status = ?", models.EligibilityIssueStatus_OPEN).
+		Count(&report.OpenIssues).Error; err != nil {
+		return report, fmt.Errorf("failed to count eligibility issues: %w", err)
+	}
+	if err := api.db.Model(&models.Member{}).
+		Where("licence_issue <> ''").
+		Count(&report.LicenceIssues).Error; err != nil {
+		return report, fmt.Errorf("failed to count licence issues: %w", err)
+	}
+
+	return report, nil
+}
+
+// freshFFTTPlayer looks up the player in the FFTT, bypassing the cache which would hide the points published since
+func (api *API) freshFFTTPlayer(ctx context.Context, permitID string) (*fftt.Player, error) {
+	if cache, ok := api.fftt.(*fftt.CachedClient); ok {
+		cache.Invalidate(permitID)
+	}
+	return api.fftt.GetPlayer(ctx, permitID)
+}
+
+// syncMemberPoints applies the points, category and club number of player to member, then checks its confirmed entries again.
+// It reports whether the member changed.
+func syncMemberPoints(tx *gorm.DB, member models.Member, player *fftt.Player) (bool, error) {
+	updated := member
+	updated.Points = player.Points
+	updated.Category = player.Category
+	clubNumber := player.ClubNumber
+	if clubNumber == "" && member.ClubNumber == "" {
+		// The members registered before the club number was stored get it from the player directory
+		var known models.Player
+		if err := tx.Where("permit_id = ?", member.PermitID).Limit(1).Find(&known).Error; err != nil {
+			return false, fmt.Errorf("failed to get directory player: %w", err)
+		}
+		clubNumber = known.ClubNumber
+	}
+	if clubNumber != "" {
+		updated.ClubNumber = clubNumber
+	}
+	changes, err := applyMemberChanges(tx, &member, updated, models.MemberChange{Source: models.MemberChangeSource_SYNC})
+	if err != nil {
+		return false, err
+	}
+
+	if _, err = checkMemberEntries(tx, member); err != nil {
+		return false, err
+	}
+	return len(changes) > 0, nil
+}
+
+// checkMemberEntries opens an eligibility issue for each confirmed entry of member in a band it can not play anymore,
+// and returns the open issues of the member
+func checkMemberEntries(tx *gorm.DB, member models.Member) ([]models.EligibilityIssue, error) {
+	var entries []models.Entry
+	if err := tx.Where("member_id = ? AND confirmed IS TRUE", member.ID).Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list member entries: %w", err)
+	}
+	var bands []models.Band
+	if err := tx.Where("id IN ?", append(lo.Map(entries, func(entry models.Entry, _ int) uuid.UUID {
+		return entry.BandID
+	}), uuid.Nil)).Find(&bands).Error; err != nil {
+		return nil, fmt.Errorf("failed to list member bands: %w", err)
+	}
+	bandsByID := lo.KeyBy(bands, func(band models.Band) uuid.UUID {
+		return band.ID
+	})
+
+	ineligibleEntryIDs := []uuid.UUID{uuid.Nil}
+	for _, entry := range entries {
+		reason := ineligibilityReason(member, bandsByID[entry.BandID])
+		if reason == "" {
+			continue
+		}
+		ineligibleEntryIDs = append(ineligibleEntryIDs, entry.ID)
+
+		// An issue already decided by an admin is left untouched
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "entry_id"}},
+			Where:     clause.Where{Exprs: []clause.Expression{clause.Eq{Column: "eligibility_issues.status", Value: models.EligibilityIssueStatus_OPEN}}},
+			DoUpdates: clause.AssignmentColumns([]string{"reason", "points", "category", "updated_at"}),
+		}).Create(&models.EligibilityIssue{
+			EntryID:  entry.ID,
+			MemberID: member.ID,
+			BandID:   entry.BandID,
+			Reason:   reason,
+			Points:   member.Points,
+			Category: member.Category,
+			Status:   models.EligibilityIssueStatus_OPEN,
+		}).Error; err != nil {
+			return nil, fmt.Errorf("failed to create eligibility issue: %w", err)
+		}
+	}
+
+	// The member fits again the bands of the other open issues, or their entries were deleted
+	if err := tx.Where("member_id = ? AND status = ? AND entry_id NOT IN ?", member.ID, models.EligibilityIssueStatus_OPEN, ineligibleEntryIDs).
+		Delete(&models.EligibilityIssue{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete eligibility issues: %w", err)
+	}
+
+	issues := []models.EligibilityIssue{}
+	if err := tx.Where("member_id = ? AND status = ?", member.ID, models.EligibilityIssueStatus_OPEN).Find(&issues).Error; err != nil {
+		return nil, fmt.Errorf("failed to list eligibility issues: %w", err)
+	}
+	return issues, nil
+}
+
+// RunPointsSync synchronises the points every POINTS_SYNC_INTERVAL (a day by default) until ctx is done, 0 disables it
+func (api *API) RunPointsSync(ctx context.Context) {
+	interval := defaultPointsSyncInterval
+	if value := os.Getenv("POINTS_SYNC_INTERVAL"); value != "" {
+		var err error
+		if interval, err = time.ParseDuration(value); err != nil {
+			log.Printf("points sync: invalid POINTS_SYNC_INTERVAL: %s", err)
+			return
+		}
+	}
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		report, err := api.SyncPoints(ctx)
+		if err != nil {
+			log.Printf("points sync: %s", err)
+			sentry.CaptureException(err)
+			continue
+		}
+		log.Printf("points sync: %d members, %d updated, %d reconciled, %d failed, %d open eligibility issues, %d licence issues", report.Members, report.Updated, report.Reconciled, report.Failed, report.OpenIssues, report.LicenceIssues)
+	}
+}
+
+// SyncMembersPoints runs the points synchronisation right away
+func (api *API) SyncMembersPoints(ctx *gin.Context) {
+	report, err := api.SyncPoints(ctx.Request.Context())
+	if err != nil {
+		ctx.AbortWithError(ffttErrorStatus(err), err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, report)
+}
+
+type EligibilityIssueView struct {
+	models.EligibilityIssue
+	FirstName     string
+	LastName      string
+	PermitID      string
+	OwnerEmail    string
+	BandName      string
+	BandDay       int
+	BandMaxPoints float64
+}
+
+// ListEligibilityIssues lists the entries whose member no longer fits the band, the open ones by default
+func (api *API) ListEligibilityIssues(ctx *gin.Context) {
+	status := ctx.DefaultQuery("status", models.EligibilityIssueStatus_OPEN)
+
+	issues := []EligibilityIssueView{}
+	if err := api.db.Model(&models.EligibilityIssue{}).
+		Select(`eligibility_issues.*,
+			members.first_name, members.last_name, members.permit_id,
+			users.email AS owner_email,
+			bands.name AS band_name, bands.day AS band_day, bands.max_points AS band_max_points`).
+		Joins("JOIN members ON members.id = eligibility_issues.member_id").
+		Joins("JOIN users ON users.id = members.user_id").
+		Joins("JOIN bands ON bands.id = eligibility_issues.band_id").
+		Where("eligibility_issues.status = ?", status).
+		Order("bands.day ASC, bands.name ASC, members.last_name ASC").
+		Scan(&issues).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list eligibility issues: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"issues": issues})
+}
+
+type ResolveEligibilityIssueInput struct {
+	Policy string `binding:"required,oneof=keep move withdraw"`
+	// BandID is the band the entry is moved to, required by the move policy
+	BandID uuid.UUID
+}
+
+var (
+	eligibilityIssueResolvedError = errors.New("eligibility issue already resolved")
+	moveBandNotPossibleError      = errors.New("the member can not play the band")
+)
+
+// ResolveEligibilityIssue keeps the entry, moves it to another band or withdraws it.
+// The owner of the member is notified of a move or a withdrawal by the entries summary.
+func (api *API) ResolveEligibilityIssue(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid eligibility issue id: %s", ctx.Param("id")))
+		return
+	}
+
+	var input ResolveEligibilityIssueInput
+	if err = ctx.ShouldBindBodyWith(&input, binding.JSON); err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
+		return
+	}
+	if input.Policy == EligibilityPolicy_MOVE && input.BandID == uuid.Nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: BandID is required to move the entry"))
+		return
+	}
+
+	var issue models.EligibilityIssue
+	err = api.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&issue, id).Error; err != nil {
+			return err
+		}
+		if issue.Status != models.EligibilityIssueStatus_OPEN {
+			return eligibilityIssueResolvedError
+		}
+
+		var previousBandIDs []uuid.UUID
+		if err := tx.Model(&models.Entry{}).
+			Where("member_id = ? AND confirmed IS TRUE", issue.MemberID).
+			Pluck("band_id", &previousBandIDs).Error; err != nil {
+			return fmt.Errorf("failed to list member entries: %w", err)
+		}
+
+		now := time.Now()
+		status := models.EligibilityIssueStatus_KEPT
+		switch input.Policy {
+		case EligibilityPolicy_MOVE:
+			status = models.EligibilityIssueStatus_MOVED
+			if err := moveEntry(tx, issue, input.BandID, previousBandIDs, user.ID); err != nil {
+				return err
+			}
+		case EligibilityPolicy_WITHDRAW:
+			status = models.EligibilityIssueStatus_WITHDRAWN
+		}
+
+		if input.Policy != EligibilityPolicy_KEEP {
+			if err := tx.Where("id = ?", issue.EntryID).
+				Updates(&models.Entry{
+					DeletedAt: gorm.DeletedAt{Time: now, Valid: true},
+					DeletedBy: uuid.NullUUID{UUID: user.ID, Valid: true},
+				}).Error; err != nil {
+				return fmt.Errorf("failed to delete entry: %w", err)
+			}
+			if err := scheduleEntriesDigest(tx, issue.MemberID, previousBandIDs); err != nil {
+				return fmt.Errorf("failed to schedule entries digest: %w", err)
+			}
+		}
+
+		issue.Status = status
+		issue.ResolvedBy = uuid.NullUUID{UUID: user.ID, Valid: true}
+		issue.ResolvedAt = &now
+		if input.Policy == EligibilityPolicy_MOVE {
+			issue.MovedToBandID = uuid.NullUUID{UUID: input.BandID, Valid: true}
+		}
+		return tx.Model(&issue).
+			Select("status", "resolved_by", "resolved_at", "moved_to_band_id").
+			Updates(&issue).Error
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("eligibility issue %s not found", id))
+		case errors.Is(err, eligibilityIssueResolvedError),
+			errors.Is(err, moveBandNotPossibleError),
+			errors.Is(err, limitThreeBandsPerDayReachedError),
+			errors.Is(err, limitSameColorPerDayReachedError):
+			ctx.AbortWithError(http.StatusConflict, err)
+		default:
+			ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to resolve eligibility issue: %w", err))
+		}
+		return
+	}
+
+	ctx.JSON(http.StatusOK, issue)
+}
+
+// moveEntry confirms an entry of the member of issue in bandID, which it must be able to play with its current bands
+func moveEntry(tx *gorm.DB, issue models.EligibilityIssue, bandID uuid.UUID, previousBandIDs []uuid.UUID, userID uuid.UUID) error {
+	var member models.Member
+	if err := tx.First(&member, issue.MemberID).Error; err != nil {
+		return fmt.Errorf("failed to get member: %w", err)
+	}
+
+	var band models.Band
+	if err := tx.Scopes(possibleBandsScope(member)).Where("id = ?", bandID).First(&band).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return moveBandNotPossibleError
+		}
+		return fmt.Errorf("failed to get band: %w", err)
+	}
+	if lo.Contains(previousBandIDs, bandID) {
+		return moveBandNotPossibleError
+	}
+
+	bandIDs := append(lo.Without(previousBandIDs, issue.BandID), bandID)
+	var bands []models.Band
+	if err := tx.Where("id IN ?", bandIDs).Find(&bands).Error; err != nil {
+		return fmt.Errorf("failed to list bands: %w", err)
+	}
+	if err := enforceBandsLimit(bands); err != nil {
+		return err
+	}
+
+	// A lock of the member in the band would conflict with the new entry
+	if err := tx.Where("member_id = ? AND band_id = ? AND confirmed IS FALSE", member.ID, bandID).Delete(&models.Entry{}).Error; err != nil {
+		return fmt.Errorf("failed to delete locked entries: %w", err)
+	}
+	if err := tx.Create(&models.Entry{
+		BandID:      bandID,
+		MemberID:    member.ID,
+		ExpiresAt:   time.Now(),
+		Confirmed:   true,
+		SessionID:   uuid.New(),
+		CreatedBy:   uuid.NullUUID{UUID: userID, Valid: true},
+		ConfirmedBy: uuid.NullUUID{UUID: userID, Valid: true},
+	}).Error; err != nil {
+		return fmt.Errorf("failed to create entry: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package public
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestIneligibilityReason(t *testing.T) {
+	member := models.Member{Sex: "M", Points: 850, Category: "S"}
+
+	require.Empty(t, ineligibilityReason(member, models.Band{SexAllowed: models.BandSex_ALL, MaxPoints: 899}))
+	require.Equal(t, models.EligibilityReason_MAX_POINTS, ineligibilityReason(member, models.Band{SexAllowed: models.BandSex_ALL, MaxPoints: 799}))
+	require.Empty(t, ineligibilityReason(member, models.Band{SexAllowed: models.BandSex_M, MaxPoints: 899, OnlyCategories: pq.StringArray{"S", "V1"}}))
+	require.Equal(t, models.EligibilityReason_CATEGORY, ineligibilityReason(member, models.Band{SexAllowed: models.BandSex_ALL, MaxPoints: 899, OnlyCategories: pq.StringArray{"C1"}}))
+	require.Equal(t, models.EligibilityReason_SEX, ineligibilityReason(models.Member{Sex: "M"}, models.Band{SexAllowed: models.BandSex_F, MaxPoints: 899}))
+}
+
+func TestSyncPoints(t *testing.T) {
+	setup := func(t *testing.T, env testEnv) (models.Member, []models.Band, models.Entry) {
+		bands := []models.Band{
+			{Name: "A", Day: 1, Color: models.BandColor_BLUE, SexAllowed: models.BandSex_ALL, MaxPoints: 599, MaxEntries: 10},
+			{Name: "B", Day: 1, Color: models.BandColor_PINK, SexAllowed: models.BandSex_ALL, MaxPoints: 1199, MaxEntries: 10},
+		}
+		require.NoError(t, env.db.Create(&bands).Error)
+
+		member := models.Member{FirstName: "Jean", LastName: "Pierre", Sex: "M", Points: 580, Category: "S", PermitID: "123456", UserID: env.user.ID}
+		require.NoError(t, env.db.Create(&member).Error)
+		entry := models.Entry{MemberID: member.ID, BandID: bands[0].ID, Confirmed: true}
+		require.NoError(t, env.db.Create(&entry).Error)
+
+		expectedFFTTReq, err := http.NewRequest(http.MethodGet, "https://fftt.dafunker.com/v1/joueur/123456", nil)
+		require.NoError(t, err)
+		env.httpClient.EXPECT().Do(expectedFFTTReq).Return(&http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewReader([]byte(`{"nom":"Pierre","prenom":"Jean","licence":"123456","sexe":"M","point":642,"cat":"S","nomclub":"Caillouville"}`))),
+		}, nil)
+
+		res := performRequest("POST", "/api/admin/points-sync", nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+		require.Equal(t, http.StatusOK, res.Code)
+		var report PointsSyncReport
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&report))
+		require.Equal(t, PointsSyncReport{Members: 1, Updated: 1, OpenIssues: 1}, report)
+
+		return member, bands, entry
+	}
+	resolve := func(t *testing.T, env testEnv, body map[string]interface{}) *http.Response {
+		res := performRequest("GET", "/api/admin/eligibility-issues", nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+		require.Equal(t, http.StatusOK, res.Code)
+		var got struct {
+			Issues []EligibilityIssueView
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		require.Len(t, got.Issues, 1)
+		require.Equal(t, models.EligibilityReason_MAX_POINTS, got.Issues[0].Reason)
+		require.Equal(t, "A", got.Issues[0].BandName)
+		require.Equal(t, 642.0, got.Issues[0].Points)
+
+		payload, err := json.Marshal(body)
+		require.NoError(t, err)
+		return performRequest("POST", fmt.Sprintf("/api/admin/eligibility-issues/%s/resolve", got.Issues[0].ID), bytes.NewBuffer(payload), map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router).Result()
+	}
+	t.Run("Withdraw", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		member, _, entry := setup(t, env)
+
+		require.NoError(t, env.db.First(&member, member.ID).Error)
+		require.Equal(t, 642.0, member.Points)
+		var changes []models.MemberChange
+		require.NoError(t, env.db.Where("member_id = ?", member.ID).Find(&changes).Error)
+		require.Len(t, changes, 1)
+		require.Equal(t, "Points", changes[0].Field)
+		require.Equal(t, "580", changes[0].PreviousValue)
+		require.Equal(t, "642", changes[0].Value)
+		require.Equal(t, models.MemberChangeSource_SYNC, changes[0].Source)
+
+		res := resolve(t, env, map[string]interface{}{"Policy": EligibilityPolicy_WITHDRAW})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		require.ErrorIs(t, env.db.First(&entry, entry.ID).Error, gorm.ErrRecordNotFound)
+		var digest models.EntriesDigest
+		require.NoError(t, env.db.Where("member_id = ?", member.ID).First(&digest).Error)
+		require.NotNil(t, digest.DueAt)
+	})
+	t.Run("ClubNumber", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		// The FFTT does not return the club number, the directory knows it
+		require.NoError(t, env.db.Create(&models.Player{PermitID: "123456", LastName: "Pierre", FirstName: "Jean", ClubNumber: "08950103", Search: "pierre jean 123456", Source: models.PlayerSource_IMPORT}).Error)
+		member, _, _ := setup(t, env)
+
+		require.NoError(t, env.db.First(&member, member.ID).Error)
+		require.Equal(t, "08950103", member.ClubNumber)
+		var change models.MemberChange
+		require.NoError(t, env.db.Where("member_id = ? AND field = ?", member.ID, "ClubNumber").First(&change).Error)
+		require.Equal(t, models.MemberChangeSource_SYNC, change.Source)
+	})
+	t.Run("Move", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		member, bands, _ := setup(t, env)
+
+		res := resolve(t, env, map[string]interface{}{"Policy": EligibilityPolicy_MOVE, "BandID": bands[1].ID})
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		var entries []models.Entry
+		require.NoError(t, env.db.Where("member_id = ? AND confirmed IS TRUE", member.ID).Find(&entries).Error)
+		require.Len(t, entries, 1)
+		require.Equal(t, bands[1].ID, entries[0].BandID)
+
+		var issue models.EligibilityIssue
+		require.NoError(t, env.db.Where("member_id = ?", member.ID).First(&issue).Error)
+		require.Equal(t, models.EligibilityIssueStatus_MOVED, issue.Status)
+	})
+	t.Run("MoveToIneligibleBand", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		_, bands, _ := setup(t, env)
+
+		res := resolve(t, env, map[string]interface{}{"Policy": EligibilityPolicy_MOVE, "BandID": bands[0].ID})
+		require.Equal(t, http.StatusConflict, res.StatusCode)
+	})
+}
@@ -0,0 +1,37 @@
+package public
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+type UpdatePreferencesInput struct {
+	Language string `binding:"required,oneof=fr en"`
+}
+
+// UpdatePreferences updates the settings of the current user, such as the language of the emails
+func (api *API) UpdatePreferences(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var input UpdatePreferencesInput
+	err = ctx.ShouldBindBodyWith(&input, binding.JSON)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
+		return
+	}
+
+	if err = api.db.Model(user).Updates(models.User{Language: input.Language}).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to update user: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, user)
+}
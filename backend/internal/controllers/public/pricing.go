@@ -0,0 +1,138 @@
+package public
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/SuperPingPong/tournoi/internal/pricing"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// getPricingRules returns the pricing rules of the tournament, the bands cost their price until an admin sets them
+func getPricingRules(db *gorm.DB) (pricing.Rules, error) {
+	var rules pricing.Rules
+	var row models.PricingRules
+	err := db.First(&row, models.PricingRulesID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return rules, nil
+	}
+	if err != nil {
+		return rules, fmt.Errorf("failed to get pricing rules: %w", err)
+	}
+	if err = json.Unmarshal(row.Rules, &rules); err != nil {
+		return rules, fmt.Errorf("invalid pricing rules: %w", err)
+	}
+	return rules, nil
+}
+
+func memberPlayer(member models.Member) pricing.Player {
+	return pricing.Player{Category: member.Category, ClubNumber: member.ClubNumber}
+}
+
+// priceEntries returns the price of each entry of member. The entries which are not at one of the due indexes,
+// scratched or on the waiting list, keep the price of their band and do not count for the multi-band discounts.
+// The breakdown by day leaves them out as well.
+func priceEntries(rules pricing.Rules, member models.Member, entries []pricing.Entry, due []int) ([]pricing.Price, pricing.Breakdown) {
+	prices := make([]pricing.Price, len(entries))
+	dueEntries := make([]pricing.Entry, 0, len(due))
+	for i, entry := range entries {
+		prices[i] = pricing.Price{BandPrice: entry.BandPrice, Adjustments: []pricing.Adjustment{}, Total: entry.BandPrice}
+	}
+	for _, i := range due {
+		dueEntries = append(dueEntries, entries[i])
+	}
+	duePrices := rules.Price(memberPlayer(member), dueEntries)
+	for i, price := range duePrices {
+		prices[due[i]] = price
+	}
+	return prices, pricing.NewBreakdown(dueEntries, duePrices)
+}
+
+// priceDueEntries sets the price of the entries of member, see priceEntries
+func priceDueEntries(rules pricing.Rules, member models.Member, entries []DueEntry) {
+	var due []int
+	pricingEntries := make([]pricing.Entry, len(entries))
+	for i, entry := range entries {
+		if entry.due() {
+			due = append(due, i)
+		}
+		pricingEntries[i] = pricing.Entry{BandName: entry.BandName, Day: entry.BandDay, BandPrice: entry.BandPrice, RegisteredAt: entry.CreatedAt}
+	}
+	prices, _ := priceEntries(rules, member, pricingEntries, due)
+	for i, price := range prices {
+		entries[i].Price, entries[i].Adjustments = price.Total, price.Adjustments
+	}
+}
+
+// priceMemberEntries sets the price of the entries of member and returns its breakdown by day, see priceEntries
+func priceMemberEntries(rules pricing.Rules, member models.Member, entries []ListMembersEntry) pricing.Breakdown {
+	var due []int
+	pricingEntries := make([]pricing.Entry, len(entries))
+	for i, entry := range entries {
+		if entry.due() {
+			due = append(due, i)
+		}
+		pricingEntries[i] = pricing.Entry{BandName: entry.BandName, Day: entry.BandDay, BandPrice: entry.BandPrice, RegisteredAt: entry.CreatedAt}
+	}
+	prices, breakdown := priceEntries(rules, member, pricingEntries, due)
+	for i, price := range prices {
+		entries[i].Price, entries[i].Adjustments = price.Total, price.Adjustments
+	}
+	return breakdown
+}
+
+func (api *API) GetPricingRules(ctx *gin.Context) {
+	rules, err := getPricingRules(api.db)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &rules)
+}
+
+type UpdatePricingRulesInput struct {
+	Categories []pricing.CategoryPrice     `binding:"dive"`
+	Clubs      []pricing.ClubDiscount      `binding:"dive"`
+	MultiBand  []pricing.MultiBandDiscount `binding:"dive"`
+	Late       []pricing.LateSurcharge     `binding:"dive"`
+}
+
+// UpdatePricingRules replaces the pricing rules, they apply to the amounts due by every member, paid ones included
+func (api *API) UpdatePricingRules(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var input UpdatePricingRulesInput
+	if err = ctx.ShouldBindBodyWith(&input, binding.JSON); err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
+		return
+	}
+
+	rules := pricing.Rules(input)
+	data, err := json.Marshal(rules)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to encode pricing rules: %w", err))
+		return
+	}
+	if err = api.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(&models.PricingRules{
+		ID:        models.PricingRulesID,
+		Rules:     data,
+		UpdatedBy: uuid.NullUUID{UUID: user.ID, Valid: true},
+	}).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to update pricing rules: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, &rules)
+}
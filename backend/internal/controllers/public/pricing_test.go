@@ -0,0 +1,141 @@
+package public
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/SuperPingPong/tournoi/internal/pricing"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceDueEntries(t *testing.T) {
+	rules := pricing.Rules{MultiBand: []pricing.MultiBandDiscount{{From: 2, Discount: 2}}}
+	entries := []DueEntry{
+		{BandName: "A", BandDay: 1, BandPrice: 10, Status: models.CheckInStatus_SCRATCHED},
+		{BandName: "B", BandDay: 1, BandPrice: 9},
+		{BandName: "C", BandDay: 1, BandPrice: 8},
+	}
+
+	priceDueEntries(rules, models.Member{}, entries)
+	// The scratched entry is not played, B is the first band of the day
+	require.Equal(t, 10, entries[0].Price)
+	require.Empty(t, entries[0].Adjustments)
+	require.Equal(t, 9, entries[1].Price)
+	require.Equal(t, 6, entries[2].Price)
+	require.Equal(t, []pricing.Adjustment{{Kind: pricing.Adjustment_MULTI_BAND, Amount: -2}}, entries[2].Adjustments)
+
+	// The waiting list does not count until promoted
+	entries = []DueEntry{
+		{BandName: "A", BandDay: 1, BandPrice: 12, Waiting: true},
+		{BandName: "B", BandDay: 1, BandPrice: 10},
+	}
+	priceDueEntries(rules, models.Member{}, entries)
+	require.Equal(t, 12, entries[0].Price)
+	require.Empty(t, entries[0].Adjustments)
+	require.Equal(t, 10, entries[1].Price)
+	require.Equal(t, 10, amountDue(entries))
+
+	entries[0].Promoted = true
+	priceDueEntries(rules, models.Member{}, entries)
+	require.Equal(t, 12, entries[0].Price)
+	require.Equal(t, 8, entries[1].Price)
+	require.Equal(t, 20, amountDue(entries))
+}
+
+func TestPriceMemberEntries(t *testing.T) {
+	rules := pricing.Rules{MultiBand: []pricing.MultiBandDiscount{{From: 2, Discount: 2}}}
+	entries := []ListMembersEntry{
+		{BandName: "A", BandDay: 1, BandPrice: 12, BandRank: 3, BandMaxEntries: 2},
+		{BandName: "B", BandDay: 1, BandPrice: 10, BandRank: 1, BandMaxEntries: 2},
+		{BandName: "C", BandDay: 2, BandPrice: 9, BandRank: 1, BandMaxEntries: 2, CheckInStatus: models.CheckInStatus_SCRATCHED},
+	}
+
+	breakdown := priceMemberEntries(rules, models.Member{}, entries)
+	// The waiting list entry keeps the price of its band but is not in the breakdown, as in the ledger
+	require.Equal(t, 12, entries[0].Price)
+	require.Equal(t, 10, entries[1].Price)
+	require.Empty(t, entries[1].Adjustments)
+	require.Equal(t, 9, entries[2].Price)
+	require.Equal(t, pricing.Breakdown{
+		Days:  []pricing.Day{{Day: 1, BandPrice: 10, Adjustments: []pricing.Adjustment{}, Total: 10}},
+		Total: 10,
+	}, breakdown)
+
+	entries[0].Promoted = true
+	breakdown = priceMemberEntries(rules, models.Member{}, entries)
+	require.Equal(t, 8, entries[1].Price)
+	require.Equal(t, 20, breakdown.Total)
+}
+
+func TestPricingRules(t *testing.T) {
+	env := getTestEnv(t)
+	defer env.teardown()
+
+	res := performRequest("GET", "/api/admin/pricing-rules", nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	var rules pricing.Rules
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&rules))
+	require.Equal(t, pricing.Rules{}, rules)
+
+	body := `{"Categories":[{"Categories":["J1"],"Price":6}],"MultiBand":[{"From":2,"Discount":2}],"Late":[{"After":"2024-06-01T00:00:00+02:00","Surcharge":3}]}`
+	res = performRequest("PUT", "/api/admin/pricing-rules", strings.NewReader(body), map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusForbidden, res.Code)
+	res = performRequest("PUT", "/api/admin/pricing-rules", strings.NewReader(`{"MultiBand":[{"From":1,"Discount":2}]}`), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusBadRequest, res.Code)
+	res = performRequest("PUT", "/api/admin/pricing-rules", strings.NewReader(body), map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+
+	bands := []models.Band{
+		{Name: "A", Day: 1, Color: models.BandColor_PINK, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 2, Price: 9},
+		{Name: "B", Day: 1, Color: models.BandColor_BLUE, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 2, Price: 8},
+		{Name: "C", Day: 2, Color: models.BandColor_GREEN, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 2, Price: 10},
+	}
+	require.NoError(t, env.db.Create(&bands).Error)
+	member := models.Member{FirstName: "Jean", LastName: "Pierre", Sex: "M", Points: 580, Category: "J1", PermitID: "123451", UserID: env.user.ID}
+	require.NoError(t, env.db.Create(&member).Error)
+	registeredAt := time.Date(2024, time.May, 20, 12, 0, 0, 0, time.UTC)
+	for _, band := range bands {
+		// Band C is registered late
+		if band.Name == "C" {
+			registeredAt = time.Date(2024, time.June, 2, 12, 0, 0, 0, time.UTC)
+		}
+		require.NoError(t, env.db.Create(&models.Entry{MemberID: member.ID, BandID: band.ID, Confirmed: true, CreatedAt: registeredAt}).Error)
+	}
+
+	res = performRequest("GET", "/api/members", nil, map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	var result ListMembersMembers
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&result))
+	require.Len(t, result.Members, 1)
+	breakdown := result.Members[0].Price
+	require.Len(t, breakdown.Days, 2)
+	// A and B at the youth price of 6 €, with 2 € off B
+	require.Equal(t, 17, breakdown.Days[0].BandPrice)
+	require.Equal(t, 10, breakdown.Days[0].Total)
+	require.Equal(t, []pricing.Adjustment{
+		{Kind: pricing.Adjustment_CATEGORY, Amount: -5},
+		{Kind: pricing.Adjustment_MULTI_BAND, Amount: -2},
+	}, breakdown.Days[0].Adjustments)
+	require.Equal(t, 9, breakdown.Days[1].Total)
+	require.Equal(t, 19, breakdown.Total)
+
+	// The ledger applies the same prices
+	ledger, err := memberLedger(env.db, member)
+	require.NoError(t, err)
+	require.Equal(t, 10, ledger[0].Due)
+	require.Equal(t, 9, ledger[1].Due)
+}
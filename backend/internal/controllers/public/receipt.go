@@ -0,0 +1,256 @@
+package public
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/SuperPingPong/tournoi/internal/pricing"
+	"github.com/SuperPingPong/tournoi/internal/receipts"
+	"github.com/getsentry/sentry-go"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// defaultReceiptOrganizer is the club the cheques are payable to, see the payment reminder of the emails
+const defaultReceiptOrganizer = "E.P. de Lognes"
+
+var nothingPaidError = errors.New("nothing was paid for this day")
+
+var paymentMethodLabels = map[string]string{
+	models.PaymentMethod_CASH:   "Espèces",
+	models.PaymentMethod_CHEQUE: "Chèque",
+	models.PaymentMethod_ONLINE: "Paiement en ligne",
+}
+
+var adjustmentLabels = map[string]string{
+	pricing.Adjustment_CATEGORY:   "Tarif catégorie",
+	pricing.Adjustment_CLUB:       "Tarif club",
+	pricing.Adjustment_MULTI_BAND: "Réduction multi-tableaux",
+	pricing.Adjustment_LATE:       "Majoration inscription tardive",
+}
+
+// receiptLetterhead returns the organizer printed on the receipts, from the RECEIPT_ORGANIZER_* environment variables
+func receiptLetterhead() receipts.Letterhead {
+	letterhead := receipts.Letterhead{
+		Name:    os.Getenv("RECEIPT_ORGANIZER_NAME"),
+		Address: os.Getenv("RECEIPT_ORGANIZER_ADDRESS"),
+		SIRET:   os.Getenv("RECEIPT_ORGANIZER_SIRET"),
+	}
+	if letterhead.Name == "" {
+		letterhead.Name = defaultReceiptOrganizer
+	}
+	return letterhead
+}
+
+// issueReceipt returns the receipt of what member paid for day, a new one when the amount changed since the last receipt
+func issueReceipt(tx *gorm.DB, member models.Member, day LedgerDay) (models.Receipt, error) {
+	amount := day.Paid - day.Refunded
+	if amount <= 0 {
+		return models.Receipt{}, nothingPaidError
+	}
+
+	var receipt models.Receipt
+	err := tx.Where("member_id = ? AND day = ?", member.ID, day.Day).Order("number DESC").First(&receipt).Error
+	if err == nil && receipt.Amount == amount {
+		return receipt, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return receipt, fmt.Errorf("failed to get receipt: %w", err)
+	}
+
+	// The numbers are sequential, without gaps
+	if err = tx.Exec("LOCK TABLE receipts IN EXCLUSIVE MODE").Error; err != nil {
+		return receipt, fmt.Errorf("failed to lock receipts: %w", err)
+	}
+	var number int
+	if err = tx.Model(&models.Receipt{}).Select("COALESCE(MAX(number), 0)").Scan(&number).Error; err != nil {
+		return receipt, fmt.Errorf("failed to get receipt number: %w", err)
+	}
+	receipt = models.Receipt{Number: number + 1, MemberID: member.ID, Day: day.Day, Amount: amount}
+	if err = tx.Create(&receipt).Error; err != nil {
+		return receipt, fmt.Errorf("failed to create receipt: %w", err)
+	}
+	return receipt, nil
+}
+
+// buildReceipt details the receipt of member for day: the entries which are due with their adjustments, and the payments
+func buildReceipt(receipt models.Receipt, member models.Member, day LedgerDay, letterhead receipts.Letterhead, location *time.Location) receipts.Receipt {
+	result := receipts.Receipt{
+		Number:     receipt.Number,
+		IssuedAt:   receipt.CreatedAt.In(location),
+		Letterhead: letterhead,
+		MemberName: fmt.Sprintf("%s %s", member.FirstName, member.LastName),
+		PermitID:   member.PermitID,
+		ClubName:   member.ClubName,
+		Day:        day.Day,
+		Due:        day.Due,
+		Paid:       day.Paid - day.Refunded,
+	}
+	if startDate, err := tournamentStartDate(); err == nil {
+		result.Date = startDate.AddDate(0, 0, day.Day-1)
+	}
+
+	// The adjustments are summed by kind, after the entries
+	var kinds []string
+	adjustments := map[string]int{}
+	for _, entry := range day.Entries {
+		if !entry.due() {
+			continue
+		}
+		result.Lines = append(result.Lines, receipts.Line{Label: "Tableau " + entry.BandName, Amount: entry.BandPrice})
+		for _, adjustment := range entry.Adjustments {
+			if _, ok := adjustments[adjustment.Kind]; !ok {
+				kinds = append(kinds, adjustment.Kind)
+			}
+			adjustments[adjustment.Kind] += adjustment.Amount
+		}
+	}
+	for _, kind := range kinds {
+		result.Lines = append(result.Lines, receipts.Line{Label: adjustmentLabels[kind], Amount: adjustments[kind]})
+	}
+
+	for _, payment := range day.Payments {
+		line := receipts.Line{
+			Label:  fmt.Sprintf("%s %s", payment.CreatedAt.In(location).Format("02/01/2006"), paymentMethodLabels[payment.Method]),
+			Amount: payment.Amount,
+		}
+		if payment.Kind == models.PaymentKind_REFUND {
+			line.Label += " (remboursement)"
+			line.Amount = -payment.Amount
+		}
+		result.Payments = append(result.Payments, line)
+	}
+	return result
+}
+
+// memberReceipts issues the receipts of member, for every day it paid or only for day when it is not 0
+func memberReceipts(tx *gorm.DB, member models.Member, day int, location *time.Location) ([]receipts.Receipt, error) {
+	ledger, err := memberLedger(tx, member)
+	if err != nil {
+		return nil, err
+	}
+	letterhead := receiptLetterhead()
+	var result []receipts.Receipt
+	for _, ledgerDay := range ledger {
+		if day != 0 && ledgerDay.Day != day {
+			continue
+		}
+		receipt, err := issueReceipt(tx, member, ledgerDay)
+		if errors.Is(err, nothingPaidError) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, buildReceipt(receipt, member, ledgerDay, letterhead, location))
+	}
+	return result, nil
+}
+
+// GetMemberReceipt downloads the PDF receipt of what a member paid for the :day
+func (api *API) GetMemberReceipt(ctx *gin.Context) {
+	member, ok := api.getOwnedMember(ctx)
+	if !ok {
+		return
+	}
+	day, err := strconv.Atoi(ctx.Param("day"))
+	if err != nil || day < 1 {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid day: %s", ctx.Param("day")))
+		return
+	}
+	location, err := time.LoadLocation(tournamentTimezone)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to load timezone: %w", err))
+		return
+	}
+
+	var result []receipts.Receipt
+	err = api.db.Transaction(func(tx *gorm.DB) error {
+		result, err = memberReceipts(tx, member, day, location)
+		return err
+	})
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	if len(result) == 0 {
+		ctx.AbortWithError(http.StatusConflict, fmt.Errorf("no receipt for member %s on day %d: %w", member.ID, day, nothingPaidError))
+		return
+	}
+
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, result[0].Filename()))
+	ctx.Data(http.StatusOK, "application/pdf", result[0].PDF())
+}
+
+// ExportReceipts downloads a zip of the receipts of every member who paid, of all days or of ?day=
+func (api *API) ExportReceipts(ctx *gin.Context) {
+	var day int
+	if value := ctx.Query("day"); value != "" {
+		var err error
+		if day, err = strconv.Atoi(value); err != nil || day < 1 {
+			ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid day: %s", value))
+			return
+		}
+	}
+	location, err := time.LoadLocation(tournamentTimezone)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to load timezone: %w", err))
+		return
+	}
+
+	var result []receipts.Receipt
+	err = api.db.Transaction(func(tx *gorm.DB) error {
+		// The members who withdrew keep the receipts of what they paid
+		var members []models.Member
+		if err := tx.Unscoped().
+			Where("id IN (SELECT DISTINCT member_id FROM payments)").
+			Order("last_name ASC, first_name ASC").
+			Find(&members).Error; err != nil {
+			return fmt.Errorf("failed to list members: %w", err)
+		}
+		for _, member := range members {
+			memberResult, err := memberReceipts(tx, member, day, location)
+			if err != nil {
+				return err
+			}
+			result = append(result, memberResult...)
+		}
+		return nil
+	})
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	filename := fmt.Sprintf("%s-recus.zip", time.Now().Format("2006-01-02-15-04-05"))
+	ctx.Header("Content-Type", "application/zip")
+	ctx.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	ctx.Status(http.StatusOK)
+	if err = writeReceiptsZip(ctx.Writer, result); err != nil {
+		log.Printf("receipts export: %s", err)
+		sentry.CaptureException(err)
+	}
+}
+
+// writeReceiptsZip writes a PDF per receipt, in the order of the members
+func writeReceiptsZip(w io.Writer, result []receipts.Receipt) error {
+	archive := zip.NewWriter(w)
+	for _, receipt := range result {
+		writer, err := archive.Create(receipt.Filename())
+		if err != nil {
+			return err
+		}
+		if _, err = writer.Write(receipt.PDF()); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
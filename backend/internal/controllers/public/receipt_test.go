@@ -0,0 +1,125 @@
+package public
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/SuperPingPong/tournoi/internal/pricing"
+	"github.com/SuperPingPong/tournoi/internal/receipts"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildReceipt(t *testing.T) {
+	t.Setenv("TOURNAMENT_START_DATE", "2024-06-08")
+	paris, err := time.LoadLocation(tournamentTimezone)
+	require.NoError(t, err)
+	paidAt := time.Date(2024, time.June, 8, 22, 30, 0, 0, time.UTC)
+
+	day := LedgerDay{
+		Day: 2,
+		Entries: []DueEntry{
+			{BandName: "A", BandPrice: 9, Price: 9},
+			{BandName: "B", BandPrice: 8, Price: 6, Adjustments: []pricing.Adjustment{{Kind: pricing.Adjustment_MULTI_BAND, Amount: -2}}},
+			{BandName: "C", BandPrice: 8, Price: 6, Adjustments: []pricing.Adjustment{{Kind: pricing.Adjustment_MULTI_BAND, Amount: -2}}},
+			{BandName: "D", BandPrice: 10, Price: 10, Status: models.CheckInStatus_SCRATCHED},
+			{BandName: "E", BandPrice: 7, Price: 7, Waiting: true},
+		},
+		Payments: []PaymentLine{
+			{Payment: models.Payment{Kind: models.PaymentKind_PAYMENT, Method: models.PaymentMethod_CHEQUE, Amount: 21, CreatedAt: paidAt}},
+			{Payment: models.Payment{Kind: models.PaymentKind_REFUND, Method: models.PaymentMethod_CASH, Amount: 2, CreatedAt: paidAt}},
+		},
+		Due:      21,
+		Paid:     21,
+		Refunded: 2,
+	}
+	member := models.Member{FirstName: "Jean", LastName: "PIERRE", PermitID: "123451", ClubName: "LOGNES EP"}
+
+	receipt := buildReceipt(models.Receipt{Number: 3, CreatedAt: paidAt}, member, day, receipts.Letterhead{Name: "E.P. de Lognes"}, paris)
+	require.Equal(t, 3, receipt.Number)
+	require.Equal(t, "Jean PIERRE", receipt.MemberName)
+	require.Equal(t, "2024-06-09", receipt.Date.Format("2006-01-02"))
+	// The scratched entries and the waiting list are not due
+	require.Equal(t, []receipts.Line{
+		{Label: "Tableau A", Amount: 9},
+		{Label: "Tableau B", Amount: 8},
+		{Label: "Tableau C", Amount: 8},
+		{Label: "Réduction multi-tableaux", Amount: -4},
+	}, receipt.Lines)
+	require.Equal(t, []receipts.Line{
+		{Label: "09/06/2024 Chèque", Amount: 21},
+		{Label: "09/06/2024 Espèces (remboursement)", Amount: -2},
+	}, receipt.Payments)
+	require.Equal(t, 21, receipt.Due)
+	require.Equal(t, 19, receipt.Paid)
+}
+
+func TestReceipts(t *testing.T) {
+	env := getTestEnv(t)
+	defer env.teardown()
+
+	band := models.Band{Name: "A", Day: 1, Color: models.BandColor_PINK, SexAllowed: models.BandSex_ALL, MaxPoints: 999, MaxEntries: 2, Price: 9}
+	require.NoError(t, env.db.Create(&band).Error)
+	member := models.Member{FirstName: "Jean", LastName: "Pierre", Sex: "M", Points: 580, Category: "S", PermitID: "123451", UserID: env.user.ID}
+	require.NoError(t, env.db.Create(&member).Error)
+	require.NoError(t, env.db.Create(&models.Entry{MemberID: member.ID, BandID: band.ID, Confirmed: true, CreatedAt: time.Now()}).Error)
+
+	url := fmt.Sprintf("/api/members/%s/receipts/1", member.ID)
+	res := performRequest("GET", url, nil, map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusConflict, res.Code)
+
+	recordPayment := func(body string) {
+		res := performRequest("POST", fmt.Sprintf("/api/admin/payments/members/%s", member.ID), strings.NewReader(body), map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+		require.Equal(t, http.StatusCreated, res.Code)
+	}
+	recordPayment(`{"Day":1,"Method":"cash","Amount":9}`)
+
+	for _, jwt := range []string{env.jwt, env.jwt, env.adminJWT} {
+		res = performRequest("GET", url, nil, map[string]string{
+			"Authorization": "Bearer " + jwt,
+		}, env.api.router)
+		require.Equal(t, http.StatusOK, res.Code)
+		require.Equal(t, "application/pdf", res.Header().Get("Content-Type"))
+		// The receipt is only issued once
+		require.Equal(t, `attachment; filename="recu-000001.pdf"`, res.Header().Get("Content-Disposition"))
+		require.True(t, bytes.HasPrefix(res.Body.Bytes(), []byte("%PDF-")))
+	}
+
+	// A new receipt is issued when the amount changes
+	recordPayment(`{"Day":1,"Kind":"refund","Method":"cash","Amount":4}`)
+	res = performRequest("GET", url, nil, map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	require.Equal(t, `attachment; filename="recu-000002.pdf"`, res.Header().Get("Content-Disposition"))
+
+	res = performRequest("GET", "/api/admin/receipts", nil, map[string]string{
+		"Authorization": "Bearer " + env.jwt,
+	}, env.api.router)
+	require.Equal(t, http.StatusForbidden, res.Code)
+	res = performRequest("GET", "/api/admin/receipts?day=1", nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	archive, err := zip.NewReader(bytes.NewReader(res.Body.Bytes()), int64(res.Body.Len()))
+	require.NoError(t, err)
+	require.Len(t, archive.File, 1)
+	require.Equal(t, "recu-000002.pdf", archive.File[0].Name)
+
+	res = performRequest("GET", "/api/admin/receipts?day=2", nil, map[string]string{
+		"Authorization": "Bearer " + env.adminJWT,
+	}, env.api.router)
+	require.Equal(t, http.StatusOK, res.Code)
+	archive, err = zip.NewReader(bytes.NewReader(res.Body.Bytes()), int64(res.Body.Len()))
+	require.NoError(t, err)
+	require.Empty(t, archive.File)
+}
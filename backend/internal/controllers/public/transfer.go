@@ -0,0 +1,312 @@
+package public
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/emails"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/SuperPingPong/tournoi/internal/outbox"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
+	"gorm.io/gorm"
+)
+
+var (
+	transferNotPendingError    = errors.New("transfer is not pending anymore")
+	transferOwnerMismatchError = errors.New("member owner changed since the transfer was requested")
+)
+
+type RequestMemberTransferInput struct {
+	PermitID string `binding:"required,min=2"`
+	Reason   string
+}
+
+// RequestMemberTransfer lets a user ask for the ownership of a member registered by another user
+func (api *API) RequestMemberTransfer(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	var input RequestMemberTransferInput
+	err = ctx.ShouldBindBodyWith(&input, binding.JSON)
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid input: %w", err))
+		return
+	}
+
+	var member models.Member
+	err = api.db.Where("permit_id = ?", input.PermitID).First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("member with permit %s not found", input.PermitID))
+			return
+		}
+
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get member: %w", err))
+		return
+	}
+
+	if member.UserID == user.ID {
+		ctx.AbortWithError(http.StatusConflict, fmt.Errorf("member with permit %s already belongs to user %s", input.PermitID, user.ID))
+		return
+	}
+
+	transfer := models.MemberTransfer{
+		MemberID:   member.ID,
+		FromUserID: member.UserID,
+		ToUserID:   user.ID,
+		Status:     models.MemberTransferStatus_PENDING,
+		Reason:     input.Reason,
+	}
+	err = api.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&transfer).Error; err != nil {
+			return err
+		}
+
+		var owner models.User
+		if err := tx.First(&owner, member.UserID).Error; err != nil {
+			return fmt.Errorf("failed to get member owner: %w", err)
+		}
+		message, err := api.renderEmail(owner.Email, owner.Language, emails.MemberTransferRequested{
+			FirstName:      member.FirstName,
+			LastName:       member.LastName,
+			RequesterEmail: user.Email,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to build email: %w", err)
+		}
+		return outbox.Enqueue(tx, "member-transfer-requested:"+transfer.ID.String(), message)
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			ctx.AbortWithError(http.StatusConflict, fmt.Errorf("a transfer of member with permit %s is already pending", input.PermitID))
+			return
+		}
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to create transfer: %w", err))
+		return
+	}
+	api.outbox.Wake()
+
+	ctx.JSON(http.StatusCreated, &transfer)
+}
+
+type MemberTransferView struct {
+	models.MemberTransfer
+	MemberPermitID  string
+	MemberFirstName string
+	MemberLastName  string
+	FromUserEmail   string
+	ToUserEmail     string
+}
+
+// ListMemberTransfers lists the transfers involving the current user, or all of them for admins
+func (api *API) ListMemberTransfers(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	query := api.db.Model(&models.MemberTransfer{})
+	if !user.IsAdmin {
+		query = query.Where("member_transfers.from_user_id = ? OR member_transfers.to_user_id = ?", user.ID, user.ID)
+	}
+	if status := ctx.Query("status"); status != "" {
+		query = query.Where("member_transfers.status = ?", status)
+	}
+
+	transfers := []MemberTransferView{}
+	if err = query.
+		Select(`member_transfers.*,
+			members.permit_id AS member_permit_id,
+			members.first_name AS member_first_name,
+			members.last_name AS member_last_name,
+			from_users.email AS from_user_email,
+			to_users.email AS to_user_email`).
+		Joins("JOIN members ON members.id = member_transfers.member_id").
+		Joins("JOIN users AS from_users ON from_users.id = member_transfers.from_user_id").
+		Joins("JOIN users AS to_users ON to_users.id = member_transfers.to_user_id").
+		Order("member_transfers.created_at DESC").
+		Scan(&transfers).Error; err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to list transfers: %w", err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"transfers": transfers})
+}
+
+// getMemberTransfer returns the requested transfer if the current user is allowed to decide on it.
+// The owner of the member and admins can approve or reject it, the requester can only cancel it.
+func (api *API) getMemberTransfer(ctx *gin.Context, user *models.User, requester bool) (*models.MemberTransfer, bool) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, fmt.Errorf("invalid transfer id: %s", ctx.Param("id")))
+		return nil, false
+	}
+
+	var transfer models.MemberTransfer
+	err = api.db.First(&transfer, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("transfer %s not found", id))
+			return nil, false
+		}
+
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to get transfer: %w", err))
+		return nil, false
+	}
+
+	allowed := user.IsAdmin || transfer.FromUserID == user.ID
+	if requester {
+		allowed = transfer.ToUserID == user.ID
+	}
+	if !allowed {
+		ctx.AbortWithError(http.StatusNotFound, fmt.Errorf("transfer %s not found", id))
+		return nil, false
+	}
+
+	if transfer.Status != models.MemberTransferStatus_PENDING {
+		ctx.AbortWithError(http.StatusConflict, transferNotPendingError)
+		return nil, false
+	}
+
+	return &transfer, true
+}
+
+// ApproveMemberTransfer moves the member, and therefore its entries, history and notifications, to the requester
+func (api *API) ApproveMemberTransfer(ctx *gin.Context) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	transfer, ok := api.getMemberTransfer(ctx, user, false)
+	if !ok {
+		return
+	}
+
+	var member models.Member
+	err = api.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.MemberTransfer{}).
+			Where("id = ? AND status = ?", transfer.ID, models.MemberTransferStatus_PENDING).
+			Updates(models.MemberTransfer{
+				Status:    models.MemberTransferStatus_APPROVED,
+				DecidedBy: uuid.NullUUID{UUID: user.ID, Valid: true},
+				DecidedAt: &now,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update transfer: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return transferNotPendingError
+		}
+
+		result = tx.Model(&models.Member{}).
+			Where("id = ? AND user_id = ?", transfer.MemberID, transfer.FromUserID).
+			Update("user_id", transfer.ToUserID)
+		if result.Error != nil {
+			return fmt.Errorf("failed to update member: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return transferOwnerMismatchError
+		}
+
+		// Other requests for the same member are now void
+		if err := tx.Model(&models.MemberTransfer{}).
+			Where("member_id = ? AND status = ? AND id <> ?", transfer.MemberID, models.MemberTransferStatus_PENDING, transfer.ID).
+			Updates(models.MemberTransfer{
+				Status:    models.MemberTransferStatus_CANCELLED,
+				DecidedBy: uuid.NullUUID{UUID: user.ID, Valid: true},
+				DecidedAt: &now,
+			}).Error; err != nil {
+			return fmt.Errorf("failed to cancel other transfers: %w", err)
+		}
+
+		if err := tx.First(&member, transfer.MemberID).Error; err != nil {
+			return fmt.Errorf("failed to get member: %w", err)
+		}
+
+		var users []models.User
+		if err := tx.Where("id IN ?", []uuid.UUID{transfer.FromUserID, transfer.ToUserID}).Find(&users).Error; err != nil {
+			return fmt.Errorf("failed to get users: %w", err)
+		}
+		newOwner, _ := lo.Find(users, func(u models.User) bool {
+			return u.ID == transfer.ToUserID
+		})
+		for _, recipient := range users {
+			message, err := api.renderEmail(recipient.Email, recipient.Language, emails.MemberTransferApproved{
+				FirstName:     member.FirstName,
+				LastName:      member.LastName,
+				NewOwnerEmail: newOwner.Email,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to build email: %w", err)
+			}
+			if err = outbox.Enqueue(tx, fmt.Sprintf("member-transfer-approved:%s:%s", transfer.ID, recipient.ID), message); err != nil {
+				return fmt.Errorf("failed to enqueue email: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, transferNotPendingError) || errors.Is(err, transferOwnerMismatchError) {
+			ctx.AbortWithError(http.StatusConflict, err)
+			return
+		}
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	api.outbox.Wake()
+
+	ctx.JSON(http.StatusOK, &member)
+}
+
+func (api *API) RejectMemberTransfer(ctx *gin.Context) {
+	api.closeMemberTransfer(ctx, false, models.MemberTransferStatus_REJECTED)
+}
+
+func (api *API) CancelMemberTransfer(ctx *gin.Context) {
+	api.closeMemberTransfer(ctx, true, models.MemberTransferStatus_CANCELLED)
+}
+
+func (api *API) closeMemberTransfer(ctx *gin.Context, requester bool, status string) {
+	user, err := ExtractUserFromContext(ctx)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	transfer, ok := api.getMemberTransfer(ctx, user, requester)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	result := api.db.Model(&models.MemberTransfer{}).
+		Where("id = ? AND status = ?", transfer.ID, models.MemberTransferStatus_PENDING).
+		Updates(models.MemberTransfer{
+			Status:    status,
+			DecidedBy: uuid.NullUUID{UUID: user.ID, Valid: true},
+			DecidedAt: &now,
+		})
+	if result.Error != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, fmt.Errorf("failed to update transfer: %w", result.Error))
+		return
+	}
+	if result.RowsAffected == 0 {
+		ctx.AbortWithError(http.StatusConflict, transferNotPendingError)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
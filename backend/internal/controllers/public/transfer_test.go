@@ -0,0 +1,213 @@
+package public
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/auth"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestMemberTransfer(t *testing.T) {
+	t.Run("AlreadyOwner", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		member := models.Member{
+			FirstName: "John",
+			LastName:  "Doe",
+			Sex:       "M",
+			PermitID:  "000000",
+			UserID:    env.user.ID,
+		}
+		require.NoError(t, env.db.Create(&member).Error)
+
+		body, err := json.Marshal(map[string]string{"PermitID": member.PermitID})
+		require.NoError(t, err)
+
+		res := performRequest("POST", "/api/member-transfers", bytes.NewBuffer(body), map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusConflict, res.Code)
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		body, err := json.Marshal(map[string]string{"PermitID": "000000"})
+		require.NoError(t, err)
+
+		res := performRequest("POST", "/api/member-transfers", bytes.NewBuffer(body), map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		var actual map[string]string
+		require.Equal(t, http.StatusNotFound, res.Code)
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&actual))
+		require.Equal(t, "member with permit 000000 not found", actual["error"])
+	})
+}
+
+func TestCloseMemberTransfer(t *testing.T) {
+	setup := func(t *testing.T, env testEnv) models.MemberTransfer {
+		owner := models.User{
+			Email: "hdupont@example.com",
+			Members: []models.Member{
+				{
+					FirstName: "Hervé",
+					LastName:  "Dupont",
+					Sex:       "M",
+					PermitID:  "000003",
+				},
+			},
+		}
+		require.NoError(t, env.db.Create(&owner).Error)
+
+		transfer := models.MemberTransfer{
+			MemberID:   owner.Members[0].ID,
+			FromUserID: owner.ID,
+			ToUserID:   env.user.ID,
+			Status:     models.MemberTransferStatus_PENDING,
+		}
+		require.NoError(t, env.db.Create(&transfer).Error)
+		return transfer
+	}
+	t.Run("CancelByRequester", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		transfer := setup(t, env)
+
+		res := performRequest("POST", fmt.Sprintf("/api/member-transfers/%s/cancel", transfer.ID), nil, map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusNoContent, res.Code)
+		require.NoError(t, env.db.First(&transfer, transfer.ID).Error)
+		require.Equal(t, models.MemberTransferStatus_CANCELLED, transfer.Status)
+		require.Equal(t, env.user.ID, transfer.DecidedBy.UUID)
+
+		// A closed transfer can't be closed again
+		res = performRequest("POST", fmt.Sprintf("/api/member-transfers/%s/cancel", transfer.ID), nil, map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusConflict, res.Code)
+	})
+	t.Run("ApproveByOwner", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		transfer := setup(t, env)
+
+		var owner models.User
+		require.NoError(t, env.db.First(&owner, transfer.FromUserID).Error)
+		ownerOTP := models.OTP{Email: owner.Email, Secret: "123456", ExpiresAt: time.Now().Add(otpExpirationDelay)}
+		require.NoError(t, env.db.Create(&ownerOTP).Error)
+		body, err := json.Marshal(auth.LoginRequest{Email: ownerOTP.Email, Secret: ownerOTP.Secret})
+		require.NoError(t, err)
+		res := performRequest("POST", "/api/login", bytes.NewBuffer(body), nil, env.api.router)
+		require.Equal(t, http.StatusOK, res.Code)
+		var login struct {
+			Token string
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&login))
+
+		res = performRequest("POST", fmt.Sprintf("/api/member-transfers/%s/approve", transfer.ID), nil, map[string]string{
+			"Authorization": "Bearer " + login.Token,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		require.NoError(t, env.db.First(&transfer, transfer.ID).Error)
+		require.Equal(t, models.MemberTransferStatus_APPROVED, transfer.Status)
+		require.Equal(t, owner.ID, transfer.DecidedBy.UUID)
+
+		var member models.Member
+		require.NoError(t, env.db.First(&member, transfer.MemberID).Error)
+		require.Equal(t, env.user.ID, member.UserID)
+
+		// Both the previous and the new owner are notified
+		_, err = env.api.outbox.ProcessBatch()
+		require.NoError(t, err)
+		sent := env.mailer.Sent()
+		require.Len(t, sent, 2)
+		require.ElementsMatch(t, []string{owner.Email, env.user.Email}, []string{sent[0].To, sent[1].To})
+	})
+	t.Run("RejectByRequester", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		transfer := setup(t, env)
+
+		// Only the owner or an admin can reject the transfer
+		res := performRequest("POST", fmt.Sprintf("/api/member-transfers/%s/reject", transfer.ID), nil, map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusNotFound, res.Code)
+	})
+	t.Run("RejectByAdmin", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		transfer := setup(t, env)
+
+		res := performRequest("POST", fmt.Sprintf("/api/member-transfers/%s/reject", transfer.ID), nil, map[string]string{
+			"Authorization": "Bearer " + env.adminJWT,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusNoContent, res.Code)
+		require.NoError(t, env.db.First(&transfer, transfer.ID).Error)
+		require.Equal(t, models.MemberTransferStatus_REJECTED, transfer.Status)
+
+		var member models.Member
+		require.NoError(t, env.db.First(&member, transfer.MemberID).Error)
+		require.Equal(t, transfer.FromUserID, member.UserID)
+	})
+}
+
+func TestListMemberTransfers(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		env := getTestEnv(t)
+		defer env.teardown()
+
+		owner := models.User{
+			Email: "hdupont@example.com",
+			Members: []models.Member{
+				{
+					FirstName: "Hervé",
+					LastName:  "Dupont",
+					Sex:       "M",
+					PermitID:  "000003",
+				},
+			},
+		}
+		require.NoError(t, env.db.Create(&owner).Error)
+		require.NoError(t, env.db.Create(&models.MemberTransfer{
+			MemberID:   owner.Members[0].ID,
+			FromUserID: owner.ID,
+			ToUserID:   env.user.ID,
+			Status:     models.MemberTransferStatus_PENDING,
+		}).Error)
+
+		res := performRequest("GET", "/api/member-transfers", nil, map[string]string{
+			"Authorization": "Bearer " + env.jwt,
+		}, env.api.router)
+
+		require.Equal(t, http.StatusOK, res.Code)
+		var got struct {
+			Transfers []MemberTransferView
+		}
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+		require.Len(t, got.Transfers, 1)
+		require.Equal(t, "000003", got.Transfers[0].MemberPermitID)
+		require.Equal(t, owner.Email, got.Transfers[0].FromUserEmail)
+		require.Equal(t, env.user.Email, got.Transfers[0].ToUserEmail)
+	})
+}
@@ -1,24 +1,16 @@
 package public
 
 import (
-	"encoding/base64"
-	"encoding/json"
 	"fmt"
-	"html"
-	"io/ioutil"
-	"log"
-	"mime"
 	"net/http"
-	"os"
-	"strings"
-
-	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
-	"google.golang.org/api/gmail/v1"
 
 	"github.com/SuperPingPong/tournoi/internal/auth"
+	"github.com/SuperPingPong/tournoi/internal/emails"
+	"github.com/SuperPingPong/tournoi/internal/mailer"
 	"github.com/SuperPingPong/tournoi/internal/models"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/samber/lo"
 	"gorm.io/gorm"
 )
 
@@ -43,183 +35,100 @@ func ExtractUserFromContext(ctx *gin.Context) (*models.User, error) {
 	return user, nil
 }
 
-func FilterByUserID(user *models.User) func(db *gorm.DB) *gorm.DB {
-	return func(db *gorm.DB) *gorm.DB {
-		if user.IsAdmin {
-			return db
-		}
-		return db.Where("user_id = ?", user.ID)
-	}
-}
-
-func loadTokenFromEnv() (*oauth2.Token, error) {
-	file := os.Getenv("TOKEN_JSON")
-	token := &oauth2.Token{}
-	err := json.Unmarshal([]byte(file), token)
-	if err != nil {
-		return nil, err
-	}
-
-	return token, nil
-}
-
-func saveTokenToEnv(token *oauth2.Token) error {
-	tokenJson, err := json.Marshal(token)
-	if err != nil {
-		return err
+// ExtractImpersonatorFromContext returns the ID of the admin impersonating the current user, if any
+func ExtractImpersonatorFromContext(ctx *gin.Context) uuid.NullUUID {
+	impersonatorValue, ok := ctx.Get(auth.ImpersonatorKey)
+	if !ok {
+		return uuid.NullUUID{}
 	}
 
-	err = os.Setenv("TOKEN_JSON", string(tokenJson))
-	if err != nil {
-		return err
+	impersonator, ok := impersonatorValue.(*models.User)
+	if !ok {
+		return uuid.NullUUID{}
 	}
 
-	return nil
+	return uuid.NullUUID{UUID: impersonator.ID, Valid: true}
 }
 
-func getClient(config *oauth2.Config) (*http.Client, error) {
-	token, err := loadTokenFromEnv()
-	if err != nil {
-		return nil, err
-	}
-
-	if !token.Valid() {
-		tokenSource := config.TokenSource(oauth2.NoContext, token)
-		newToken, err := tokenSource.Token()
+// AdminRequired rejects requests from users who are not admins
+func AdminRequired() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, err := ExtractUserFromContext(ctx)
 		if err != nil {
-			return nil, err
+			ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
 		}
-
-		// Save the updated token
-		err = saveTokenToEnv(newToken)
-		if err != nil {
-			return nil, err
+		if !user.IsAdmin {
+			ctx.AbortWithError(http.StatusForbidden, fmt.Errorf("user %s should be admin", user.ID.String()))
+			return
 		}
-
-		token = newToken
+		ctx.Next()
 	}
-
-	return config.Client(oauth2.NoContext, token), nil
 }
 
-func GetGmailService() (*gmail.Service, error) {
-
-	// Load client credentials
-	clientSecretData := os.Getenv("CREDENTIALS_JSON")
-
-	config, err := google.ConfigFromJSON([]byte(clientSecretData), gmail.MailGoogleComScope)
-	if err != nil {
-		log.Fatalf("Unable to parse client secret file to config: %v", err)
-	}
-
-	// Obtain an OAuth client
-	client, err := getClient(config)
-	if err != nil {
-		log.Fatalf("Unable to get OAuth client: %v", err)
-	}
-
-	// Create a Gmail service
-	var service *gmail.Service
-	service, err = gmail.New(client)
-	if err != nil {
-		log.Fatalf("Unable to create Gmail service: %v", err)
+// ClubManagerRequired rejects requests from users who don't manage a club
+func ClubManagerRequired() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		user, err := ExtractUserFromContext(ctx)
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		if !user.IsClubManager || user.ClubNumber == "" {
+			ctx.AbortWithError(http.StatusForbidden, fmt.Errorf("user %s should be club manager", user.ID.String()))
+			return
+		}
+		ctx.Next()
 	}
-
-	return service, err
 }
 
-/*
-func sendEmailOTP(to string, code string) error {
-	service, err := GetGmailService()
-
-	// Set up the email message
-	message := &gmail.Message{
-		Raw: base64.RawURLEncoding.EncodeToString([]byte(
-			fmt.Sprintf("To: %s\r\nSubject: OTP %s Tournoi de Lognes\r\n\r\nVoici votre code de vérification OTP: %s", to, code, code)),
-		),
-	}
-
-	_, err = service.Users.Messages.Send("me", message).Do()
-	if err != nil {
-		return err
+func FilterByUserID(user *models.User) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if user.IsAdmin {
+			return db
+		}
+		return db.Where("user_id = ?", user.ID)
 	}
-
-	return nil
 }
-*/
-
-func sendEmailHTMLOTP(to string, code string) error {
-	service, err := GetGmailService()
-	if err != nil {
-		return fmt.Errorf("failed to get Gmail service: %v", err)
-	}
 
-	// Read the HTML content from the file
-	htmlContent, err := ioutil.ReadFile("email_templates/otp_code.html")
+// renderEmail renders email in the language of the recipient
+func (api *API) renderEmail(to string, language string, email emails.Email) (*mailer.Message, error) {
+	rendered, err := api.emails.Render(language, email)
 	if err != nil {
-		return fmt.Errorf("failed to read email HTML file: %v", err)
-	}
-
-	// Replace the placeholder with the otp code
-	replacedContent := strings.Replace(string(htmlContent), "OTP_CODE", code, -1)
-
-	// Set up the email message
-	message := &gmail.Message{
-		Raw: base64.URLEncoding.EncodeToString([]byte(
-			fmt.Sprintf("To: %s\r\nSubject: OTP %s\r\nMIME-Version: 1.0\r\nContent-Type: text/html; charset=\"utf-8\"\r\n\r\n%s", to, code, replacedContent)),
-		),
-	}
-
-	_, err = service.Users.Messages.Send("me", message).Do()
-	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return &mailer.Message{
+		To:      to,
+		Subject: rendered.Subject,
+		HTML:    rendered.HTML,
+		Text:    rendered.Text,
+	}, nil
 }
 
-func sendEmailHTML(to string, lastName string, firstName string) error {
-	service, err := GetGmailService()
-	if err != nil {
-		return fmt.Errorf("failed to get Gmail service: %v", err)
-	}
-
-	// Read the HTML content from the file
-	htmlContent, err := ioutil.ReadFile("email_templates/register_confirm.html")
-	if err != nil {
-		return fmt.Errorf("failed to read email HTML file: %v", err)
-	}
-
-	// Replace the placeholder with the environment variable
-	externalURL := os.Getenv("EXTERNAL_URL")
-	if externalURL == "" {
-		return fmt.Errorf("EXTERNAL_URL environment variable not set")
-	}
-	replacedContent := strings.Replace(string(htmlContent), "EXTERNAL_URL", externalURL, -1)
-
-	// Escape the first name and last name to ensure proper encoding
-	escapedFirstName := html.EscapeString(firstName)
-	escapedLastName := html.EscapeString(lastName)
-
-	// Set up the email message
-	subject := fmt.Sprintf("Confirmation inscription %s %s Tournoi de Lognes", escapedLastName, escapedFirstName)
-	encodedSubject := encodeHeader(subject)
-	message := &gmail.Message{
-		Raw: base64.URLEncoding.EncodeToString([]byte(
-			fmt.Sprintf("To: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/html; charset=\"utf-8\"\r\n\r\n%s", to, encodedSubject, replacedContent)),
-		),
-	}
-
-	_, err = service.Users.Messages.Send("me", message).Do()
+func (api *API) sendEmailOTP(to string, language string, code string) error {
+	message, err := api.renderEmail(to, language, emails.OTP{Code: code, Validity: int(otpExpirationDelay.Minutes())})
 	if err != nil {
 		return err
 	}
-
-	return nil
+	return api.mailer.Send(message)
 }
 
-// encodeHeader encodes special characters in the given header string using MIME encoding
-func encodeHeader(header string) string {
-	encoded := mime.QEncoding.Encode("utf-8", header)
-	return encoded
+// clubMembersEmail maps members with their entries to the data of the club registrations email
+func clubMembersEmail(members []ListMembersMember) []emails.ClubMember {
+	return lo.Map(members, func(member ListMembersMember, _ int) emails.ClubMember {
+		return emails.ClubMember{
+			FirstName: member.FirstName,
+			LastName:  member.LastName,
+			PermitID:  member.PermitID,
+			Entries: lo.Map(member.Entries, func(entry ListMembersEntry, _ int) emails.Entry {
+				return emails.Entry{
+					BandName:   entry.BandName,
+					BandDay:    entry.BandDay,
+					Rank:       entry.BandRank,
+					MaxEntries: entry.BandMaxEntries,
+				}
+			}),
+		}
+	})
 }
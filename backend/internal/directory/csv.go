@@ -0,0 +1,114 @@
+package directory
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SuperPingPong/tournoi/internal/fftt"
+	"golang.org/x/text/encoding/charmap"
+)
+
+var ErrInvalidCSV = errors.New("invalid CSV")
+
+// csvColumns maps the normalized headers of the FFTT exports to the fields of the players
+var csvColumns = map[string]string{
+	"licence":   "permit",
+	"numlic":    "permit",
+	"nom":       "last_name",
+	"prenom":    "first_name",
+	"sexe":      "sex",
+	"points":    "points",
+	"point":     "points",
+	"cat":       "category",
+	"categorie": "category",
+	"club":      "club_name",
+	"nomclub":   "club_name",
+	"numclub":   "club_number",
+	"nclub":     "club_number",
+	"type":      "permit_type",
+}
+
+// ParseCSV reads the players of an FFTT export, delimited by semicolons or commas and encoded in UTF-8 or ISO-8859-1
+func ParseCSV(r io.Reader) ([]fftt.Player, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV: %w", err)
+	}
+	if !utf8.Valid(data) {
+		if data, err = charmap.ISO8859_1.NewDecoder().Bytes(data); err != nil {
+			return nil, fmt.Errorf("failed to decode CSV: %w", err)
+		}
+	}
+	// Excel prefixes its UTF-8 exports with a byte order mark
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		reader.Comma = ';'
+	}
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCSV, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidCSV)
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		if field, ok := csvColumns[strings.ReplaceAll(Normalize(name), " ", "")]; ok {
+			if _, exists := columns[field]; !exists {
+				columns[field] = i
+			}
+		}
+	}
+	for _, field := range []string{"permit", "last_name", "first_name"} {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("%w: missing %s column", ErrInvalidCSV, field)
+		}
+	}
+
+	players := []fftt.Player{}
+	for line, record := range records[1:] {
+		value := func(field string) string {
+			i, ok := columns[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		if value("permit") == "" {
+			continue
+		}
+
+		var points float64
+		if raw := value("points"); raw != "" {
+			if points, err = strconv.ParseFloat(strings.Replace(raw, ",", ".", 1), 64); err != nil {
+				return nil, fmt.Errorf("%w: invalid points %q on line %d", ErrInvalidCSV, raw, line+2)
+			}
+		}
+
+		players = append(players, fftt.Player{
+			PermitID:   value("permit"),
+			LastName:   value("last_name"),
+			FirstName:  value("first_name"),
+			Sex:        strings.ToUpper(value("sex")),
+			Points:     points,
+			Category:   value("category"),
+			ClubName:   value("club_name"),
+			ClubNumber: value("club_number"),
+			PermitType: value("permit_type"),
+		})
+	}
+	return players, nil
+}
@@ -0,0 +1,123 @@
+package directory
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/SuperPingPong/tournoi/internal/fftt"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/samber/lo"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Normalize lowercases the text and removes its accents and punctuation, so that "Hélène D'Aubigné" matches "helene d aubigne"
+func Normalize(text string) string {
+	decomposed, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn))), text)
+	if err != nil {
+		decomposed = text
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(decomposed), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
+
+func searchText(player fftt.Player) string {
+	return Normalize(strings.Join([]string{player.LastName, player.FirstName, player.ClubName, player.PermitID}, " "))
+}
+
+func FromFFTT(player fftt.Player, source string) models.Player {
+	return models.Player{
+		PermitID:   player.PermitID,
+		LastName:   player.LastName,
+		FirstName:  player.FirstName,
+		Sex:        player.Sex,
+		Points:     player.Points,
+		Category:   player.Category,
+		ClubName:   player.ClubName,
+		ClubNumber: player.ClubNumber,
+		PermitType: player.PermitType,
+		Search:     searchText(player),
+		Source:     source,
+	}
+}
+
+func ToFFTT(player models.Player) fftt.Player {
+	return fftt.Player{
+		LastName:   player.LastName,
+		FirstName:  player.FirstName,
+		PermitID:   player.PermitID,
+		Sex:        player.Sex,
+		Points:     player.Points,
+		Category:   player.Category,
+		ClubName:   player.ClubName,
+		ClubNumber: player.ClubNumber,
+		PermitType: player.PermitType,
+	}
+}
+
+// keepKnown updates a column unless the new value is empty, the search results of the FFTT lack the category and permit type
+func keepKnown(column string) clause.Assignment {
+	return clause.Assignment{
+		Column: clause.Column{Name: column},
+		Value:  gorm.Expr(fmt.Sprintf("COALESCE(NULLIF(excluded.%s, ''), players.%s)", column, column)),
+	}
+}
+
+// Upsert stores the players in the directory, updating the players already known by their permit
+func Upsert(db *gorm.DB, players []fftt.Player, source string) (int64, error) {
+	players = lo.UniqBy(lo.Filter(players, func(player fftt.Player, _ int) bool {
+		return player.PermitID != "" && (player.LastName != "" || player.FirstName != "")
+	}), func(player fftt.Player) string {
+		return player.PermitID
+	})
+	if len(players) == 0 {
+		return 0, nil
+	}
+	rows := lo.Map(players, func(player fftt.Player, _ int) models.Player {
+		return FromFFTT(player, source)
+	})
+
+	var imported int64
+	// A savepoint is used when the caller is in a transaction, so that a failed upsert does not abort it
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "permit_id"}},
+			DoUpdates: append(
+				clause.AssignmentColumns([]string{"last_name", "first_name", "points", "club_name", "search", "source", "updated_at"}),
+				keepKnown("sex"), keepKnown("category"), keepKnown("club_number"), keepKnown("permit_type"),
+			),
+		}).CreateInBatches(&rows, 500)
+		imported = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to upsert players: %w", err)
+	}
+	return imported, nil
+}
+
+// SearchScope filters the players whose words are similar to the query, or whose permit starts with it
+func SearchScope(query string) func(db *gorm.DB) *gorm.DB {
+	normalized := Normalize(query)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("? <% search OR permit_id LIKE ?", normalized, normalized+"%")
+	}
+}
+
+// RankScope orders the players of SearchScope, the most similar first
+func RankScope(query string) func(db *gorm.DB) *gorm.DB {
+	normalized := Normalize(query)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:  "word_similarity(?, search) DESC, last_name, first_name",
+			Vars: []interface{}{normalized},
+		}})
+	}
+}
@@ -0,0 +1,65 @@
+package directory
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SuperPingPong/tournoi/internal/fftt"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestNormalize(t *testing.T) {
+	require.Equal(t, "helene d aubigne", Normalize("  Hélène D'Aubigné "))
+	require.Equal(t, "francois lefevre", Normalize("FRANÇOIS LEFÈVRE"))
+	require.Equal(t, "t t saint etienne 0042", Normalize("T.T. Saint-Étienne / 0042"))
+	require.Equal(t, "", Normalize(" - "))
+}
+
+func TestFromFFTT(t *testing.T) {
+	player := FromFFTT(fftt.Player{
+		LastName:  "LEFÈVRE",
+		FirstName: "Chloé",
+		PermitID:  "7512345",
+		ClubName:  "US Créteil",
+	}, "import")
+	require.Equal(t, "lefevre chloe us creteil 7512345", player.Search)
+	require.Equal(t, "import", player.Source)
+}
+
+func TestParseCSV(t *testing.T) {
+	t.Run("Semicolons", func(t *testing.T) {
+		players, err := ParseCSV(strings.NewReader(
+			"Licence;Nom;Prénom;Sexe;Points;Cat;Club;N° Club\n" +
+				"7512345;LEFÈVRE;Chloé;f;1234,5;S;US Créteil;08940001\n" +
+				";MISSING;Permit;M;500;S;;\n" +
+				"7512346;DUPONT;Jean;M;;V1;US Créteil;08940001\n",
+		))
+		require.NoError(t, err)
+		require.Equal(t, []fftt.Player{
+			{PermitID: "7512345", LastName: "LEFÈVRE", FirstName: "Chloé", Sex: "F", Points: 1234.5, Category: "S", ClubName: "US Créteil", ClubNumber: "08940001"},
+			{PermitID: "7512346", LastName: "DUPONT", FirstName: "Jean", Sex: "M", Category: "V1", ClubName: "US Créteil", ClubNumber: "08940001"},
+		}, players)
+	})
+
+	t.Run("CommasLatin1", func(t *testing.T) {
+		content, err := charmap.ISO8859_1.NewEncoder().String("licence,nom,prenom,nomclub\n7512345,LEFÈVRE,Chloé,US Créteil\n")
+		require.NoError(t, err)
+
+		players, err := ParseCSV(strings.NewReader(content))
+		require.NoError(t, err)
+		require.Equal(t, []fftt.Player{
+			{PermitID: "7512345", LastName: "LEFÈVRE", FirstName: "Chloé", ClubName: "US Créteil"},
+		}, players)
+	})
+
+	t.Run("MissingColumn", func(t *testing.T) {
+		_, err := ParseCSV(strings.NewReader("licence;nom\n7512345;LEFÈVRE\n"))
+		require.ErrorIs(t, err, ErrInvalidCSV)
+	})
+
+	t.Run("InvalidPoints", func(t *testing.T) {
+		_, err := ParseCSV(strings.NewReader("licence;nom;prenom;points\n7512345;LEFÈVRE;Chloé;abc\n"))
+		require.ErrorIs(t, err, ErrInvalidCSV)
+	})
+}
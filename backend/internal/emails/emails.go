@@ -0,0 +1,110 @@
+package emails
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/samber/lo"
+)
+
+const (
+	Language_FR string = "fr"
+	Language_EN        = "en"
+
+	DefaultLanguage = Language_FR
+)
+
+var Languages = []string{Language_FR, Language_EN}
+
+// Email is the data of an email, each kind of email has its own struct and templates
+type Email interface {
+	// Template is the name of the templates in each language directory, without extension
+	Template() string
+}
+
+// Rendered is an email ready to be sent
+type Rendered struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// templateData is given to every template, the email itself is available as .Data
+type templateData struct {
+	ExternalURL string
+	Language    string
+	// OnlinePayments is set when the entries can be paid online, see the payment reminder
+	OnlinePayments bool
+	Data           Email
+}
+
+// Renderer renders the templates of dir:
+//   - layout.html wraps the "content" template of every HTML email
+//   - <language>/common.{html,txt} hold snippets shared by the emails of a language
+//   - <language>/<template>.html defines the "content" of the HTML part
+//   - <language>/<template>.txt is the text/plain part and defines the "subject"
+type Renderer struct {
+	dir            string
+	externalURL    string
+	onlinePayments bool
+}
+
+func NewRenderer(dir string, externalURL string, onlinePayments bool) *Renderer {
+	return &Renderer{dir: dir, externalURL: externalURL, onlinePayments: onlinePayments}
+}
+
+// Language returns language if it is supported, the default language otherwise
+func Language(language string) string {
+	if lo.Contains(Languages, language) {
+		return language
+	}
+	return DefaultLanguage
+}
+
+func (r *Renderer) Render(language string, email Email) (*Rendered, error) {
+	language = Language(language)
+	data := templateData{
+		ExternalURL:    r.externalURL,
+		Language:       language,
+		OnlinePayments: r.onlinePayments,
+		Data:           email,
+	}
+
+	htmlTemplate, err := htmltemplate.ParseFiles(
+		filepath.Join(r.dir, "layout.html"),
+		filepath.Join(r.dir, language, "common.html"),
+		filepath.Join(r.dir, language, email.Template()+".html"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML template %s: %w", email.Template(), err)
+	}
+	var html bytes.Buffer
+	if err = htmlTemplate.ExecuteTemplate(&html, "layout.html", data); err != nil {
+		return nil, fmt.Errorf("failed to render HTML template %s: %w", email.Template(), err)
+	}
+
+	textTemplate, err := texttemplate.ParseFiles(
+		filepath.Join(r.dir, language, "common.txt"),
+		filepath.Join(r.dir, language, email.Template()+".txt"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse text template %s: %w", email.Template(), err)
+	}
+	var subject, text bytes.Buffer
+	if err = textTemplate.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to render subject of %s: %w", email.Template(), err)
+	}
+	if err = textTemplate.ExecuteTemplate(&text, email.Template()+".txt", data); err != nil {
+		return nil, fmt.Errorf("failed to render text template %s: %w", email.Template(), err)
+	}
+
+	return &Rendered{
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    html.String(),
+		Text:    text.String(),
+	}, nil
+}
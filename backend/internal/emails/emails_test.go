@@ -0,0 +1,91 @@
+package emails
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	renderer := NewRenderer("../../email_templates", "https://tournoi.example.com", false)
+
+	t.Run("AllSamples", func(t *testing.T) {
+		for name, sample := range Samples() {
+			for _, language := range Languages {
+				rendered, err := renderer.Render(language, sample)
+				require.NoError(t, err, "%s in %s", name, language)
+				require.NotEmpty(t, rendered.Subject, "%s in %s", name, language)
+				require.NotContains(t, rendered.Subject, "\n")
+				require.Contains(t, rendered.HTML, `<html lang="`+language+`"`)
+				require.NotEmpty(t, rendered.Text, "%s in %s", name, language)
+			}
+		}
+	})
+	t.Run("Escaping", func(t *testing.T) {
+		rendered, err := renderer.Render(Language_FR, RegisterConfirm{FirstName: "<script>", LastName: "Doe"})
+		require.NoError(t, err)
+		require.NotContains(t, rendered.HTML, "<script>")
+		require.Contains(t, rendered.HTML, "&lt;script&gt;")
+		require.Contains(t, rendered.HTML, `href="https://tournoi.example.com"`)
+		require.Contains(t, rendered.Text, "<script> Doe")
+	})
+	t.Run("Ranks", func(t *testing.T) {
+		email := Samples()["club_register_confirm"]
+
+		rendered, err := renderer.Render(Language_FR, email)
+		require.NoError(t, err)
+		require.Equal(t, "Confirmation inscriptions club LOGNES EP Tournoi de Lognes", rendered.Subject)
+		require.Contains(t, rendered.HTML, "Tableau A (jour 1) : rang 12")
+		require.Contains(t, rendered.HTML, "Tableau E (jour 2) : liste d'attente n°3")
+		require.Contains(t, rendered.HTML, "Aucun tableau")
+		require.Contains(t, rendered.Text, "  - Tableau E (jour 2) : liste d'attente n°3")
+
+		rendered, err = renderer.Render(Language_EN, email)
+		require.NoError(t, err)
+		require.Contains(t, rendered.HTML, "Band E (day 2): waiting list #3")
+	})
+	t.Run("Adjustments", func(t *testing.T) {
+		email := Samples()["register_confirm"]
+
+		rendered, err := renderer.Render(Language_FR, email)
+		require.NoError(t, err)
+		require.Contains(t, rendered.HTML, "Réduction multi-tableaux")
+		require.Contains(t, rendered.HTML, "-2&nbsp;€")
+		require.Contains(t, rendered.Text, "  - Réduction multi-tableaux : -2 €")
+		require.Contains(t, rendered.Text, "Total jour 1 : 17 €")
+		// The waiting list is left out of the totals
+		require.Contains(t, rendered.Text, "  - Tableau E : liste d'attente n°3, 0 €")
+		require.Contains(t, rendered.Text, "Total : 17 €")
+		require.Contains(t, rendered.Text, "ne sont dus qu'une fois une place obtenue")
+
+		rendered, err = renderer.Render(Language_EN, email)
+		require.NoError(t, err)
+		require.Contains(t, rendered.Text, "  - Multi-band discount: -€2")
+	})
+	t.Run("PaymentReminder", func(t *testing.T) {
+		email := Samples()["register_confirm"]
+
+		rendered, err := renderer.Render(Language_FR, email)
+		require.NoError(t, err)
+		require.Contains(t, rendered.Text, "Les paiements sont uniquement sur place")
+
+		online := NewRenderer("../../email_templates", "https://tournoi.example.com", true)
+		for _, name := range []string{"register_confirm", "club_register_confirm"} {
+			rendered, err = online.Render(Language_FR, Samples()[name])
+			require.NoError(t, err)
+			require.Contains(t, rendered.Text, "Les paiements se font en ligne depuis votre espace")
+			require.Contains(t, rendered.HTML, `<a href="https://tournoi.example.com">en ligne</a>`)
+			require.NotContains(t, rendered.Text, "uniquement sur place")
+
+			rendered, err = online.Render(Language_EN, Samples()[name])
+			require.NoError(t, err)
+			require.Contains(t, rendered.Text, "payments are made online from your account")
+		}
+	})
+	t.Run("UnknownLanguage", func(t *testing.T) {
+		rendered, err := renderer.Render("de", OTP{Code: "123456", Validity: 10})
+		require.NoError(t, err)
+		require.Equal(t, "OTP 123456", rendered.Subject)
+		require.Contains(t, rendered.Text, "valable 10 minutes")
+	})
+}
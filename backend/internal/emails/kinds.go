@@ -0,0 +1,229 @@
+package emails
+
+import "sort"
+
+// OTP carries the code used to log in
+type OTP struct {
+	Code string
+	// Validity of the code, in minutes
+	Validity int
+}
+
+func (OTP) Template() string { return "otp_code" }
+
+// RegisterConfirm summarizes the entries of a member and what changed since the previous summary
+type RegisterConfirm struct {
+	FirstName string
+	LastName  string
+	// Update is false for the first summary of the member
+	Update bool
+	Days   []Day
+	Total  int
+	// Badge is set when the QR code to show at the check-in desk is attached
+	Badge bool
+
+	Added   []Entry
+	Removed []Entry
+}
+
+// Day groups the entries of a day of the tournament
+type Day struct {
+	Day     int
+	Entries []Entry
+	// Adjustments are the discounts and surcharges of the entries, summed by kind
+	Adjustments []Adjustment
+	Price       int
+}
+
+// Waiting tells whether some entries are on the waiting list, they are left out of the totals
+func (r RegisterConfirm) Waiting() bool {
+	for _, day := range r.Days {
+		for _, entry := range day.Entries {
+			if entry.WaitingListRank() > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NewDays groups entries by day, in the order of the days, with the price of the main draw
+func NewDays(entries []Entry) ([]Day, int) {
+	var days []Day
+	var total int
+	for _, entry := range entries {
+		index := -1
+		for i := range days {
+			if days[i].Day == entry.BandDay {
+				index = i
+			}
+		}
+		if index == -1 {
+			days = append(days, Day{Day: entry.BandDay})
+			index = len(days) - 1
+		}
+		days[index].Entries = append(days[index].Entries, entry)
+		// The waiting list is only due once the player gets a place
+		if entry.WaitingListRank() > 0 {
+			continue
+		}
+		days[index].Price += entry.Price
+		total += entry.Price
+		for _, adjustment := range entry.Adjustments {
+			days[index].addAdjustment(adjustment)
+			days[index].Price += adjustment.Amount
+			total += adjustment.Amount
+		}
+	}
+	sort.SliceStable(days, func(i, j int) bool {
+		return days[i].Day < days[j].Day
+	})
+	return days, total
+}
+
+func (d *Day) addAdjustment(adjustment Adjustment) {
+	for i := range d.Adjustments {
+		if d.Adjustments[i].Kind == adjustment.Kind {
+			d.Adjustments[i].Amount += adjustment.Amount
+			return
+		}
+	}
+	d.Adjustments = append(d.Adjustments, adjustment)
+}
+
+func (RegisterConfirm) Template() string { return "register_confirm" }
+
+// Entry is a band a member is registered to
+type Entry struct {
+	BandName   string
+	BandDay    int
+	Rank       int
+	MaxEntries int
+	// Price of the band in euros
+	Price int
+	// Adjustments are the discounts and surcharges of the pricing rules
+	Adjustments []Adjustment
+}
+
+// Adjustment changes the price of an entry, see pricing.Adjustment
+type Adjustment struct {
+	Kind string
+	// Amount in euros, negative for discounts
+	Amount int
+}
+
+// Discount tells whether the adjustment lowers the price
+func (a Adjustment) Discount() bool {
+	return a.Amount < 0
+}
+
+// Abs is the amount without its sign
+func (a Adjustment) Abs() int {
+	if a.Amount < 0 {
+		return -a.Amount
+	}
+	return a.Amount
+}
+
+// Due is the price of the entry in the main draw, 0 on the waiting list
+func (e Entry) Due() int {
+	if e.WaitingListRank() > 0 {
+		return 0
+	}
+	return e.Price
+}
+
+// WaitingListRank returns the position on the waiting list, 0 if the entry is in the main draw
+func (e Entry) WaitingListRank() int {
+	if e.Rank > e.MaxEntries {
+		return e.Rank - e.MaxEntries
+	}
+	return 0
+}
+
+type ClubMember struct {
+	FirstName string
+	LastName  string
+	PermitID  string
+	Entries   []Entry
+}
+
+// ClubRegistrations summarizes a bulk registration of a club manager
+type ClubRegistrations struct {
+	ClubName string
+	Members  []ClubMember
+}
+
+func (ClubRegistrations) Template() string { return "club_register_confirm" }
+
+// MemberTransferRequested is sent to the owner of a member another user asks to manage
+type MemberTransferRequested struct {
+	FirstName      string
+	LastName       string
+	RequesterEmail string
+}
+
+func (MemberTransferRequested) Template() string { return "member_transfer_requested" }
+
+// MemberTransferApproved is sent to both the previous and the new owner of a member
+type MemberTransferApproved struct {
+	FirstName     string
+	LastName      string
+	NewOwnerEmail string
+}
+
+func (MemberTransferApproved) Template() string { return "member_transfer_approved" }
+
+// Samples returns an email of each kind filled with sample data, indexed by template
+func Samples() map[string]Email {
+	samples := []Email{
+		OTP{Code: "123456", Validity: 10},
+		sampleRegisterConfirm(),
+		ClubRegistrations{
+			ClubName: "LOGNES EP",
+			Members: []ClubMember{
+				{
+					FirstName: "Jean",
+					LastName:  "PIERRE",
+					PermitID:  "123456",
+					Entries: []Entry{
+						{BandName: "A", BandDay: 1, Rank: 12, MaxEntries: 72},
+						{BandName: "E", BandDay: 2, Rank: 75, MaxEntries: 72},
+					},
+				},
+				{
+					FirstName: "Marie",
+					LastName:  "DUPONT",
+					PermitID:  "654321",
+				},
+			},
+		},
+		MemberTransferRequested{FirstName: "Jean", LastName: "PIERRE", RequesterEmail: "john.doe@example.com"},
+		MemberTransferApproved{FirstName: "Jean", LastName: "PIERRE", NewOwnerEmail: "john.doe@example.com"},
+	}
+
+	indexed := map[string]Email{}
+	for _, sample := range samples {
+		indexed[sample.Template()] = sample
+	}
+	return indexed
+}
+
+func sampleRegisterConfirm() RegisterConfirm {
+	entries := []Entry{
+		{BandName: "A", BandDay: 1, Rank: 12, MaxEntries: 72, Price: 9},
+		{BandName: "C", BandDay: 1, Rank: 40, MaxEntries: 72, Price: 10, Adjustments: []Adjustment{{Kind: "multi_band", Amount: -2}}},
+		{BandName: "E", BandDay: 2, Rank: 75, MaxEntries: 72, Price: 9},
+	}
+	days, total := NewDays(entries)
+	return RegisterConfirm{
+		FirstName: "Jean",
+		LastName:  "PIERRE",
+		Update:    true,
+		Days:      days,
+		Total:     total,
+		Badge:     true,
+		Added:     entries[2:],
+		Removed:   []Entry{{BandName: "B", BandDay: 1}},
+	}
+}
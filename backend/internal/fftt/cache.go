@@ -0,0 +1,106 @@
+package fftt
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type cacheEntry struct {
+	permitID  string
+	player    Player
+	expiresAt time.Time
+}
+
+// CachedClient keeps the players looked up by permit in a LRU cache whose entries expire after a TTL.
+// Searches are not cached, their results are not reused as often.
+type CachedClient struct {
+	Client
+	ttl  time.Duration
+	size int
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+}
+
+func NewCachedClient(client Client, ttl time.Duration, size int) *CachedClient {
+	return &CachedClient{
+		Client:  client,
+		ttl:     ttl,
+		size:    size,
+		now:     time.Now,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+func (c *CachedClient) GetPlayer(ctx context.Context, permitID string) (*Player, error) {
+	if player, ok := c.get(permitID); ok {
+		return player, nil
+	}
+
+	player, err := c.Client.GetPlayer(ctx, permitID)
+	if err != nil {
+		return nil, err
+	}
+	c.add(permitID, *player)
+	return player, nil
+}
+
+func (c *CachedClient) get(permitID string) (*Player, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[permitID]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*cacheEntry)
+	if c.now().After(entry.expiresAt) {
+		c.order.Remove(element)
+		delete(c.entries, permitID)
+		return nil, false
+	}
+
+	c.order.MoveToFront(element)
+	player := entry.player
+	return &player, true
+}
+
+func (c *CachedClient) add(permitID string, player Player) {
+	if c.size <= 0 || c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[permitID]; ok {
+		c.order.Remove(element)
+	}
+	c.entries[permitID] = c.order.PushFront(&cacheEntry{
+		permitID:  permitID,
+		player:    player,
+		expiresAt: c.now().Add(c.ttl),
+	})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).permitID)
+	}
+}
+
+// Invalidate drops the cached player, so that the next lookup reaches the FFTT
+func (c *CachedClient) Invalidate(permitID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[permitID]; ok {
+		c.order.Remove(element)
+		delete(c.entries, permitID)
+	}
+}
@@ -0,0 +1,324 @@
+package fftt
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Provider_DAFUNKER  string = "dafunker"
+	Provider_SMARTPING        = "smartping"
+)
+
+const (
+	DefaultBaseURL        = "https://fftt.dafunker.com/v1"
+	SmartpingBaseURL      = "https://www.fftt.com/mobile/pxml"
+	defaultTimeout        = 10 * time.Second
+	defaultMaxRetries     = 2
+	defaultRetryBaseDelay = 200 * time.Millisecond
+	defaultCacheTTL       = time.Hour
+	defaultCacheSize      = 1000
+)
+
+var (
+	// ErrNotFound is returned when the FFTT does not know the player
+	ErrNotFound = errors.New("player not found")
+	// ErrUpstreamDown is returned when the FFTT API can not be reached or fails, even after retries
+	ErrUpstreamDown = errors.New("FFTT API is unavailable")
+)
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type Player struct {
+	LastName   string  `json:"nom"`
+	FirstName  string  `json:"prenom"`
+	PermitID   string  `json:"licence"`
+	Sex        string  `json:"sexe"`
+	Points     float64 `json:"point"`
+	Category   string  `json:"cat,omitempty"`
+	ClubName   string  `json:"nomclub"`
+	ClubNumber string  `json:"numclub,omitempty"`
+	PermitType string  `json:"type,omitempty"`
+	// Validation is the date the licence was validated for the season, formatted as 02/01/2006, empty if it was not
+	Validation string `json:"validation,omitempty"`
+	// MedicalCertificate is the status of the medical certificate, empty when the provider does not report it
+	MedicalCertificate string `json:"certif,omitempty"`
+}
+
+// Client looks up licensed players in the FFTT database
+type Client interface {
+	GetPlayer(ctx context.Context, permitID string) (*Player, error)
+	// SearchPlayers returns the players matching the given last and first names
+	SearchPlayers(ctx context.Context, lastName string, firstName string) ([]Player, error)
+	ListClubPlayers(ctx context.Context, clubNumber string) ([]Player, error)
+}
+
+type Config struct {
+	Provider           string
+	BaseURL            string
+	InsecureSkipVerify bool
+	// Timeout bounds each HTTP call, every retry has its own
+	Timeout        time.Duration
+	MaxRetries     int
+	RetryBaseDelay time.Duration
+	CacheTTL       time.Duration
+	CacheSize      int
+
+	// The credentials delivered by the FFTT for the Smartping API
+	SmartpingAppID    string
+	SmartpingPassword string
+	SmartpingSerial   string
+}
+
+// ConfigFromEnv reads the FFTT_* environment variables, falling back to the defaults
+func ConfigFromEnv() (Config, error) {
+	config := Config{
+		Provider:          Provider_DAFUNKER,
+		BaseURL:           DefaultBaseURL,
+		Timeout:           defaultTimeout,
+		MaxRetries:        defaultMaxRetries,
+		RetryBaseDelay:    defaultRetryBaseDelay,
+		CacheTTL:          defaultCacheTTL,
+		CacheSize:         defaultCacheSize,
+		SmartpingAppID:    os.Getenv("FFTT_SMARTPING_APP_ID"),
+		SmartpingPassword: os.Getenv("FFTT_SMARTPING_PASSWORD"),
+		SmartpingSerial:   os.Getenv("FFTT_SMARTPING_SERIAL"),
+	}
+
+	var err error
+	switch provider := os.Getenv("FFTT_PROVIDER"); provider {
+	case "", Provider_DAFUNKER:
+	case Provider_SMARTPING:
+		config.Provider = Provider_SMARTPING
+		config.BaseURL = SmartpingBaseURL
+	default:
+		return config, fmt.Errorf("unknown FFTT provider %s", provider)
+	}
+	if baseURL := os.Getenv("FFTT_BASE_URL"); baseURL != "" {
+		config.BaseURL = strings.TrimSuffix(baseURL, "/")
+	}
+	if value := os.Getenv("FFTT_INSECURE_SKIP_VERIFY"); value != "" {
+		if config.InsecureSkipVerify, err = strconv.ParseBool(value); err != nil {
+			return config, fmt.Errorf("invalid FFTT_INSECURE_SKIP_VERIFY: %w", err)
+		}
+	}
+	if value := os.Getenv("FFTT_TIMEOUT"); value != "" {
+		if config.Timeout, err = time.ParseDuration(value); err != nil {
+			return config, fmt.Errorf("invalid FFTT_TIMEOUT: %w", err)
+		}
+	}
+	if value := os.Getenv("FFTT_MAX_RETRIES"); value != "" {
+		if config.MaxRetries, err = strconv.Atoi(value); err != nil {
+			return config, fmt.Errorf("invalid FFTT_MAX_RETRIES: %w", err)
+		}
+	}
+	if value := os.Getenv("FFTT_CACHE_TTL"); value != "" {
+		if config.CacheTTL, err = time.ParseDuration(value); err != nil {
+			return config, fmt.Errorf("invalid FFTT_CACHE_TTL: %w", err)
+		}
+	}
+	if value := os.Getenv("FFTT_CACHE_SIZE"); value != "" {
+		if config.CacheSize, err = strconv.Atoi(value); err != nil {
+			return config, fmt.Errorf("invalid FFTT_CACHE_SIZE: %w", err)
+		}
+	}
+
+	return config, nil
+}
+
+// NewFromEnv builds the cached client of the FFTT provider selected by FFTT_PROVIDER, the dafunker proxy being the default
+func NewFromEnv() (Client, error) {
+	config, err := ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := &http.Client{
+		Timeout: config.Timeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify},
+		},
+	}
+
+	var client Client
+	switch config.Provider {
+	case Provider_SMARTPING:
+		if client, err = NewSmartpingClient(httpClient, config); err != nil {
+			return nil, err
+		}
+	default:
+		client = NewClient(httpClient, config)
+	}
+	return NewCachedClient(client, config.CacheTTL, config.CacheSize), nil
+}
+
+// requester performs the GET calls of the providers, retrying them while the API is unavailable
+type requester struct {
+	httpClient     HTTPClient
+	maxRetries     int
+	retryBaseDelay time.Duration
+}
+
+func newRequester(httpClient HTTPClient, config Config) requester {
+	return requester{
+		httpClient:     httpClient,
+		maxRetries:     config.MaxRetries,
+		retryBaseDelay: config.RetryBaseDelay,
+	}
+}
+
+// get performs the request, retrying with exponential backoff while the FFTT API is unavailable
+func (c requester) get(ctx context.Context, url string, query map[string]string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	if len(query) > 0 {
+		q := req.URL.Query()
+		for key, value := range query {
+			q.Add(key, value)
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+
+	delay := c.retryBaseDelay
+	for attempt := 0; ; attempt++ {
+		body, err := c.do(req)
+		if err == nil || !errors.Is(err, ErrUpstreamDown) || attempt >= c.maxRetries {
+			return body, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", ErrUpstreamDown, ctx.Err())
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+func (c requester) do(req *http.Request) ([]byte, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUpstreamDown, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError:
+		return nil, fmt.Errorf("%w: status %d", ErrUpstreamDown, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read response: %w", ErrUpstreamDown, err)
+	}
+	return body, nil
+}
+
+// httpFFTTClient calls the JSON and XML endpoints of the FFTT proxy
+type httpFFTTClient struct {
+	requester
+	baseURL string
+}
+
+func NewClient(httpClient HTTPClient, config Config) Client {
+	return &httpFFTTClient{
+		requester: newRequester(httpClient, config),
+		baseURL:   config.BaseURL,
+	}
+}
+
+func (c *httpFFTTClient) GetPlayer(ctx context.Context, permitID string) (*Player, error) {
+	body, err := c.get(ctx, fmt.Sprintf("%s/joueur/%s", c.baseURL, permitID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch player %s from FFTT: %w", permitID, err)
+	}
+
+	var player Player
+	if err = json.Unmarshal(body, &player); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response for player %s from FFTT: %w", permitID, err)
+	}
+	// Unknown permits are answered with an empty player
+	if player.LastName == "" && player.FirstName == "" {
+		return nil, fmt.Errorf("player %s: %w", permitID, ErrNotFound)
+	}
+
+	return &player, nil
+}
+
+type playerXML struct {
+	LastName  string  `xml:"nom"`
+	FirstName string  `xml:"prenom"`
+	ClubName  string  `xml:"nclub"`
+	Points    float64 `xml:"points"`
+	PermitID  string  `xml:"licence"`
+	Sex       string  `xml:"sexe"`
+}
+
+type playersXML struct {
+	Players []playerXML `xml:"joueur"`
+}
+
+func (c *httpFFTTClient) searchPlayers(ctx context.Context, query map[string]string) ([]Player, error) {
+	body, err := c.get(ctx, c.baseURL+"//proxy/xml_liste_joueur_o.php", query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search FFTT players: %w", err)
+	}
+
+	var data playersXML
+	xmlString := strings.Replace(string(body), "encoding=\"ISO-8859-1\"", "encoding=\"UTF-8\"", 1)
+	if err = xml.Unmarshal([]byte(xmlString), &data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal search response from FFTT: %w", err)
+	}
+
+	players := []Player{}
+	for _, player := range data.Players {
+		players = append(players, Player{
+			LastName:  strings.TrimSpace(player.LastName),
+			FirstName: strings.TrimSpace(player.FirstName),
+			PermitID:  player.PermitID,
+			Points:    player.Points,
+			ClubName:  player.ClubName,
+			Sex:       player.Sex,
+		})
+	}
+	return players, nil
+}
+
+func (c *httpFFTTClient) SearchPlayers(ctx context.Context, lastName string, firstName string) ([]Player, error) {
+	return c.searchPlayers(ctx, map[string]string{
+		"nom":    lastName,
+		"prenom": firstName,
+	})
+}
+
+func (c *httpFFTTClient) ListClubPlayers(ctx context.Context, clubNumber string) ([]Player, error) {
+	players, err := c.searchPlayers(ctx, map[string]string{
+		"club": clubNumber,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range players {
+		players[i].ClubNumber = clubNumber
+	}
+	return players, nil
+}
@@ -0,0 +1,187 @@
+package fftt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) Client {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return NewClient(server.Client(), Config{
+		BaseURL:        server.URL,
+		MaxRetries:     2,
+		RetryBaseDelay: time.Millisecond,
+	})
+}
+
+func TestGetPlayer(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "/joueur/123456", r.URL.Path)
+			fmt.Fprint(w, `{"nom":"Pierre","prenom":"Jean","licence":"123456","sexe":"M","point":801,"nomclub":"Caillouville"}`)
+		})
+
+		player, err := client.GetPlayer(context.Background(), "123456")
+		require.NoError(t, err)
+		require.Equal(t, "Pierre", player.LastName)
+		require.Equal(t, 801.0, player.Points)
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"nom":"","prenom":"","licence":"invalid"}`)
+		})
+
+		_, err := client.GetPlayer(context.Background(), "invalid")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+	t.Run("RetriedUntilSuccess", func(t *testing.T) {
+		var calls int32
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			fmt.Fprint(w, `{"nom":"Pierre","prenom":"Jean","licence":"123456"}`)
+		})
+
+		_, err := client.GetPlayer(context.Background(), "123456")
+		require.NoError(t, err)
+		require.EqualValues(t, 3, calls)
+	})
+	t.Run("UpstreamDown", func(t *testing.T) {
+		var calls int32
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+
+		_, err := client.GetPlayer(context.Background(), "123456")
+		require.ErrorIs(t, err, ErrUpstreamDown)
+		require.EqualValues(t, 3, calls)
+	})
+	t.Run("BadRequestIsNotRetried", func(t *testing.T) {
+		var calls int32
+		client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusBadRequest)
+		})
+
+		_, err := client.GetPlayer(context.Background(), "123456")
+		require.Error(t, err)
+		require.False(t, errors.Is(err, ErrUpstreamDown))
+		require.EqualValues(t, 1, calls)
+	})
+	t.Run("Timeout", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(100 * time.Millisecond)
+		}))
+		defer server.Close()
+		httpClient := server.Client()
+		httpClient.Timeout = 10 * time.Millisecond
+		client := NewClient(httpClient, Config{BaseURL: server.URL})
+
+		_, err := client.GetPlayer(context.Background(), "123456")
+		require.ErrorIs(t, err, ErrUpstreamDown)
+	})
+}
+
+func TestListClubPlayers(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "08770047", r.URL.Query().Get("club"))
+		fmt.Fprint(w, `<?xml version="1.0" encoding="ISO-8859-1"?><liste>`+
+			`<joueur><licence>123456</licence><nom>PIERRE </nom><prenom>Jean</prenom><nclub>LOGNES EP</nclub><sexe>M</sexe><points>801</points></joueur>`+
+			`</liste>`)
+	})
+
+	players, err := client.ListClubPlayers(context.Background(), "08770047")
+	require.NoError(t, err)
+	require.Equal(t, []Player{{
+		LastName:   "PIERRE",
+		FirstName:  "Jean",
+		PermitID:   "123456",
+		Sex:        "M",
+		Points:     801,
+		ClubName:   "LOGNES EP",
+		ClubNumber: "08770047",
+	}}, players)
+}
+
+type countingClient struct {
+	Client
+	calls int
+}
+
+func (c *countingClient) GetPlayer(ctx context.Context, permitID string) (*Player, error) {
+	c.calls++
+	if permitID == "unknown" {
+		return nil, ErrNotFound
+	}
+	return &Player{PermitID: permitID, LastName: "Pierre"}, nil
+}
+
+func TestCachedClient(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	counting := &countingClient{}
+	client := NewCachedClient(counting, time.Hour, 2)
+	client.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		player, err := client.GetPlayer(ctx, "1")
+		require.NoError(t, err)
+		require.Equal(t, "1", player.PermitID)
+	}
+	require.Equal(t, 1, counting.calls)
+
+	// Errors are not cached
+	for i := 0; i < 2; i++ {
+		_, err := client.GetPlayer(ctx, "unknown")
+		require.ErrorIs(t, err, ErrNotFound)
+	}
+	require.Equal(t, 3, counting.calls)
+
+	// The least recently used player is evicted
+	_, _ = client.GetPlayer(ctx, "2")
+	_, _ = client.GetPlayer(ctx, "1")
+	_, _ = client.GetPlayer(ctx, "3")
+	require.Equal(t, 5, counting.calls)
+	_, _ = client.GetPlayer(ctx, "1")
+	require.Equal(t, 5, counting.calls)
+	_, _ = client.GetPlayer(ctx, "2")
+	require.Equal(t, 6, counting.calls)
+
+	// Entries expire after the TTL
+	now = now.Add(2 * time.Hour)
+	_, _ = client.GetPlayer(ctx, "2")
+	require.Equal(t, 7, counting.calls)
+
+	client.Invalidate("2")
+	_, _ = client.GetPlayer(ctx, "2")
+	require.Equal(t, 8, counting.calls)
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("FFTT_PROVIDER", Provider_SMARTPING)
+	config, err := ConfigFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, SmartpingBaseURL, config.BaseURL)
+
+	t.Setenv("FFTT_BASE_URL", "http://localhost:8081/")
+	config, err = ConfigFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost:8081", config.BaseURL)
+
+	t.Setenv("FFTT_PROVIDER", "minitel")
+	_, err = ConfigFromEnv()
+	require.EqualError(t, err, "unknown FFTT provider minitel")
+}
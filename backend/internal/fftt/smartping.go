@@ -0,0 +1,252 @@
+package fftt
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+var smartpingSerialRegexp = regexp.MustCompile(`^[A-Z0-9]{15}$`)
+
+// SmartpingClient calls the official Smartping XML API of the FFTT.
+// Every call is signed with the application password, and the serial is initialized once before the first call.
+type SmartpingClient struct {
+	requester
+	baseURL  string
+	appID    string
+	password string
+	serial   string
+	now      func() time.Time
+
+	mu          sync.Mutex
+	initialized bool
+}
+
+func NewSmartpingClient(httpClient HTTPClient, config Config) (*SmartpingClient, error) {
+	if config.SmartpingAppID == "" || config.SmartpingPassword == "" {
+		return nil, fmt.Errorf("FFTT_SMARTPING_APP_ID and FFTT_SMARTPING_PASSWORD environment variables are required by the Smartping provider")
+	}
+	if !smartpingSerialRegexp.MatchString(config.SmartpingSerial) {
+		return nil, fmt.Errorf("FFTT_SMARTPING_SERIAL must be made of 15 uppercase letters or digits")
+	}
+
+	return &SmartpingClient{
+		requester: newRequester(httpClient, config),
+		baseURL:   config.BaseURL,
+		appID:     config.SmartpingAppID,
+		password:  config.SmartpingPassword,
+		serial:    config.SmartpingSerial,
+		now:       time.Now,
+	}, nil
+}
+
+// smartpingSignature returns the tmc parameter: the HMAC-SHA1 of the timestamp keyed by the MD5 of the password
+func smartpingSignature(password string, tm string) string {
+	key := md5.Sum([]byte(password))
+	mac := hmac.New(sha1.New, []byte(hex.EncodeToString(key[:])))
+	mac.Write([]byte(tm))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (c *SmartpingClient) signedGet(ctx context.Context, endpoint string, query map[string]string) ([]byte, error) {
+	now := c.now()
+	tm := fmt.Sprintf("%s%03d", now.Format("20060102150405"), now.Nanosecond()/int(time.Millisecond))
+
+	params := map[string]string{
+		"serie": c.serial,
+		"id":    c.appID,
+		"tm":    tm,
+		"tmc":   smartpingSignature(c.password, tm),
+	}
+	for key, value := range query {
+		params[key] = value
+	}
+
+	return c.get(ctx, fmt.Sprintf("%s/%s", c.baseURL, endpoint), params)
+}
+
+type smartpingInitialisation struct {
+	Appli string `xml:"appli"`
+}
+
+// initialize registers the serial of the application, the API rejects the calls of unknown serials
+func (c *SmartpingClient) initialize(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.initialized {
+		return nil
+	}
+
+	body, err := c.signedGet(ctx, "xml_initialisation.php", nil)
+	if err != nil {
+		return fmt.Errorf("failed to initialize Smartping serial: %w", err)
+	}
+	var data smartpingInitialisation
+	if err = decodeXML(body, &data); err != nil {
+		return fmt.Errorf("failed to unmarshal Smartping initialization: %w", err)
+	}
+	if data.Appli != "1" {
+		return fmt.Errorf("the Smartping application %s is not authorized", c.appID)
+	}
+
+	c.initialized = true
+	return nil
+}
+
+func (c *SmartpingClient) call(ctx context.Context, endpoint string, query map[string]string, data interface{}) error {
+	if err := c.initialize(ctx); err != nil {
+		return err
+	}
+
+	body, err := c.signedGet(ctx, endpoint, query)
+	if err != nil {
+		return err
+	}
+	if err = decodeXML(body, data); err != nil {
+		return fmt.Errorf("failed to unmarshal %s response: %w", endpoint, err)
+	}
+	return nil
+}
+
+type smartpingLicence struct {
+	LastName   string `xml:"nom"`
+	FirstName  string `xml:"prenom"`
+	PermitID   string `xml:"licence"`
+	ClubNumber string `xml:"numclub"`
+	ClubName   string `xml:"nomclub"`
+	Sex        string `xml:"sexe"`
+	Type       string `xml:"type"`
+	Points     string `xml:"point"`
+	Category   string `xml:"cat"`
+	Validation string `xml:"validation"`
+	Certif     string `xml:"certif"`
+}
+
+type smartpingLicences struct {
+	Licences []smartpingLicence `xml:"licence"`
+}
+
+type smartpingJoueur struct {
+	LastName   string `xml:"nom"`
+	FirstName  string `xml:"prenom"`
+	PermitID   string `xml:"licence"`
+	ClubName   string `xml:"club"`
+	ClubNumber string `xml:"nclub"`
+	Sex        string `xml:"sexe"`
+	// Points is the field of xml_liste_joueur_o, xml_joueur names it point
+	Points string `xml:"points"`
+	Point  string `xml:"point"`
+}
+
+type smartpingJoueurs struct {
+	Joueurs []smartpingJoueur `xml:"joueur"`
+}
+
+// GetPlayer reads the licence of the player, with its official points.
+// New players have no official points yet, the points of their ranking are read from xml_joueur instead.
+func (c *SmartpingClient) GetPlayer(ctx context.Context, permitID string) (*Player, error) {
+	var licences smartpingLicences
+	if err := c.call(ctx, "xml_licence.php", map[string]string{"licence": permitID}, &licences); err != nil {
+		return nil, fmt.Errorf("failed to fetch player %s from Smartping: %w", permitID, err)
+	}
+	if len(licences.Licences) == 0 {
+		return nil, fmt.Errorf("player %s: %w", permitID, ErrNotFound)
+	}
+
+	licence := licences.Licences[0]
+	player := &Player{
+		LastName:           strings.TrimSpace(licence.LastName),
+		FirstName:          strings.TrimSpace(licence.FirstName),
+		PermitID:           licence.PermitID,
+		Sex:                licence.Sex,
+		Points:             parsePoints(licence.Points),
+		Category:           licence.Category,
+		ClubName:           strings.TrimSpace(licence.ClubName),
+		ClubNumber:         licence.ClubNumber,
+		PermitType:         licence.Type,
+		Validation:         strings.TrimSpace(licence.Validation),
+		MedicalCertificate: licence.Certif,
+	}
+
+	if strings.TrimSpace(licence.Points) == "" {
+		var joueurs smartpingJoueurs
+		if err := c.call(ctx, "xml_joueur.php", map[string]string{"licence": permitID}, &joueurs); err != nil {
+			return nil, fmt.Errorf("failed to fetch ranking of player %s from Smartping: %w", permitID, err)
+		}
+		if len(joueurs.Joueurs) > 0 {
+			player.Points = parsePoints(joueurs.Joueurs[0].Point)
+		}
+	}
+
+	return player, nil
+}
+
+// searchPlayers calls the _o variant of xml_liste_joueur, which also returns the sex and points of the players
+func (c *SmartpingClient) searchPlayers(ctx context.Context, query map[string]string) ([]Player, error) {
+	var joueurs smartpingJoueurs
+	if err := c.call(ctx, "xml_liste_joueur_o.php", query, &joueurs); err != nil {
+		return nil, fmt.Errorf("failed to search Smartping players: %w", err)
+	}
+
+	players := []Player{}
+	for _, joueur := range joueurs.Joueurs {
+		players = append(players, Player{
+			LastName:   strings.TrimSpace(joueur.LastName),
+			FirstName:  strings.TrimSpace(joueur.FirstName),
+			PermitID:   joueur.PermitID,
+			Sex:        joueur.Sex,
+			Points:     parsePoints(joueur.Points),
+			ClubName:   strings.TrimSpace(joueur.ClubName),
+			ClubNumber: joueur.ClubNumber,
+		})
+	}
+	return players, nil
+}
+
+func (c *SmartpingClient) SearchPlayers(ctx context.Context, lastName string, firstName string) ([]Player, error) {
+	return c.searchPlayers(ctx, map[string]string{
+		"nom":    lastName,
+		"prenom": firstName,
+	})
+}
+
+func (c *SmartpingClient) ListClubPlayers(ctx context.Context, clubNumber string) ([]Player, error) {
+	return c.searchPlayers(ctx, map[string]string{
+		"club": clubNumber,
+	})
+}
+
+// parsePoints parses the points of the API, which are empty for players without ranking
+func parsePoints(points string) float64 {
+	value, err := strconv.ParseFloat(strings.Replace(strings.TrimSpace(points), ",", ".", 1), 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// decodeXML decodes the responses of the Smartping API, which are encoded in ISO-8859-1
+func decodeXML(body []byte, v interface{}) error {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		if strings.EqualFold(charset, "ISO-8859-1") {
+			return charmap.ISO8859_1.NewDecoder().Reader(input), nil
+		}
+		return nil, fmt.Errorf("unsupported charset %s", charset)
+	}
+	return decoder.Decode(v)
+}
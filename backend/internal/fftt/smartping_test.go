@@ -0,0 +1,135 @@
+package fftt
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSmartpingAppID    = "SX000"
+	testSmartpingPassword = "s3cr3t"
+	testSmartpingSerial   = "ABCDEFGHIJ12345"
+)
+
+// newTestSmartpingClient serves the recorded responses of testdata/smartping, checking the signature of every call
+func newTestSmartpingClient(t *testing.T) (*SmartpingClient, *[]string) {
+	var calls []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		require.Equal(t, "20240501120000042", query.Get("tm"))
+		require.Equal(t, testSmartpingAppID, query.Get("id"))
+		require.Equal(t, testSmartpingSerial, query.Get("serie"))
+		require.Equal(t, smartpingSignature(testSmartpingPassword, query.Get("tm")), query.Get("tmc"))
+
+		endpoint := strings.TrimSuffix(filepath.Base(r.URL.Path), ".php")
+		calls = append(calls, endpoint)
+		fixture := endpoint
+		if licence := query.Get("licence"); licence != "" {
+			fixture += "_" + licence
+		}
+
+		content, err := os.ReadFile(filepath.Join("testdata", "smartping", fixture+".xml"))
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "text/xml; charset=ISO-8859-1")
+		_, _ = w.Write(content)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewSmartpingClient(server.Client(), Config{
+		BaseURL:           server.URL,
+		SmartpingAppID:    testSmartpingAppID,
+		SmartpingPassword: testSmartpingPassword,
+		SmartpingSerial:   testSmartpingSerial,
+	})
+	require.NoError(t, err)
+	client.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 42*int(time.Millisecond), time.UTC) }
+	return client, &calls
+}
+
+func TestSmartpingSignature(t *testing.T) {
+	// HMAC-SHA1 of the timestamp keyed by md5("s3cr3t") = "a4d80eac9ab26a4a2da04125bc2c096a"
+	require.Equal(t, "71b825763fff5f519be515e00a6bab38dc539c54", smartpingSignature("s3cr3t", "20240501120000000"))
+}
+
+func TestSmartpingGetPlayer(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		client, calls := newTestSmartpingClient(t)
+
+		player, err := client.GetPlayer(context.Background(), "123456")
+		require.NoError(t, err)
+		require.Equal(t, &Player{
+			LastName:           "DUPRÉ",
+			FirstName:          "Hervé",
+			PermitID:           "123456",
+			Sex:                "M",
+			Points:             1234,
+			Category:           "V1",
+			ClubName:           "LOGNES EP",
+			ClubNumber:         "08770047",
+			PermitType:         "T",
+			Validation:         "03/09/2023",
+			MedicalCertificate: "A",
+		}, player)
+		require.Equal(t, []string{"xml_initialisation", "xml_licence"}, *calls)
+
+		// The serial is only initialized once
+		_, err = client.GetPlayer(context.Background(), "123456")
+		require.NoError(t, err)
+		require.Equal(t, []string{"xml_initialisation", "xml_licence", "xml_licence"}, *calls)
+	})
+	t.Run("NoOfficialPoints", func(t *testing.T) {
+		client, calls := newTestSmartpingClient(t)
+
+		player, err := client.GetPlayer(context.Background(), "777777")
+		require.NoError(t, err)
+		require.Equal(t, "Léa", player.FirstName)
+		require.Equal(t, "F", player.Sex)
+		require.Equal(t, "P", player.PermitType)
+		require.Equal(t, 547.0, player.Points)
+		require.Equal(t, []string{"xml_initialisation", "xml_licence", "xml_joueur"}, *calls)
+	})
+	t.Run("NotFound", func(t *testing.T) {
+		client, _ := newTestSmartpingClient(t)
+
+		_, err := client.GetPlayer(context.Background(), "000000")
+		require.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestSmartpingListClubPlayers(t *testing.T) {
+	client, _ := newTestSmartpingClient(t)
+
+	players, err := client.ListClubPlayers(context.Background(), "08770047")
+	require.NoError(t, err)
+	require.Len(t, players, 2)
+	require.Equal(t, Player{
+		LastName:   "DUPRÉ",
+		FirstName:  "Hervé",
+		PermitID:   "123456",
+		Sex:        "M",
+		Points:     1234,
+		ClubName:   "LOGNES EP",
+		ClubNumber: "08770047",
+	}, players[0])
+	require.Equal(t, 547.0, players[1].Points)
+}
+
+func TestNewSmartpingClient(t *testing.T) {
+	_, err := NewSmartpingClient(http.DefaultClient, Config{SmartpingAppID: "SX000", SmartpingPassword: "secret", SmartpingSerial: "abc"})
+	require.ErrorContains(t, err, "FFTT_SMARTPING_SERIAL")
+
+	_, err = NewSmartpingClient(http.DefaultClient, Config{SmartpingSerial: testSmartpingSerial})
+	require.ErrorContains(t, err, "FFTT_SMARTPING_APP_ID")
+
+}
@@ -0,0 +1,100 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"time"
+)
+
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405Z"
+	// Lines longer than 75 octets must be folded, see RFC 5545 section 3.1
+	maxLineLength = 75
+)
+
+// Event is a VEVENT, it lasts whole days when AllDay is set
+type Event struct {
+	// UID must be stable for clients to update the event instead of duplicating it
+	UID         string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	Summary     string
+	Description string
+	Location    string
+}
+
+// Calendar is a minimal VCALENDAR writer
+type Calendar struct {
+	ProdID string
+	Name   string
+	// Method is PUBLISH for attachments, empty for feeds
+	Method string
+	Events []Event
+}
+
+func (c *Calendar) Bytes(now time.Time) []byte {
+	var buf bytes.Buffer
+	writeLine(&buf, "BEGIN:VCALENDAR")
+	writeLine(&buf, "VERSION:2.0")
+	writeLine(&buf, "PRODID:"+c.ProdID)
+	writeLine(&buf, "CALSCALE:GREGORIAN")
+	if c.Method != "" {
+		writeLine(&buf, "METHOD:"+c.Method)
+	}
+	if c.Name != "" {
+		writeLine(&buf, "X-WR-CALNAME:"+escapeText(c.Name))
+	}
+
+	for _, event := range c.Events {
+		writeLine(&buf, "BEGIN:VEVENT")
+		writeLine(&buf, "UID:"+event.UID)
+		writeLine(&buf, "DTSTAMP:"+now.UTC().Format(dateTimeFormat))
+		if event.AllDay {
+			writeLine(&buf, "DTSTART;VALUE=DATE:"+event.Start.Format(dateFormat))
+			writeLine(&buf, "DTEND;VALUE=DATE:"+event.End.Format(dateFormat))
+		} else {
+			writeLine(&buf, "DTSTART:"+event.Start.UTC().Format(dateTimeFormat))
+			writeLine(&buf, "DTEND:"+event.End.UTC().Format(dateTimeFormat))
+		}
+		writeLine(&buf, "SUMMARY:"+escapeText(event.Summary))
+		if event.Description != "" {
+			writeLine(&buf, "DESCRIPTION:"+escapeText(event.Description))
+		}
+		if event.Location != "" {
+			writeLine(&buf, "LOCATION:"+escapeText(event.Location))
+		}
+		writeLine(&buf, "END:VEVENT")
+	}
+
+	writeLine(&buf, "END:VCALENDAR")
+	return buf.Bytes()
+}
+
+// escapeText escapes a TEXT value, see RFC 5545 section 3.3.11
+func escapeText(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(text)
+}
+
+// writeLine writes a content line folded at 75 octets without splitting UTF-8 characters
+func writeLine(buf *bytes.Buffer, line string) {
+	length := 0
+	for _, r := range line {
+		size := len(string(r))
+		if length+size > maxLineLength {
+			buf.WriteString("\r\n ")
+			// The leading space counts in the length of the continuation line
+			length = 1
+		}
+		buf.WriteRune(r)
+		length += size
+	}
+	buf.WriteString("\r\n")
+}
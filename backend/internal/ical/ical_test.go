@@ -0,0 +1,61 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendarBytes(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	calendar := Calendar{
+		ProdID: "-//Tournoi de Lognes//FR",
+		Method: "PUBLISH",
+		Events: []Event{
+			{
+				UID:         "member-day1@tournoi",
+				Start:       time.Date(2024, 6, 8, 9, 0, 0, 0, paris),
+				End:         time.Date(2024, 6, 8, 20, 0, 0, 0, paris),
+				Summary:     "Tournoi de Lognes: John Doe",
+				Description: "Tableau A, rang 3\nTableau C; liste d'attente n°2",
+			},
+			{
+				UID:     "member-day2@tournoi",
+				Start:   time.Date(2024, 6, 9, 0, 0, 0, 0, paris),
+				End:     time.Date(2024, 6, 10, 0, 0, 0, 0, paris),
+				AllDay:  true,
+				Summary: "Tournoi de Lognes",
+			},
+		},
+	}
+
+	content := string(calendar.Bytes(now))
+
+	require.True(t, strings.HasPrefix(content, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	require.True(t, strings.HasSuffix(content, "END:VCALENDAR\r\n"))
+	require.Contains(t, content, "METHOD:PUBLISH\r\n")
+	require.Contains(t, content, "DTSTAMP:20240501T120000Z\r\n")
+	require.Contains(t, content, "DTSTART:20240608T070000Z\r\n")
+	require.Contains(t, content, "DTEND:20240608T180000Z\r\n")
+	require.Contains(t, content, `DESCRIPTION:Tableau A\, rang 3\nTableau C\; liste d'attente n°2`+"\r\n")
+	require.Contains(t, content, "DTSTART;VALUE=DATE:20240609\r\n")
+	require.Contains(t, content, "DTEND;VALUE=DATE:20240610\r\n")
+	require.Equal(t, 2, strings.Count(content, "BEGIN:VEVENT"))
+}
+
+func TestWriteLineFolding(t *testing.T) {
+	calendar := Calendar{
+		ProdID: "-//Tournoi de Lognes//FR",
+		Events: []Event{{UID: "1", Summary: strings.Repeat("é", 60)}},
+	}
+
+	for _, line := range strings.Split(string(calendar.Bytes(time.Now())), "\r\n") {
+		require.LessOrEqual(t, len(line), 75)
+	}
+	require.Contains(t, string(calendar.Bytes(time.Now())), "\r\n é")
+}
@@ -0,0 +1,44 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes emails as .eml files in a maildir, which is convenient for local development
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir string, from string) (*FileMailer, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("MAILER_DIR environment variable is required by the file mailer")
+	}
+
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create maildir: %w", err)
+		}
+	}
+
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (f *FileMailer) Send(message *Message) error {
+	raw, err := message.Bytes(f.from)
+	if err != nil {
+		return fmt.Errorf("failed to build message: %w", err)
+	}
+
+	// Write in tmp then move to new so that readers never see partial files
+	name := fmt.Sprintf("%d.%s.eml", time.Now().UnixNano(), randomID())
+	tmpPath := filepath.Join(f.dir, "tmp", name)
+	if err = os.WriteFile(tmpPath, raw, 0o644); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+
+	return os.Rename(tmpPath, filepath.Join(f.dir, "new", name))
+}
@@ -0,0 +1,150 @@
+package mailer
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/getsentry/sentry-go"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/api/gmail/v1"
+	"google.golang.org/api/option"
+)
+
+// GmailMailer sends emails through the Gmail API of the account owning the OAuth token
+type GmailMailer struct {
+	service *gmail.Service
+	store   TokenStore
+}
+
+// NewGmailMailer builds the Gmail service once, with the token of store or tokenJSON.
+// tokenJSON replaces the stored token when its refresh token differs, it is only required on the first start.
+func NewGmailMailer(credentialsJSON string, tokenJSON string, store TokenStore) (*GmailMailer, error) {
+	if credentialsJSON == "" {
+		return nil, fmt.Errorf("CREDENTIALS_JSON environment variable is required by the Gmail mailer")
+	}
+
+	// Load client credentials
+	config, err := google.ConfigFromJSON([]byte(credentialsJSON), gmail.MailGoogleComScope)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse client secret file to config: %w", err)
+	}
+
+	token, err := loadGmailToken(tokenJSON, store)
+	if err != nil {
+		return nil, err
+	}
+
+	// The token source refreshes the access token whenever it expires, and the refreshed token is saved
+	ctx := context.Background()
+	source := &persistingTokenSource{
+		source:    config.TokenSource(ctx, token),
+		store:     store,
+		saved:     token.AccessToken,
+		onRevoked: alertRevokedToken,
+	}
+	service, err := gmail.NewService(ctx, option.WithTokenSource(source))
+	if err != nil {
+		return nil, fmt.Errorf("unable to create Gmail service: %w", err)
+	}
+
+	return &GmailMailer{service: service, store: store}, nil
+}
+
+func loadGmailToken(tokenJSON string, store TokenStore) (*oauth2.Token, error) {
+	stored, _, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenJSON == "" {
+		if stored == nil {
+			return nil, fmt.Errorf("TOKEN_JSON environment variable is required by the Gmail mailer until a token is stored")
+		}
+		return stored, nil
+	}
+
+	token := &oauth2.Token{}
+	if err = json.Unmarshal([]byte(tokenJSON), token); err != nil {
+		return nil, fmt.Errorf("unable to parse token: %w", err)
+	}
+
+	// The stored token is the most recently refreshed one, unless TOKEN_JSON was set to a new refresh token
+	if stored != nil && stored.RefreshToken == token.RefreshToken {
+		return stored, nil
+	}
+	if err = store.Save(token); err != nil {
+		return nil, fmt.Errorf("unable to save token: %w", err)
+	}
+	return token, nil
+}
+
+func (g *GmailMailer) Send(message *Message) error {
+	raw, err := message.Bytes("")
+	if err != nil {
+		return fmt.Errorf("failed to build message: %w", err)
+	}
+
+	_, err = g.service.Users.Messages.Send("me", &gmail.Message{
+		Raw: base64.URLEncoding.EncodeToString(raw),
+	}).Do()
+	return err
+}
+
+func (g *GmailMailer) TokenHealth() (*TokenHealth, error) {
+	return g.store.Health()
+}
+
+// persistingTokenSource saves the tokens refreshed by source, and reports when the refresh token gets revoked
+type persistingTokenSource struct {
+	mu        sync.Mutex
+	source    oauth2.TokenSource
+	store     TokenStore
+	saved     string
+	onRevoked func(err error)
+}
+
+func (s *persistingTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, err := s.source.Token()
+	if err != nil {
+		if isTokenRevoked(err) {
+			revoked, markErr := s.store.MarkRevoked(err)
+			if markErr != nil {
+				log.Printf("%s", markErr)
+			} else if revoked {
+				s.onRevoked(err)
+			}
+		}
+		return nil, err
+	}
+
+	if token.AccessToken != s.saved {
+		// A failed save is not fatal, the token is refreshed once more after a restart
+		if err = s.store.Save(token); err != nil {
+			log.Printf("failed to save refreshed token: %s", err)
+		} else {
+			s.saved = token.AccessToken
+		}
+	}
+
+	return token, nil
+}
+
+// isTokenRevoked reports whether the authorization server rejected the refresh token
+func isTokenRevoked(err error) bool {
+	var retrieveErr *oauth2.RetrieveError
+	return errors.As(err, &retrieveErr) && retrieveErr.ErrorCode == "invalid_grant"
+}
+
+func alertRevokedToken(err error) {
+	log.Printf("the Gmail refresh token was revoked: %s", err)
+	sentry.CaptureMessage(fmt.Sprintf("the Gmail refresh token was revoked, no email can be sent until TOKEN_JSON is renewed: %s", err))
+}
@@ -0,0 +1,187 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Mailer interface {
+	Send(message *Message) error
+}
+
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Message is an email with an HTML body, an optional plain text alternative and attachments
+type Message struct {
+	To          string
+	Subject     string
+	HTML        string
+	Text        string
+	Attachments []Attachment
+}
+
+const (
+	Backend_GMAIL  string = "gmail"
+	Backend_SMTP          = "smtp"
+	Backend_FILE          = "file"
+	Backend_MEMORY        = "memory"
+)
+
+// NewFromEnv builds the mailer selected by the MAILER_BACKEND environment variable, Gmail being the default.
+// db stores the OAuth token of the Gmail mailer. The memory mailer drops every email, it requires DEV_MODE=true.
+func NewFromEnv(db *gorm.DB) (Mailer, error) {
+	from := os.Getenv("MAIL_FROM")
+
+	switch backend := os.Getenv("MAILER_BACKEND"); backend {
+	case "", Backend_GMAIL:
+		key, err := ParseEncryptionKey(os.Getenv("TOKEN_ENCRYPTION_KEY"))
+		if err != nil {
+			return nil, err
+		}
+		store, err := NewDBTokenStore(db, Backend_GMAIL, key)
+		if err != nil {
+			return nil, err
+		}
+		return NewGmailMailer(os.Getenv("CREDENTIALS_JSON"), os.Getenv("TOKEN_JSON"), store)
+	case Backend_SMTP:
+		return NewSMTPMailer(os.Getenv("SMTP_HOST"), os.Getenv("SMTP_PORT"), os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), from)
+	case Backend_FILE:
+		return NewFileMailer(os.Getenv("MAILER_DIR"), from)
+	case Backend_MEMORY:
+		if os.Getenv("DEV_MODE") != "true" {
+			return nil, errors.New("the memory mailer requires DEV_MODE=true")
+		}
+		return NewMemoryMailer(), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer backend %s", backend)
+	}
+}
+
+// encodeHeader encodes special characters in the given header string using MIME encoding
+func encodeHeader(header string) string {
+	return mime.QEncoding.Encode("utf-8", header)
+}
+
+// Bytes renders the message in the RFC 5322 format expected by the mail backends
+func (m *Message) Bytes(from string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if from != "" {
+		fmt.Fprintf(&buf, "From: %s\r\n", from)
+	}
+	fmt.Fprintf(&buf, "To: %s\r\n", m.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", encodeHeader(m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@tournoi>\r\n", randomID())
+	buf.WriteString("MIME-Version: 1.0\r\n")
+
+	// Keep the historical single part layout when there is nothing but HTML
+	if m.Text == "" && len(m.Attachments) == 0 {
+		buf.WriteString("Content-Type: text/html; charset=\"utf-8\"\r\n\r\n")
+		buf.WriteString(m.HTML)
+		return buf.Bytes(), nil
+	}
+
+	mixed := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", mixed.Boundary())
+
+	var alternativeBuf bytes.Buffer
+	alternative := multipart.NewWriter(&alternativeBuf)
+	if m.Text != "" {
+		if err := writeQuotedPrintablePart(alternative, "text/plain; charset=\"utf-8\"", m.Text); err != nil {
+			return nil, err
+		}
+	}
+	if m.HTML != "" {
+		if err := writeQuotedPrintablePart(alternative, "text/html; charset=\"utf-8\"", m.HTML); err != nil {
+			return nil, err
+		}
+	}
+	if err := alternative.Close(); err != nil {
+		return nil, err
+	}
+
+	part, err := mixed.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {fmt.Sprintf("multipart/alternative; boundary=%q", alternative.Boundary())},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if _, err = part.Write(alternativeBuf.Bytes()); err != nil {
+		return nil, err
+	}
+
+	for _, attachment := range m.Attachments {
+		contentType := attachment.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		part, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {fmt.Sprintf("%s; name=%q", contentType, attachment.Filename)},
+			"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", attachment.Filename)},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err = part.Write([]byte(wrapBase64(attachment.Data))); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintablePart(writer *multipart.Writer, contentType string, content string) error {
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err = qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// wrapBase64 encodes data in base64 with lines of 76 characters as required by RFC 2045
+func wrapBase64(data []byte) string {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var lines []string
+	for len(encoded) > 76 {
+		lines = append(lines, encoded[:76])
+		encoded = encoded[76:]
+	}
+	lines = append(lines, encoded)
+	return strings.Join(lines, "\r\n")
+}
+
+func randomID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,139 @@
+package mailer
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageBytes(t *testing.T) {
+	t.Run("HTMLOnly", func(t *testing.T) {
+		message := Message{
+			To:      "jdoe@example.com",
+			Subject: "Confirmation inscription Hervé",
+			HTML:    "<p>Bonjour</p>",
+		}
+
+		raw, err := message.Bytes("tournoi@example.com")
+		require.NoError(t, err)
+
+		parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+		require.NoError(t, err)
+		require.Equal(t, "tournoi@example.com", parsed.Header.Get("From"))
+		require.Equal(t, "jdoe@example.com", parsed.Header.Get("To"))
+		subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+		require.NoError(t, err)
+		require.Equal(t, "Confirmation inscription Hervé", subject)
+		require.Equal(t, `text/html; charset="utf-8"`, parsed.Header.Get("Content-Type"))
+		body, err := io.ReadAll(parsed.Body)
+		require.NoError(t, err)
+		require.Equal(t, "<p>Bonjour</p>", string(body))
+	})
+	t.Run("Multipart", func(t *testing.T) {
+		message := Message{
+			To:      "jdoe@example.com",
+			Subject: "Confirmation",
+			HTML:    "<p>Bonjour</p>",
+			Text:    "Bonjour",
+			Attachments: []Attachment{
+				{Filename: "tournoi.ics", ContentType: "text/calendar", Data: []byte("BEGIN:VCALENDAR")},
+			},
+		}
+
+		raw, err := message.Bytes("")
+		require.NoError(t, err)
+
+		parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+		require.NoError(t, err)
+		mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		require.Equal(t, "multipart/mixed", mediaType)
+
+		reader := multipart.NewReader(parsed.Body, params["boundary"])
+
+		// Text and HTML alternatives
+		part, err := reader.NextPart()
+		require.NoError(t, err)
+		mediaType, alternativeParams, err := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		require.Equal(t, "multipart/alternative", mediaType)
+		alternatives := multipart.NewReader(part, alternativeParams["boundary"])
+		text, err := alternatives.NextPart()
+		require.NoError(t, err)
+		require.Equal(t, `text/plain; charset="utf-8"`, text.Header.Get("Content-Type"))
+		content, err := io.ReadAll(text)
+		require.NoError(t, err)
+		require.Equal(t, "Bonjour", string(content))
+		html, err := alternatives.NextPart()
+		require.NoError(t, err)
+		require.Equal(t, `text/html; charset="utf-8"`, html.Header.Get("Content-Type"))
+
+		// Attachment
+		part, err = reader.NextPart()
+		require.NoError(t, err)
+		require.Equal(t, "tournoi.ics", part.FileName())
+		_, err = reader.NextPart()
+		require.ErrorIs(t, err, io.EOF)
+	})
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	fileMailer, err := NewFileMailer(dir, "tournoi@example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, fileMailer.Send(&Message{To: "jdoe@example.com", Subject: "OTP 123456", HTML: "123456"}))
+
+	files, err := filepath.Glob(filepath.Join(dir, "new", "*.eml"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	raw, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	require.NoError(t, err)
+	require.Equal(t, "OTP 123456", parsed.Header.Get("Subject"))
+
+	tmpFiles, err := os.ReadDir(filepath.Join(dir, "tmp"))
+	require.NoError(t, err)
+	require.Empty(t, tmpFiles)
+}
+
+func TestNewFromEnv(t *testing.T) {
+	t.Run("Memory", func(t *testing.T) {
+		t.Setenv("MAILER_BACKEND", Backend_MEMORY)
+		t.Setenv("DEV_MODE", "true")
+		m, err := NewFromEnv(nil)
+		require.NoError(t, err)
+		require.IsType(t, &MemoryMailer{}, m)
+	})
+	t.Run("MemoryOutsideOfDevelopment", func(t *testing.T) {
+		t.Setenv("MAILER_BACKEND", Backend_MEMORY)
+		t.Setenv("DEV_MODE", "")
+		_, err := NewFromEnv(nil)
+		require.ErrorContains(t, err, "DEV_MODE")
+	})
+	t.Run("MissingSMTPConfiguration", func(t *testing.T) {
+		t.Setenv("MAILER_BACKEND", Backend_SMTP)
+		t.Setenv("SMTP_HOST", "")
+		_, err := NewFromEnv(nil)
+		require.Error(t, err)
+	})
+	t.Run("MissingTokenEncryptionKey", func(t *testing.T) {
+		t.Setenv("MAILER_BACKEND", Backend_GMAIL)
+		t.Setenv("TOKEN_ENCRYPTION_KEY", "")
+		_, err := NewFromEnv(nil)
+		require.ErrorContains(t, err, "TOKEN_ENCRYPTION_KEY")
+	})
+	t.Run("UnknownBackend", func(t *testing.T) {
+		t.Setenv("MAILER_BACKEND", "pigeon")
+		_, err := NewFromEnv(nil)
+		require.EqualError(t, err, "unknown mailer backend pigeon")
+	})
+}
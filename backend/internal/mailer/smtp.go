@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+)
+
+// SMTPMailer sends emails through a plain SMTP relay, using STARTTLS when the server supports it
+type SMTPMailer struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(host string, port string, username string, password string, from string) (*SMTPMailer, error) {
+	if host == "" || from == "" {
+		return nil, fmt.Errorf("SMTP_HOST and MAIL_FROM environment variables are required by the SMTP mailer")
+	}
+	if port == "" {
+		port = "587"
+	}
+
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPMailer{
+		addr: net.JoinHostPort(host, port),
+		auth: auth,
+		from: from,
+	}, nil
+}
+
+func (s *SMTPMailer) Send(message *Message) error {
+	raw, err := message.Bytes(s.from)
+	if err != nil {
+		return fmt.Errorf("failed to build message: %w", err)
+	}
+
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{message.To}, raw)
+}
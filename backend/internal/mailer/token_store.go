@@ -0,0 +1,185 @@
+package mailer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	TokenStatus_VALID string = "valid"
+	// TokenStatus_EXPIRED means the access token expired, it is refreshed by the next email
+	TokenStatus_EXPIRED = "expired"
+	// TokenStatus_REVOKED means the refresh token was rejected, a new TOKEN_JSON has to be configured
+	TokenStatus_REVOKED = "revoked"
+)
+
+// TokenHealth describes the state of the OAuth token of a mailer
+type TokenHealth struct {
+	Provider    string
+	Status      string
+	Expiry      time.Time
+	RefreshedAt *time.Time
+	RevokedAt   *time.Time
+	LastError   string
+}
+
+// TokenHealthReporter is implemented by the mailers authenticating with an OAuth token
+type TokenHealthReporter interface {
+	TokenHealth() (*TokenHealth, error)
+}
+
+// TokenStore persists the OAuth token of a mailer, so that refreshed tokens survive restarts
+type TokenStore interface {
+	// Load returns the stored token and whether it was revoked, a nil token if nothing is stored
+	Load() (token *oauth2.Token, revoked bool, err error)
+	// Save stores a token, clearing any previous revocation
+	Save(token *oauth2.Token) error
+	// MarkRevoked records that the refresh token was rejected, it reports whether it was not already known
+	MarkRevoked(cause error) (bool, error)
+	Health() (*TokenHealth, error)
+}
+
+// ParseEncryptionKey decodes the base64 encoded AES-256 key of TOKEN_ENCRYPTION_KEY
+func ParseEncryptionKey(encoded string) ([]byte, error) {
+	if encoded == "" {
+		return nil, fmt.Errorf("TOKEN_ENCRYPTION_KEY environment variable is required to store OAuth tokens")
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOKEN_ENCRYPTION_KEY: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid TOKEN_ENCRYPTION_KEY: expected 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// DBTokenStore stores the token of a provider in the oauth_tokens table, encrypted with AES-GCM
+type DBTokenStore struct {
+	db       *gorm.DB
+	provider string
+	aead     cipher.AEAD
+}
+
+func NewDBTokenStore(db *gorm.DB, provider string, key []byte) (*DBTokenStore, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return &DBTokenStore{db: db, provider: provider, aead: aead}, nil
+}
+
+func (s *DBTokenStore) encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	// The provider is authenticated too, a token can not be moved to another provider row
+	return s.aead.Seal(nonce, nonce, plaintext, []byte(s.provider)), nil
+}
+
+func (s *DBTokenStore) decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < s.aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:s.aead.NonceSize()], ciphertext[s.aead.NonceSize():]
+	return s.aead.Open(nil, nonce, sealed, []byte(s.provider))
+}
+
+func (s *DBTokenStore) Load() (*oauth2.Token, bool, error) {
+	var stored models.OAuthToken
+	err := s.db.Where("provider = ?", s.provider).First(&stored).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	plaintext, err := s.decrypt(stored.Ciphertext)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decrypt token, was TOKEN_ENCRYPTION_KEY changed?: %w", err)
+	}
+	token := &oauth2.Token{}
+	if err = json.Unmarshal(plaintext, token); err != nil {
+		return nil, false, fmt.Errorf("failed to parse token: %w", err)
+	}
+
+	return token, stored.RevokedAt != nil, nil
+}
+
+func (s *DBTokenStore) Save(token *oauth2.Token) error {
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("failed to marshal token: %w", err)
+	}
+	ciphertext, err := s.encrypt(plaintext)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "provider"}},
+		DoUpdates: clause.AssignmentColumns([]string{"ciphertext", "expiry", "refreshed_at", "revoked_at", "last_error", "updated_at"}),
+	}).Create(&models.OAuthToken{
+		Provider:    s.provider,
+		Ciphertext:  ciphertext,
+		Expiry:      token.Expiry,
+		RefreshedAt: &now,
+	}).Error
+}
+
+func (s *DBTokenStore) MarkRevoked(cause error) (bool, error) {
+	result := s.db.Model(&models.OAuthToken{}).
+		Where("provider = ? AND revoked_at IS NULL", s.provider).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "last_error": cause.Error()})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to mark token as revoked: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (s *DBTokenStore) Health() (*TokenHealth, error) {
+	var stored models.OAuthToken
+	err := s.db.Omit("ciphertext").Where("provider = ?", s.provider).First(&stored).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	return newTokenHealth(s.provider, stored.Expiry, stored.RefreshedAt, stored.RevokedAt, stored.LastError), nil
+}
+
+func newTokenHealth(provider string, expiry time.Time, refreshedAt *time.Time, revokedAt *time.Time, lastError string) *TokenHealth {
+	status := TokenStatus_VALID
+	if revokedAt != nil {
+		status = TokenStatus_REVOKED
+	} else if !expiry.IsZero() && expiry.Before(time.Now()) {
+		status = TokenStatus_EXPIRED
+	}
+
+	return &TokenHealth{
+		Provider:    provider,
+		Status:      status,
+		Expiry:      expiry,
+		RefreshedAt: refreshedAt,
+		RevokedAt:   revokedAt,
+		LastError:   lastError,
+	}
+}
@@ -0,0 +1,173 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+type memoryTokenStore struct {
+	token   *oauth2.Token
+	revoked bool
+	saves   int
+}
+
+func (s *memoryTokenStore) Load() (*oauth2.Token, bool, error) {
+	return s.token, s.revoked, nil
+}
+
+func (s *memoryTokenStore) Save(token *oauth2.Token) error {
+	s.token = token
+	s.revoked = false
+	s.saves++
+	return nil
+}
+
+func (s *memoryTokenStore) MarkRevoked(cause error) (bool, error) {
+	if s.revoked {
+		return false, nil
+	}
+	s.revoked = true
+	return true, nil
+}
+
+func (s *memoryTokenStore) Health() (*TokenHealth, error) {
+	return newTokenHealth("memory", s.token.Expiry, nil, nil, ""), nil
+}
+
+type tokenSourceFunc func() (*oauth2.Token, error)
+
+func (f tokenSourceFunc) Token() (*oauth2.Token, error) {
+	return f()
+}
+
+func TestParseEncryptionKey(t *testing.T) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+
+	parsed, err := ParseEncryptionKey(base64.StdEncoding.EncodeToString(key))
+	require.NoError(t, err)
+	require.Equal(t, key, parsed)
+
+	_, err = ParseEncryptionKey(base64.StdEncoding.EncodeToString(key[:16]))
+	require.EqualError(t, err, "invalid TOKEN_ENCRYPTION_KEY: expected 32 bytes, got 16")
+
+	_, err = ParseEncryptionKey("")
+	require.Error(t, err)
+}
+
+func TestDBTokenStoreEncryption(t *testing.T) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	store, err := NewDBTokenStore(nil, Backend_GMAIL, key)
+	require.NoError(t, err)
+
+	plaintext := []byte(`{"refresh_token":"secret"}`)
+	ciphertext, err := store.encrypt(plaintext)
+	require.NoError(t, err)
+	require.False(t, bytes.Contains(ciphertext, []byte("secret")))
+
+	decrypted, err := store.decrypt(ciphertext)
+	require.NoError(t, err)
+	require.Equal(t, plaintext, decrypted)
+
+	// The ciphertext is bound to its provider
+	other, err := NewDBTokenStore(nil, "other", key)
+	require.NoError(t, err)
+	_, err = other.decrypt(ciphertext)
+	require.Error(t, err)
+}
+
+func TestLoadGmailToken(t *testing.T) {
+	t.Run("FirstStart", func(t *testing.T) {
+		store := &memoryTokenStore{}
+		token, err := loadGmailToken(`{"refresh_token":"r1"}`, store)
+		require.NoError(t, err)
+		require.Equal(t, "r1", token.RefreshToken)
+		require.Equal(t, 1, store.saves)
+	})
+	t.Run("StoredTokenIsPreferred", func(t *testing.T) {
+		store := &memoryTokenStore{token: &oauth2.Token{AccessToken: "refreshed", RefreshToken: "r1"}}
+		token, err := loadGmailToken(`{"refresh_token":"r1"}`, store)
+		require.NoError(t, err)
+		require.Equal(t, "refreshed", token.AccessToken)
+		require.Equal(t, 0, store.saves)
+	})
+	t.Run("NewRefreshToken", func(t *testing.T) {
+		store := &memoryTokenStore{token: &oauth2.Token{RefreshToken: "r1"}, revoked: true}
+		token, err := loadGmailToken(`{"refresh_token":"r2"}`, store)
+		require.NoError(t, err)
+		require.Equal(t, "r2", token.RefreshToken)
+		require.False(t, store.revoked)
+	})
+	t.Run("NoToken", func(t *testing.T) {
+		_, err := loadGmailToken("", &memoryTokenStore{})
+		require.ErrorContains(t, err, "TOKEN_JSON")
+	})
+}
+
+func TestPersistingTokenSource(t *testing.T) {
+	t.Run("SavesRefreshedToken", func(t *testing.T) {
+		store := &memoryTokenStore{}
+		accessToken := "a1"
+		source := &persistingTokenSource{
+			source: tokenSourceFunc(func() (*oauth2.Token, error) {
+				return &oauth2.Token{AccessToken: accessToken, RefreshToken: "r1", Expiry: time.Now().Add(time.Hour)}, nil
+			}),
+			store: store,
+			saved: "a1",
+		}
+
+		_, err := source.Token()
+		require.NoError(t, err)
+		require.Equal(t, 0, store.saves)
+
+		accessToken = "a2"
+		_, err = source.Token()
+		require.NoError(t, err)
+		_, err = source.Token()
+		require.NoError(t, err)
+		require.Equal(t, 1, store.saves)
+		require.Equal(t, "a2", store.token.AccessToken)
+	})
+	t.Run("AlertsOnceWhenRevoked", func(t *testing.T) {
+		store := &memoryTokenStore{token: &oauth2.Token{RefreshToken: "r1"}}
+		alerts := 0
+		source := &persistingTokenSource{
+			source: tokenSourceFunc(func() (*oauth2.Token, error) {
+				return nil, &oauth2.RetrieveError{ErrorCode: "invalid_grant"}
+			}),
+			store:     store,
+			onRevoked: func(err error) { alerts++ },
+		}
+
+		for i := 0; i < 2; i++ {
+			_, err := source.Token()
+			require.True(t, isTokenRevoked(err))
+		}
+		require.True(t, store.revoked)
+		require.Equal(t, 1, alerts)
+	})
+	t.Run("OtherErrorsAreNotRevocations", func(t *testing.T) {
+		store := &memoryTokenStore{}
+		source := &persistingTokenSource{
+			source: tokenSourceFunc(func() (*oauth2.Token, error) {
+				return nil, errors.New("connection reset")
+			}),
+			store:     store,
+			onRevoked: func(err error) { t.Fatal("unexpected alert") },
+		}
+
+		_, err := source.Token()
+		require.Error(t, err)
+		require.False(t, store.revoked)
+	})
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AuditAction_IMPERSONATE string = "impersonate"
+	AuditAction_REQUEST            = "request"
+)
+
+// AuditLog records the actions taken by admins on behalf of other users
+type AuditLog struct {
+	ID             uuid.UUID     `gorm:"primaryKey;type:uuid;default:gen_random_uuid();->"`
+	Action         string        `gorm:"not null"`
+	UserID         uuid.UUID     `gorm:"type:uuid;not null;index"`
+	ImpersonatorID uuid.NullUUID `gorm:"type:uuid;index"`
+	Method         string
+	Path           string
+	Status         int
+
+	CreatedAt time.Time `gorm:"<-:create;not null;index"`
+}
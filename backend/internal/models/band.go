@@ -28,9 +28,13 @@ type Band struct {
 	MaxPoints  float64   `gorm:"not null"`
 	MaxEntries int       `gorm:"not null"`
 	Price      int       `gorm:"not null"`
+	// StartTime is the time the band starts on its day, formatted as 15:04
+	StartTime string
 
 	SexAllowed     string         `gorm:"not null"`
 	OnlyCategories pq.StringArray `gorm:"type:text[]"`
+	// CheckInClosedAt is when the deadline of the check in was last applied, the waiting list did not get the places left
+	CheckInClosedAt *time.Time
 
 	CreatedAt time.Time `gorm:"<-:create;not null"`
 }
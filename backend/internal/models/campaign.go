@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CampaignSegment_CONFIRMED        string = "confirmed"
+	CampaignSegment_WAITING_LIST            = "waiting_list"
+	CampaignSegment_PREVIOUS_EDITION        = "previous_edition"
+)
+
+const (
+	CampaignStatus_DRAFT   string = "draft"
+	CampaignStatus_SENDING        = "sending"
+	CampaignStatus_DONE           = "done"
+)
+
+const (
+	CampaignRecipientStatus_PENDING string = "pending"
+	CampaignRecipientStatus_SENT           = "sent"
+	CampaignRecipientStatus_FAILED         = "failed"
+)
+
+// Campaign is an email sent by admins to a segment of the users
+type Campaign struct {
+	ID      uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid();->"`
+	Name    string    `gorm:"not null"`
+	Subject string    `gorm:"not null"`
+	// HTML and Text are html/template and text/template sources, see campaigns.Data
+	HTML string `gorm:"type:text;not null"`
+	Text string `gorm:"type:text"`
+
+	AttachmentName        string
+	AttachmentContentType string
+	Attachment            []byte `json:"-"`
+
+	Segment string `gorm:"not null"`
+	// SegmentBandID is the band of the waiting_list segment
+	SegmentBandID uuid.NullUUID `gorm:"type:uuid"`
+	// SegmentEdition is the past edition of the previous_edition segment
+	SegmentEdition string
+
+	Status    string        `gorm:"not null;default:draft"`
+	CreatedBy uuid.NullUUID `gorm:"type:uuid"`
+	SentAt    *time.Time
+
+	CreatedAt time.Time `gorm:"<-:create;not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
+
+// CampaignRecipient logs the sending of a campaign to an email, so that a run can be resumed without duplicates
+type CampaignRecipient struct {
+	ID         uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid();->"`
+	CampaignID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_campaign_recipients_campaign_id_email"`
+	Email      string    `gorm:"not null;uniqueIndex:idx_campaign_recipients_campaign_id_email"`
+
+	Status string `gorm:"not null;default:pending;index"`
+	Error  string
+	SentAt *time.Time
+
+	CreatedAt time.Time `gorm:"<-:create;not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
+
+// PastParticipant is an email which had confirmed entries in a previous edition of the tournament
+type PastParticipant struct {
+	ID      uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid();->"`
+	Edition string    `gorm:"not null;uniqueIndex:idx_past_participants_edition_email"`
+	Email   string    `gorm:"not null;uniqueIndex:idx_past_participants_edition_email"`
+
+	CreatedAt time.Time `gorm:"<-:create;not null"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	CheckInStatus_PRESENT string = "present"
+	// CheckInStatus_LATE is a player who warned of a late arrival, its place is kept at the deadline
+	CheckInStatus_LATE = "late"
+	// CheckInStatus_ABSENT_WITH_NOTICE is a player who warned they would not come
+	CheckInStatus_ABSENT_WITH_NOTICE = "absent_with_notice"
+	// CheckInStatus_SCRATCHED is a player of the main draw who was not checked in at the deadline
+	CheckInStatus_SCRATCHED = "scratched"
+)
+
+// CheckIn is the status of a confirmed entry on the day of the tournament, the entries without check in are not checked yet
+type CheckIn struct {
+	ID       uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid();->"`
+	EntryID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex"`
+	MemberID uuid.UUID `gorm:"type:uuid;not null;index"`
+	BandID   uuid.UUID `gorm:"type:uuid;not null;index"`
+	Status   string    `gorm:"not null"`
+	// Promoted is set on the waiting list entries which took a place freed at the deadline
+	Promoted bool `gorm:"not null;default:false"`
+
+	CheckedBy uuid.NullUUID `gorm:"type:uuid"`
+
+	CreatedAt time.Time `gorm:"<-:create;not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
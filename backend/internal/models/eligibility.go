@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EligibilityReason_MAX_POINTS string = "max_points"
+	EligibilityReason_CATEGORY          = "category"
+	EligibilityReason_SEX               = "sex"
+)
+
+const (
+	EligibilityIssueStatus_OPEN      string = "open"
+	EligibilityIssueStatus_KEPT             = "kept"
+	EligibilityIssueStatus_MOVED            = "moved"
+	EligibilityIssueStatus_WITHDRAWN        = "withdrawn"
+)
+
+// EligibilityIssue is a confirmed entry of a member who no longer fits its band since its points or category changed
+type EligibilityIssue struct {
+	ID       uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid();->"`
+	EntryID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex"`
+	MemberID uuid.UUID `gorm:"type:uuid;not null;index"`
+	BandID   uuid.UUID `gorm:"type:uuid;not null"`
+	Reason   string    `gorm:"not null"`
+	Points   float64   `gorm:"not null"`
+	Category string
+	Status   string `gorm:"not null;default:open;index"`
+
+	// MovedToBandID is the band the entry was moved to when Status is moved
+	MovedToBandID uuid.NullUUID `gorm:"type:uuid"`
+	ResolvedBy    uuid.NullUUID `gorm:"type:uuid"`
+	ResolvedAt    *time.Time
+
+	CreatedAt time.Time `gorm:"<-:create;not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// EntriesDigest debounces the summary email of a member's entries: every change pushes DueAt back,
+// and the summary is sent once the member has not been changed for a while
+type EntriesDigest struct {
+	ID       uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid();->"`
+	MemberID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex"`
+	Member   Member
+
+	// NotifiedBandIDs are the bands of the last summary sent, to tell what changed since
+	NotifiedBandIDs pq.StringArray `gorm:"type:text[]"`
+	// DueAt is null when there is no pending change to notify
+	DueAt *time.Time `gorm:"index"`
+
+	CreatedAt time.Time `gorm:"<-:create;not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
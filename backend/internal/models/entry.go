@@ -24,4 +24,9 @@ type Entry struct {
 	CreatedBy   uuid.NullUUID `gorm:"type:uuid"`
 	ConfirmedBy uuid.NullUUID `gorm:"type:uuid"`
 	DeletedBy   uuid.NullUUID `gorm:"type:uuid"`
+
+	// Admins acting as CreatedBy, ConfirmedBy or DeletedBy through impersonation
+	CreatedByImpersonator   uuid.NullUUID `gorm:"type:uuid"`
+	ConfirmedByImpersonator uuid.NullUUID `gorm:"type:uuid"`
+	DeletedByImpersonator   uuid.NullUUID `gorm:"type:uuid"`
 }
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	PermitType_TRADITIONAL string = "T"
+	PermitType_PROMOTIONAL        = "P"
+)
+
+const (
+	LicenceIssue_PERMIT_TYPE         string = "permit_type"
+	LicenceIssue_NOT_VALIDATED              = "not_validated"
+	LicenceIssue_MEDICAL_CERTIFICATE        = "medical_certificate"
+)
+
+// LicenceRulesID is the ID of the single row of licence_rules, the rules of the tournament
+const LicenceRulesID = 1
+
+// LicenceRules are the licences accepted by the tournament, checked when members are created and by the points synchronisation
+type LicenceRules struct {
+	ID uint `gorm:"primaryKey"`
+	// AllowedPermitTypes are the accepted permit types, like T and P, all of them when empty
+	AllowedPermitTypes pq.StringArray `gorm:"type:text[]"`
+	// RequireValidation rejects the licences which were not validated for the season of the tournament
+	RequireValidation bool `gorm:"not null;default:false"`
+	// RequireMedicalCertificate rejects the licences without medical certificate, when the FFTT provider reports it
+	RequireMedicalCertificate bool `gorm:"not null;default:false"`
+
+	UpdatedBy uuid.NullUUID `gorm:"type:uuid"`
+	UpdatedAt time.Time     `gorm:"not null"`
+}
@@ -7,6 +7,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// ManualPermitPrefix prefixes the placeholder permits of the manual members declared without any licence
+const ManualPermitPrefix = "MANUAL-"
+
 type Member struct {
 	ID              uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid();->"`
 	PermitID        string    `gorm:"not null;uniqueIndex:idx_members_permit_id,where:deleted_at IS NULL"`
@@ -16,9 +19,23 @@ type Member struct {
 	Points          float64   `gorm:"not null"`
 	Category        string
 	ClubName        string
+	ClubNumber      string `gorm:"index"`
 	PermitType      string
 	HasBeenNotified bool
 
+	// Manual members were declared without an FFTT record, their points and category are the declared ones
+	Manual       bool `gorm:"not null;default:false"`
+	ManualReason string
+	// PendingValidation is set on the manual members declared by users, they can not confirm entries until an admin validates them
+	PendingValidation bool          `gorm:"not null;default:false;index"`
+	ValidatedBy       uuid.NullUUID `gorm:"type:uuid"`
+	ValidatedAt       *time.Time
+	// ReconciledAt is set when a manual member is linked to the FFTT record which appeared since
+	ReconciledAt *time.Time
+	// LicenceIssue tells why the licence of the member is not accepted anymore by the licence rules, see LicenceIssue_*
+	LicenceIssue     string
+	LicenceCheckedAt *time.Time
+
 	CreatedAt time.Time      `gorm:"<-:create;not null"`
 	UpdatedAt time.Time      `gorm:"not null"`
 	DeletedAt gorm.DeletedAt `gorm:"index"`
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MemberChangeSource_SYNC is the periodic synchronisation with the FFTT
+	MemberChangeSource_SYNC string = "sync"
+	// MemberChangeSource_REFRESH is a refresh from the FFTT requested by the owner or an admin
+	MemberChangeSource_REFRESH = "refresh"
+	// MemberChangeSource_OVERRIDE is a value set by an admin, with a reason
+	MemberChangeSource_OVERRIDE = "override"
+	// MemberChangeSource_RECONCILE is the link of a manual member to its FFTT record
+	MemberChangeSource_RECONCILE = "reconcile"
+)
+
+// MemberChange records the change of a field of a member, like its points or category
+type MemberChange struct {
+	ID            uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid();->"`
+	MemberID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	Field         string    `gorm:"not null"`
+	PreviousValue string
+	Value         string
+	Source        string `gorm:"not null"`
+	Reason        string
+
+	ChangedBy             uuid.NullUUID `gorm:"type:uuid"`
+	ChangedByImpersonator uuid.NullUUID `gorm:"type:uuid"`
+
+	CreatedAt time.Time `gorm:"<-:create;not null"`
+}
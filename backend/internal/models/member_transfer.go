@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	MemberTransferStatus_PENDING   string = "pending"
+	MemberTransferStatus_APPROVED         = "approved"
+	MemberTransferStatus_REJECTED         = "rejected"
+	MemberTransferStatus_CANCELLED        = "cancelled"
+)
+
+// MemberTransfer is a request from ToUserID to take over the ownership of a member owned by FromUserID
+type MemberTransfer struct {
+	ID         uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid();->"`
+	MemberID   uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_member_transfer_member_id_to_user_id,where:status = 'pending'"`
+	FromUserID uuid.UUID `gorm:"type:uuid;not null;index"`
+	ToUserID   uuid.UUID `gorm:"type:uuid;not null;index;uniqueIndex:idx_member_transfer_member_id_to_user_id,where:status = 'pending'"`
+	Status     string    `gorm:"not null;default:pending"`
+	Reason     string
+
+	DecidedBy uuid.NullUUID `gorm:"type:uuid"`
+	DecidedAt *time.Time
+
+	CreatedAt time.Time `gorm:"<-:create;not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
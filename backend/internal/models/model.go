@@ -7,5 +7,23 @@ func ListModels() []interface{} {
 		&Band{},
 		&OTP{},
 		&Entry{},
+		&AuditLog{},
+		&MemberTransfer{},
+		&OutboxMessage{},
+		&EntriesDigest{},
+		&Campaign{},
+		&CampaignRecipient{},
+		&PastParticipant{},
+		&OAuthToken{},
+		&MemberChange{},
+		&EligibilityIssue{},
+		&Player{},
+		&LicenceRules{},
+		&PricingRules{},
+		&CheckIn{},
+		&Payment{},
+		&OnlinePayment{},
+		&OnlinePaymentLine{},
+		&Receipt{},
 	}
 }
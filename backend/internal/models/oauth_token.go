@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OAuthToken is the last known OAuth token of a provider, encrypted so that a database dump does not leak it
+type OAuthToken struct {
+	ID       uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid();->"`
+	Provider string    `gorm:"not null;uniqueIndex"`
+	// Ciphertext is the AES-GCM encrypted JSON of the token, prefixed with its nonce
+	Ciphertext []byte    `gorm:"not null"`
+	Expiry     time.Time `gorm:"not null"`
+
+	RefreshedAt *time.Time
+	// RevokedAt is set when the provider rejected the refresh token, a new one has to be configured
+	RevokedAt *time.Time
+	LastError string
+
+	CreatedAt time.Time `gorm:"<-:create;not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	OnlinePaymentStatus_PENDING string = "pending"
+	OnlinePaymentStatus_PAID           = "paid"
+	// OnlinePaymentStatus_FAILED is a checkout which expired or whose payment was declined
+	OnlinePaymentStatus_FAILED = "failed"
+)
+
+const (
+	OnlinePaymentLineStatus_PENDING string = "pending"
+	// OnlinePaymentLineStatus_HELD is the payment of a waiting list entry, it is only settled once the entry is promoted
+	OnlinePaymentLineStatus_HELD = "held"
+	// OnlinePaymentLineStatus_SETTLED is recorded in the ledger of the member as a payment
+	OnlinePaymentLineStatus_SETTLED  = "settled"
+	OnlinePaymentLineStatus_REFUNDED = "refunded"
+	OnlinePaymentLineStatus_FAILED   = "failed"
+)
+
+// OnlinePayment is a checkout of the payment provider, paying some entries of a member
+type OnlinePayment struct {
+	ID       uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid();->"`
+	MemberID uuid.UUID `gorm:"type:uuid;not null;index"`
+	// UserID is the user who opened the checkout
+	UserID   uuid.UUID `gorm:"type:uuid;not null"`
+	Provider string    `gorm:"not null"`
+	// CheckoutID is empty until the checkout is opened at the provider, after the payment is saved
+	CheckoutID string `gorm:"not null;uniqueIndex:idx_online_payments_checkout,where:checkout_id <> ''"`
+	// CheckoutURL is the payment page the player is redirected to
+	CheckoutURL string
+	// ProviderPaymentID is known once paid, it is required to refund
+	ProviderPaymentID string
+	// Amount in euros, as Band.Price
+	Amount int    `gorm:"not null"`
+	Status string `gorm:"not null;index"`
+
+	Lines []OnlinePaymentLine
+
+	CreatedAt time.Time `gorm:"<-:create;not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
+
+// OnlinePaymentLine is the part of an online payment which pays an entry
+type OnlinePaymentLine struct {
+	ID              uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid();->"`
+	OnlinePaymentID uuid.UUID `gorm:"type:uuid;not null;index"`
+	EntryID         uuid.UUID `gorm:"type:uuid;not null;index"`
+	Day             int       `gorm:"not null"`
+	// Amount in euros, as Band.Price
+	Amount     int    `gorm:"not null"`
+	Status     string `gorm:"not null;index"`
+	RefundedAt *time.Time
+
+	CreatedAt time.Time `gorm:"<-:create;not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	OutboxStatus_PENDING string = "pending"
+	// OutboxStatus_SENDING is a message claimed by a sender, until NextAttemptAt
+	OutboxStatus_SENDING = "sending"
+	OutboxStatus_SENT    = "sent"
+	OutboxStatus_DEAD    = "dead"
+)
+
+// OutboxMessage is an email written in the same transaction as the change it notifies, and sent afterwards
+type OutboxMessage struct {
+	ID uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid();->"`
+	// Key makes enqueuing idempotent, a message is only stored once per key
+	Key         string `gorm:"not null;uniqueIndex"`
+	To          string `gorm:"not null"`
+	Subject     string `gorm:"not null"`
+	HTML        string `gorm:"type:text"`
+	Text        string `gorm:"type:text"`
+	Attachments []byte `gorm:"type:jsonb"`
+
+	Status        string    `gorm:"not null;default:pending;index"`
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"not null;index"`
+	LastError     string
+	SentAt        *time.Time
+
+	CreatedAt time.Time `gorm:"<-:create;not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
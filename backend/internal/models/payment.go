@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	PaymentMethod_CASH   string = "cash"
+	PaymentMethod_CHEQUE        = "cheque"
+	// PaymentMethod_ONLINE is recorded when a payment of the provider is settled, see OnlinePayment
+	PaymentMethod_ONLINE = "online"
+)
+
+const (
+	PaymentKind_PAYMENT string = "payment"
+	// PaymentKind_REFUND is money given back to the player, its amount is positive too
+	PaymentKind_REFUND = "refund"
+)
+
+// Payment is a line of the ledger of a member for a day of the tournament, the ledger is never updated:
+// a mistake is corrected by a refund
+type Payment struct {
+	ID       uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid();->"`
+	MemberID uuid.UUID `gorm:"type:uuid;not null;index:idx_payment_member_id_day"`
+	Day      int       `gorm:"not null;index:idx_payment_member_id_day"`
+	Kind     string    `gorm:"not null"`
+	Method   string    `gorm:"not null"`
+	// Amount in euros, as Band.Price
+	Amount  int `gorm:"not null"`
+	Comment string
+
+	// CashierID is the admin who collected or gave back the money, empty for online payments
+	CashierID uuid.NullUUID `gorm:"type:uuid;index"`
+	// OnlinePaymentLineID is the entry paid online this payment settles
+	OnlinePaymentLineID uuid.NullUUID `gorm:"type:uuid;index"`
+
+	CreatedAt time.Time `gorm:"<-:create;not null;index"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// PlayerSource_IMPORT players come from an FFTT export imported by an admin
+	PlayerSource_IMPORT string = "import"
+	// PlayerSource_LOOKUP players were stored when they were fetched from the FFTT API
+	PlayerSource_LOOKUP = "lookup"
+)
+
+// Player is a licensed player of the local directory, searched instead of calling the FFTT on each keystroke
+type Player struct {
+	ID         uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid();->"`
+	PermitID   string    `gorm:"not null;uniqueIndex"`
+	LastName   string    `gorm:"not null"`
+	FirstName  string    `gorm:"not null"`
+	Sex        string
+	Points     float64
+	Category   string
+	ClubName   string
+	ClubNumber string `gorm:"index"`
+	PermitType string
+	// Search is the lowercase text without accents of the names, club and permit, indexed for trigram searches
+	Search string `gorm:"not null;index:idx_players_search,type:gin,expression:search gin_trgm_ops"`
+	Source string `gorm:"not null"`
+
+	CreatedAt time.Time `gorm:"<-:create;not null"`
+	UpdatedAt time.Time `gorm:"not null"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PricingRulesID is the ID of the single row of pricing_rules, the pricing of the tournament
+const PricingRulesID = 1
+
+// PricingRules are the discounts and surcharges applied to the price of the bands, see pricing.Rules
+type PricingRules struct {
+	ID uint `gorm:"primaryKey"`
+	// Rules is the JSON encoded pricing.Rules
+	Rules []byte `gorm:"type:jsonb;not null"`
+
+	UpdatedBy uuid.NullUUID `gorm:"type:uuid"`
+	UpdatedAt time.Time     `gorm:"not null"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Receipt numbers what a member paid for a day of the tournament. Receipts are never changed:
+// a new receipt, with the next number, is issued when the amount paid for the day changes.
+type Receipt struct {
+	ID       uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid();->"`
+	Number   int       `gorm:"not null;uniqueIndex"`
+	MemberID uuid.UUID `gorm:"type:uuid;not null;index:idx_receipt_member_id_day"`
+	Day      int       `gorm:"not null;index:idx_receipt_member_id_day"`
+	// Amount paid for the day, minus the refunds, in euros
+	Amount int `gorm:"not null"`
+
+	CreatedAt time.Time `gorm:"<-:create;not null"`
+}
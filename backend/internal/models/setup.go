@@ -31,6 +31,8 @@ func ConnectDatabase() (*gorm.DB, error) {
 	}
 
 	db.Exec("CREATE EXTENSION IF NOT EXISTS \"uuid-ossp\"")
+	// pg_trgm provides the trigram index of the player directory
+	db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm")
 
 	for _, model := range ListModels() {
 		err = db.AutoMigrate(model)
@@ -11,6 +11,14 @@ type User struct {
 	ID      uuid.UUID `gorm:"primaryKey;type:uuid;default:gen_random_uuid()"`
 	Email   string    `gorm:"not null"`
 	IsAdmin bool      `gorm:"not null"`
+	// Language of the emails sent to the user, see emails.Languages
+	Language string `gorm:"not null;default:fr"`
+	// CalendarToken authenticates the calendar feed of the user
+	CalendarToken uuid.NullUUID `gorm:"type:uuid;uniqueIndex" json:"-"`
+
+	// Club managers register players of the FFTT club identified by ClubNumber
+	IsClubManager bool `gorm:"not null;default:false"`
+	ClubNumber    string
 
 	CreatedAt time.Time      `gorm:"<-:create;not null"`
 	UpdatedAt time.Time      `gorm:"not null"`
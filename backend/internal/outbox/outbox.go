@@ -0,0 +1,229 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/mailer"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/getsentry/sentry-go"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultMaxAttempts = 8
+	defaultBaseDelay   = 30 * time.Second
+	defaultMaxDelay    = 6 * time.Hour
+	defaultBatchSize   = 20
+	defaultInterval    = 30 * time.Second
+	// claimLease is how long a message being sent is kept from the other senders,
+	// it is sent again past it as its sender stopped before recording the outcome
+	claimLease = 10 * time.Minute
+)
+
+// Enqueue stores the message in the outbox with tx, so that it is only sent if the transaction commits.
+// Enqueuing twice the same key is a no-op.
+func Enqueue(tx *gorm.DB, key string, message *mailer.Message) error {
+	var attachments []byte
+	if len(message.Attachments) > 0 {
+		var err error
+		attachments, err = json.Marshal(message.Attachments)
+		if err != nil {
+			return fmt.Errorf("failed to marshal attachments: %w", err)
+		}
+	}
+
+	return tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "key"}}, DoNothing: true}).
+		Create(&models.OutboxMessage{
+			Key:           key,
+			To:            message.To,
+			Subject:       message.Subject,
+			HTML:          message.HTML,
+			Text:          message.Text,
+			Attachments:   attachments,
+			Status:        models.OutboxStatus_PENDING,
+			NextAttemptAt: time.Now(),
+		}).Error
+}
+
+// Resend puts a message back in the queue, whatever its status
+func Resend(db *gorm.DB, message *models.OutboxMessage) error {
+	return db.Model(message).
+		Select("status", "attempts", "next_attempt_at", "last_error").
+		Updates(models.OutboxMessage{
+			Status:        models.OutboxStatus_PENDING,
+			Attempts:      0,
+			NextAttemptAt: time.Now(),
+			LastError:     "",
+		}).Error
+}
+
+// Backoff returns the delay before the next attempt, doubling at each attempt up to maxDelay
+func Backoff(attempts int, baseDelay time.Duration, maxDelay time.Duration) time.Duration {
+	delay := baseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+	return delay
+}
+
+// Sender delivers the pending messages of the outbox
+type Sender struct {
+	db     *gorm.DB
+	mailer mailer.Mailer
+	wake   chan struct{}
+
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	BatchSize   int
+	Interval    time.Duration
+}
+
+func NewSender(db *gorm.DB, mailer mailer.Mailer) *Sender {
+	return &Sender{
+		db:          db,
+		mailer:      mailer,
+		wake:        make(chan struct{}, 1),
+		MaxAttempts: defaultMaxAttempts,
+		BaseDelay:   defaultBaseDelay,
+		MaxDelay:    defaultMaxDelay,
+		BatchSize:   defaultBatchSize,
+		Interval:    defaultInterval,
+	}
+}
+
+// Wake triggers a delivery without waiting for the next tick
+func (s *Sender) Wake() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers pending messages until ctx is done
+func (s *Sender) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			processed, err := s.ProcessBatch()
+			if err != nil {
+				log.Printf("outbox: %s", err)
+				sentry.CaptureException(err)
+			}
+			if err != nil || processed < s.BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// ProcessBatch sends the messages which are due, one at a time, and returns how many were processed.
+// No transaction is held while a message is sent, the outcome of each message is saved as soon as it is known.
+func (s *Sender) ProcessBatch() (int, error) {
+	var processed int
+	for processed < s.BatchSize {
+		message, err := s.claim()
+		if err != nil {
+			return processed, err
+		}
+		if message == nil {
+			break
+		}
+
+		if err = s.db.Select("status", "attempts", "next_attempt_at", "last_error", "sent_at").
+			Updates(s.deliver(message)).Error; err != nil {
+			return processed, fmt.Errorf("failed to update message %s: %w", message.ID, err)
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// claim marks the next message which is due as sending, so that several senders never deliver the same message,
+// and returns nil when there is none
+func (s *Sender) claim() (*models.OutboxMessage, error) {
+	var message *models.OutboxMessage
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var messages []models.OutboxMessage
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND next_attempt_at <= ?", []string{models.OutboxStatus_PENDING, models.OutboxStatus_SENDING}, time.Now()).
+			Order("next_attempt_at ASC").
+			Limit(1).
+			Find(&messages).Error; err != nil {
+			return fmt.Errorf("failed to list pending messages: %w", err)
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+
+		message = &messages[0]
+		message.Status = models.OutboxStatus_SENDING
+		message.NextAttemptAt = time.Now().Add(claimLease)
+		if err := tx.Select("status", "next_attempt_at").Updates(message).Error; err != nil {
+			return fmt.Errorf("failed to claim message %s: %w", message.ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// deliver sends the message and returns it updated with the outcome of the attempt
+func (s *Sender) deliver(message *models.OutboxMessage) *models.OutboxMessage {
+	message.Attempts += 1
+
+	err := s.send(message)
+	if err == nil {
+		now := time.Now()
+		message.Status = models.OutboxStatus_SENT
+		message.SentAt = &now
+		message.LastError = ""
+		return message
+	}
+
+	message.LastError = err.Error()
+	if message.Attempts >= s.MaxAttempts {
+		message.Status = models.OutboxStatus_DEAD
+		sentry.CaptureMessage(fmt.Sprintf("outbox message %s to %s is dead: %s", message.Key, message.To, err))
+		return message
+	}
+	message.Status = models.OutboxStatus_PENDING
+	message.NextAttemptAt = time.Now().Add(Backoff(message.Attempts, s.BaseDelay, s.MaxDelay))
+	return message
+}
+
+func (s *Sender) send(message *models.OutboxMessage) error {
+	var attachments []mailer.Attachment
+	if len(message.Attachments) > 0 {
+		if err := json.Unmarshal(message.Attachments, &attachments); err != nil {
+			return fmt.Errorf("failed to unmarshal attachments: %w", err)
+		}
+	}
+
+	return s.mailer.Send(&mailer.Message{
+		To:          message.To,
+		Subject:     message.Subject,
+		HTML:        message.HTML,
+		Text:        message.Text,
+		Attachments: attachments,
+	})
+}
@@ -0,0 +1,58 @@
+package outbox
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/mailer"
+	"github.com/SuperPingPong/tournoi/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+type failingMailer struct{}
+
+func (failingMailer) Send(message *mailer.Message) error {
+	return errors.New("connection refused")
+}
+
+func TestBackoff(t *testing.T) {
+	require.Equal(t, 30*time.Second, Backoff(1, 30*time.Second, time.Hour))
+	require.Equal(t, 60*time.Second, Backoff(2, 30*time.Second, time.Hour))
+	require.Equal(t, 4*time.Minute, Backoff(4, 30*time.Second, time.Hour))
+	require.Equal(t, time.Hour, Backoff(12, 30*time.Second, time.Hour))
+}
+
+func TestDeliver(t *testing.T) {
+	t.Run("Success", func(t *testing.T) {
+		memoryMailer := mailer.NewMemoryMailer()
+		sender := NewSender(nil, memoryMailer)
+
+		message := sender.deliver(&models.OutboxMessage{To: "john.doe@example.com", Subject: "Hello", HTML: "<p>Hello</p>"})
+
+		require.Equal(t, models.OutboxStatus_SENT, message.Status)
+		require.Equal(t, 1, message.Attempts)
+		require.NotNil(t, message.SentAt)
+		require.Len(t, memoryMailer.Sent(), 1)
+		require.Equal(t, "john.doe@example.com", memoryMailer.Sent()[0].To)
+	})
+	t.Run("Retry", func(t *testing.T) {
+		sender := NewSender(nil, failingMailer{})
+
+		before := time.Now()
+		message := sender.deliver(&models.OutboxMessage{To: "john.doe@example.com", Status: models.OutboxStatus_SENDING, Attempts: 2})
+
+		require.Equal(t, models.OutboxStatus_PENDING, message.Status)
+		require.Equal(t, 3, message.Attempts)
+		require.Equal(t, "connection refused", message.LastError)
+		require.True(t, message.NextAttemptAt.After(before.Add(2*time.Minute-time.Second)))
+	})
+	t.Run("Dead", func(t *testing.T) {
+		sender := NewSender(nil, failingMailer{})
+
+		message := sender.deliver(&models.OutboxMessage{To: "john.doe@example.com", Attempts: sender.MaxAttempts - 1})
+
+		require.Equal(t, models.OutboxStatus_DEAD, message.Status)
+		require.Equal(t, sender.MaxAttempts, message.Attempts)
+	})
+}
@@ -0,0 +1,121 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// FakeSignatureHeader holds the hex HMAC-SHA256 of the body of the webhooks of the fake provider
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// FakeRefund is a refund made with the fake provider
+type FakeRefund struct {
+	PaymentID      string
+	Amount         int
+	IdempotencyKey string
+}
+
+// FakeProvider keeps checkouts and refunds in memory, for tests and local development.
+// Its checkout pages redirect to the success URL, payments are completed with Pay.
+type FakeProvider struct {
+	secret []byte
+
+	mu        sync.Mutex
+	checkouts map[string]CheckoutRequest
+	refunds   map[string]FakeRefund
+}
+
+func NewFakeProvider(secret []byte) *FakeProvider {
+	return &FakeProvider{
+		secret:    secret,
+		checkouts: map[string]CheckoutRequest{},
+		refunds:   map[string]FakeRefund{},
+	}
+}
+
+func (p *FakeProvider) Name() string { return Provider_FAKE }
+
+func (p *FakeProvider) CreateCheckout(_ context.Context, request CheckoutRequest) (Checkout, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	id := fmt.Sprintf("fake_cs_%d", len(p.checkouts)+1)
+	p.checkouts[id] = request
+	return Checkout{ID: id, URL: request.SuccessURL}, nil
+}
+
+func (p *FakeProvider) Refund(_ context.Context, paymentID string, amount int, idempotencyKey string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.refunds[idempotencyKey]; !ok {
+		p.refunds[idempotencyKey] = FakeRefund{PaymentID: paymentID, Amount: amount, IdempotencyKey: idempotencyKey}
+	}
+	return nil
+}
+
+// Refunds returns the refunds made so far, by idempotency key
+func (p *FakeProvider) Refunds() map[string]FakeRefund {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	refunds := make(map[string]FakeRefund, len(p.refunds))
+	for key, refund := range p.refunds {
+		refunds[key] = refund
+	}
+	return refunds
+}
+
+func (p *FakeProvider) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// Webhook returns the signed webhook of an event on a checkout
+func (p *FakeProvider) Webhook(eventType string, checkoutID string) (http.Header, []byte, error) {
+	p.mu.Lock()
+	request, ok := p.checkouts[checkoutID]
+	p.mu.Unlock()
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown checkout %s", checkoutID)
+	}
+
+	body, err := json.Marshal(Event{
+		Type:       eventType,
+		CheckoutID: checkoutID,
+		Reference:  request.Reference,
+		PaymentID:  "fake_pi_" + checkoutID,
+		Amount:     request.Amount,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	header := http.Header{}
+	header.Set(FakeSignatureHeader, hex.EncodeToString(p.sign(body)))
+	return header, body, nil
+}
+
+// Pay returns the signed webhook of the payment of a checkout
+func (p *FakeProvider) Pay(checkoutID string) (http.Header, []byte, error) {
+	return p.Webhook(EventType_PAID, checkoutID)
+}
+
+func (p *FakeProvider) ParseWebhook(header http.Header, body []byte) (Event, error) {
+	signature, err := hex.DecodeString(header.Get(FakeSignatureHeader))
+	if err != nil || !hmac.Equal(signature, p.sign(body)) {
+		return Event{}, ErrInvalidSignature
+	}
+
+	var event Event
+	if err = json.Unmarshal(body, &event); err != nil {
+		return Event{}, fmt.Errorf("invalid webhook: %w", err)
+	}
+	return event, nil
+}
@@ -0,0 +1,82 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+const (
+	// Provider_NONE disables the online payments
+	Provider_NONE string = ""
+	Provider_FAKE        = "fake"
+)
+
+const (
+	EventType_PAID string = "paid"
+	// EventType_FAILED is sent when the checkout expires or the payment is declined
+	EventType_FAILED = "failed"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// CheckoutRequest describes what the player is asked to pay
+type CheckoutRequest struct {
+	// Reference identifies the payment on our side, it is sent back in the webhooks
+	Reference   string
+	Description string
+	Email       string
+	// Amount in cents
+	Amount     int
+	SuccessURL string
+	CancelURL  string
+}
+
+// Checkout is a hosted payment page of the provider
+type Checkout struct {
+	ID  string
+	URL string
+}
+
+// Event is the notification of the provider about a checkout
+type Event struct {
+	Type       string
+	CheckoutID string
+	Reference  string
+	// PaymentID identifies the payment at the provider, it is required to refund it
+	PaymentID string
+	// Amount in cents
+	Amount int
+}
+
+// Provider is a payment service with hosted checkout pages and signed webhooks, such as HelloAsso or Stripe
+type Provider interface {
+	Name() string
+	CreateCheckout(ctx context.Context, request CheckoutRequest) (Checkout, error)
+	// Refund gives back amount cents of a payment, idempotencyKey prevents refunding twice on retries
+	Refund(ctx context.Context, paymentID string, amount int, idempotencyKey string) error
+	// ParseWebhook verifies the signature of a webhook and returns its event
+	ParseWebhook(header http.Header, body []byte) (Event, error)
+}
+
+// NewFromEnv builds the provider selected by the PAYMENT_PROVIDER environment variable,
+// nil when the online payments are disabled. The fake provider collects no money, it requires DEV_MODE=true.
+func NewFromEnv() (Provider, error) {
+	switch provider := os.Getenv("PAYMENT_PROVIDER"); provider {
+	case Provider_NONE:
+		return nil, nil
+	case Provider_FAKE:
+		if os.Getenv("DEV_MODE") != "true" {
+			return nil, errors.New("the fake payment provider requires DEV_MODE=true")
+		}
+		secret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+		if secret == "" {
+			return nil, errors.New("PAYMENT_WEBHOOK_SECRET environment variable is empty")
+		}
+		return NewFakeProvider([]byte(secret)), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %s", provider)
+	}
+}
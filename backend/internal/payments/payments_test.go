@@ -0,0 +1,70 @@
+package payments
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeProvider(t *testing.T) {
+	provider := NewFakeProvider([]byte("secret"))
+	checkout, err := provider.CreateCheckout(context.Background(), CheckoutRequest{
+		Reference:  "ref",
+		Amount:     1900,
+		SuccessURL: "https://tournoi.example.com/app.html?payment=success",
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, checkout.ID)
+	require.Equal(t, "https://tournoi.example.com/app.html?payment=success", checkout.URL)
+
+	header, body, err := provider.Pay(checkout.ID)
+	require.NoError(t, err)
+	event, err := provider.ParseWebhook(header, body)
+	require.NoError(t, err)
+	require.Equal(t, Event{Type: EventType_PAID, CheckoutID: checkout.ID, Reference: "ref", PaymentID: "fake_pi_" + checkout.ID, Amount: 1900}, event)
+
+	// The signature covers the whole body
+	_, err = provider.ParseWebhook(header, append(body, ' '))
+	require.ErrorIs(t, err, ErrInvalidSignature)
+	header.Set(FakeSignatureHeader, "not hex")
+	_, err = provider.ParseWebhook(header, body)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+	other := NewFakeProvider([]byte("other"))
+	_, err = other.ParseWebhook(header, body)
+	require.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, _, err = provider.Pay("unknown")
+	require.Error(t, err)
+
+	// Refunds are idempotent
+	require.NoError(t, provider.Refund(context.Background(), event.PaymentID, 900, "key"))
+	require.NoError(t, provider.Refund(context.Background(), event.PaymentID, 900, "key"))
+	require.Len(t, provider.Refunds(), 1)
+	require.Equal(t, 900, provider.Refunds()["key"].Amount)
+}
+
+func TestNewFromEnv(t *testing.T) {
+	t.Setenv("PAYMENT_PROVIDER", "")
+	provider, err := NewFromEnv()
+	require.NoError(t, err)
+	require.Nil(t, provider)
+
+	t.Setenv("PAYMENT_PROVIDER", "fake")
+	t.Setenv("DEV_MODE", "true")
+	_, err = NewFromEnv()
+	require.Error(t, err)
+	t.Setenv("PAYMENT_WEBHOOK_SECRET", "secret")
+	// The fake provider is refused outside of development
+	t.Setenv("DEV_MODE", "")
+	_, err = NewFromEnv()
+	require.Error(t, err)
+	t.Setenv("DEV_MODE", "true")
+	provider, err = NewFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, Provider_FAKE, provider.Name())
+
+	t.Setenv("PAYMENT_PROVIDER", "paypal")
+	_, err = NewFromEnv()
+	require.Error(t, err)
+}
@@ -0,0 +1,175 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const (
+	// A4 in points
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+const (
+	Font_REGULAR string = "F1"
+	Font_BOLD           = "F2"
+)
+
+// baseFonts are the standard fonts of the documents, every PDF reader has them so they are not embedded
+var baseFonts = map[string]string{
+	Font_REGULAR: "Helvetica",
+	Font_BOLD:    "Helvetica-Bold",
+}
+
+// Document is a minimal PDF writer: text with the standard Helvetica fonts, encoded in Windows-1252, and lines
+type Document struct {
+	Title string
+	pages []*Page
+}
+
+// Page is a page of the document, its coordinates are in points from the top left corner
+type Page struct {
+	content bytes.Buffer
+}
+
+func (d *Document) AddPage() *Page {
+	page := &Page{}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// encode converts text to Windows-1252, the runes it does not have are replaced by ?
+func encode(text string) []byte {
+	encoded := make([]byte, 0, len(text))
+	for _, r := range text {
+		b, ok := charmap.Windows1252.EncodeRune(r)
+		if !ok {
+			b = '?'
+		}
+		encoded = append(encoded, b)
+	}
+	return encoded
+}
+
+// literal returns text as a PDF string
+func literal(text string) string {
+	var buf strings.Builder
+	buf.WriteByte('(')
+	for _, b := range encode(text) {
+		if b == '(' || b == ')' || b == '\\' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(b)
+	}
+	buf.WriteByte(')')
+	return buf.String()
+}
+
+func number(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// TextWidth returns the width of text in points
+func TextWidth(font string, size float64, text string) float64 {
+	widths := helveticaWidths
+	if font == Font_BOLD {
+		widths = helveticaBoldWidths
+	}
+	var width int
+	for _, b := range encode(text) {
+		if b >= 32 && int(b-32) < len(widths) {
+			width += widths[b-32]
+		} else {
+			// Accented letters are about as wide as the average letter
+			width += 556
+		}
+	}
+	return float64(width) * size / 1000
+}
+
+// Text writes text starting at x, y being its baseline
+func (p *Page) Text(x, y float64, font string, size float64, text string) {
+	fmt.Fprintf(&p.content, "BT /%s %s Tf %s %s Td %s Tj ET\n", font, number(size), number(x), number(PageHeight-y), literal(text))
+}
+
+// TextRight writes text ending at x
+func (p *Page) TextRight(x, y float64, font string, size float64, text string) {
+	p.Text(x-TextWidth(font, size, text), y, font, size, text)
+}
+
+// Line draws a line of width 0.5
+func (p *Page) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(&p.content, "0.5 w %s %s m %s %s l S\n", number(x1), number(PageHeight-y1), number(x2), number(PageHeight-y2))
+}
+
+// Bytes returns the PDF file
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	_ = d.Write(&buf)
+	return buf.Bytes()
+}
+
+func (d *Document) Write(w io.Writer) error {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(content string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), content)
+	}
+
+	// The catalog, the page tree, the fonts and the info come first, then each page and its content
+	const firstPage = 6
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	for _, font := range []string{Font_REGULAR, Font_BOLD} {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", baseFonts[font]))
+	}
+	object(fmt.Sprintf("<< /Title %s /Producer (tournoi) >>", literal(d.Title)))
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			number(PageWidth), number(PageHeight), firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.content.Len(), page.content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// helveticaWidths are the widths of the characters 32 to 126 of Helvetica, in thousandths of the font size
+var helveticaWidths = []int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// helveticaBoldWidths are the widths of the characters 32 to 126 of Helvetica-Bold
+var helveticaBoldWidths = []int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
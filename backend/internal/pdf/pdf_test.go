@@ -0,0 +1,50 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentBytes(t *testing.T) {
+	document := Document{Title: "Reçu"}
+	page := document.AddPage()
+	page.Text(50, 60, Font_BOLD, 14, "Reçu n° 1 (tournoi)")
+	page.TextRight(545, 80, Font_REGULAR, 10, "9 €")
+	page.Line(50, 90, 545, 90)
+	document.AddPage().Text(50, 60, Font_REGULAR, 10, "Page 2")
+
+	content := document.Bytes()
+	require.True(t, bytes.HasPrefix(content, []byte("%PDF-1.4\n")))
+	require.True(t, bytes.HasSuffix(content, []byte("%%EOF\n")))
+	require.Contains(t, string(content), "/Kids [6 0 R 8 0 R] /Count 2")
+	require.Contains(t, string(content), "/BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding")
+	// Text is encoded in Windows-1252, parentheses are escaped and y goes down from the top
+	require.Contains(t, string(content), "BT /F2 14 Tf 50 781.89 Td (Re\xe7u n\xb0 1 \\(tournoi\\)) Tj ET")
+	require.Contains(t, string(content), "(9 \x80) Tj ET")
+
+	// The cross-reference table points to every object
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(content)
+	require.NotNil(t, startxref)
+	xref, err := strconv.Atoi(string(startxref[1]))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(content[xref:], []byte("xref\n0 10\n")))
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(content[xref:], -1)
+	require.Len(t, offsets, 9)
+	for i, offset := range offsets {
+		position, err := strconv.Atoi(string(offset[1]))
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(content[position:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))), "object %d", i+1)
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	require.Equal(t, 5.56, TextWidth(Font_REGULAR, 10, "a"))
+	require.Equal(t, 6.11, TextWidth(Font_BOLD, 10, "b"))
+	require.Equal(t, 11.12, TextWidth(Font_REGULAR, 10, "é€"))
+	require.Zero(t, TextWidth(Font_REGULAR, 10, ""))
+}
@@ -0,0 +1,230 @@
+package pricing
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	// Adjustment_CATEGORY lowers the price of a band for the players of some categories, like the youth
+	Adjustment_CATEGORY string = "category"
+	// Adjustment_CLUB discounts the bands of the players of some clubs, like the organizing club
+	Adjustment_CLUB = "club"
+	// Adjustment_MULTI_BAND discounts the bands played after the first ones of a day
+	Adjustment_MULTI_BAND = "multi_band"
+	// Adjustment_LATE is the surcharge of the late registrations
+	Adjustment_LATE = "late"
+)
+
+// Rules are the pricing rules of the tournament, every amount is in euros.
+// Without rules, an entry costs the price of its band.
+type Rules struct {
+	Categories []CategoryPrice
+	Clubs      []ClubDiscount
+	MultiBand  []MultiBandDiscount
+	Late       []LateSurcharge
+}
+
+// CategoryPrice is the price of a band for the players of some FFTT categories, like P, B1 or J3.
+// It only applies to the bands which cost more.
+type CategoryPrice struct {
+	Categories []string `binding:"required,min=1"`
+	Price      int      `binding:"min=0"`
+}
+
+// ClubDiscount is the discount of a band for the players of some clubs, by club number
+type ClubDiscount struct {
+	ClubNumbers []string `binding:"required,min=1"`
+	Discount    int      `binding:"required,min=1"`
+}
+
+// MultiBandDiscount is the discount of a band from the From-th band a player plays the same day.
+// The most expensive bands of the day are counted first, so that the discount goes to the cheapest ones.
+type MultiBandDiscount struct {
+	From     int `binding:"required,min=2"`
+	Discount int `binding:"required,min=1"`
+}
+
+// LateSurcharge is added to the entries registered after a date
+type LateSurcharge struct {
+	After     time.Time `binding:"required"`
+	Surcharge int       `binding:"required,min=1"`
+}
+
+// Player is what the rules need to know about a member
+type Player struct {
+	Category   string
+	ClubNumber string
+}
+
+// Entry is a band a player is registered to
+type Entry struct {
+	BandName     string
+	Day          int
+	BandPrice    int
+	RegisteredAt time.Time
+}
+
+// Adjustment is a change of the price of a band by a rule, negative for discounts
+type Adjustment struct {
+	Kind   string
+	Amount int
+}
+
+// Price is the price of an entry: the price of its band and its adjustments
+type Price struct {
+	BandPrice   int
+	Adjustments []Adjustment
+	Total       int
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// basePrice is the price of a band for player, before discounts and surcharges
+func (r Rules) basePrice(player Player, bandPrice int) int {
+	price := bandPrice
+	for _, rule := range r.Categories {
+		if contains(rule.Categories, player.Category) && rule.Price < price {
+			price = rule.Price
+		}
+	}
+	return price
+}
+
+func (r Rules) clubDiscount(player Player) int {
+	var discount int
+	for _, rule := range r.Clubs {
+		if player.ClubNumber != "" && contains(rule.ClubNumbers, player.ClubNumber) && rule.Discount > discount {
+			discount = rule.Discount
+		}
+	}
+	return discount
+}
+
+// multiBandDiscount is the discount of the index-th band of a day, counted from 1
+func (r Rules) multiBandDiscount(index int) int {
+	var discount, from int
+	for _, rule := range r.MultiBand {
+		if rule.From <= index && rule.From > from {
+			discount, from = rule.Discount, rule.From
+		}
+	}
+	return discount
+}
+
+// lateSurcharge is the surcharge of the latest period registeredAt falls into
+func (r Rules) lateSurcharge(registeredAt time.Time) int {
+	var surcharge int
+	var after time.Time
+	for _, rule := range r.Late {
+		if registeredAt.After(rule.After) && !rule.After.Before(after) {
+			surcharge, after = rule.Surcharge, rule.After
+		}
+	}
+	return surcharge
+}
+
+// Price computes the price of the entries of player, in the order of entries.
+// The discounts never make an entry cost less than nothing.
+func (r Rules) Price(player Player, entries []Entry) []Price {
+	// Order the bands of each day, the most expensive first
+	order := make([]int, len(entries))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := entries[order[i]], entries[order[j]]
+		if a.Day != b.Day {
+			return a.Day < b.Day
+		}
+		if a.BandPrice != b.BandPrice {
+			return a.BandPrice > b.BandPrice
+		}
+		return a.BandName < b.BandName
+	})
+	dayIndex := make([]int, len(entries))
+	for i, index := range order {
+		dayIndex[index] = 1
+		if i > 0 && entries[order[i-1]].Day == entries[index].Day {
+			dayIndex[index] = dayIndex[order[i-1]] + 1
+		}
+	}
+
+	clubDiscount := r.clubDiscount(player)
+	prices := make([]Price, len(entries))
+	for i, entry := range entries {
+		price := Price{BandPrice: entry.BandPrice, Adjustments: []Adjustment{}, Total: entry.BandPrice}
+		adjust := func(kind string, amount int) {
+			// Discounts are capped by what is left to pay
+			if amount < -price.Total {
+				amount = -price.Total
+			}
+			if amount != 0 {
+				price.Adjustments = append(price.Adjustments, Adjustment{Kind: kind, Amount: amount})
+				price.Total += amount
+			}
+		}
+		adjust(Adjustment_CATEGORY, r.basePrice(player, entry.BandPrice)-entry.BandPrice)
+		adjust(Adjustment_CLUB, -clubDiscount)
+		adjust(Adjustment_MULTI_BAND, -r.multiBandDiscount(dayIndex[i]))
+		adjust(Adjustment_LATE, r.lateSurcharge(entry.RegisteredAt))
+		prices[i] = price
+	}
+	return prices
+}
+
+// Day is the price of the bands of a day, its adjustments are summed by kind
+type Day struct {
+	Day         int
+	BandPrice   int
+	Adjustments []Adjustment
+	Total       int
+}
+
+// Breakdown details the price of the entries of a player by day, in the order of the days
+type Breakdown struct {
+	Days  []Day
+	Total int
+}
+
+// NewBreakdown sums prices by day, prices are the prices of entries as returned by Rules.Price
+func NewBreakdown(entries []Entry, prices []Price) Breakdown {
+	breakdown := Breakdown{Days: []Day{}}
+	days := map[int]*Day{}
+	for i, entry := range entries {
+		day, ok := days[entry.Day]
+		if !ok {
+			day = &Day{Day: entry.Day, Adjustments: []Adjustment{}}
+			days[entry.Day] = day
+		}
+		day.BandPrice += prices[i].BandPrice
+		day.Total += prices[i].Total
+		for _, adjustment := range prices[i].Adjustments {
+			found := false
+			for j := range day.Adjustments {
+				if day.Adjustments[j].Kind == adjustment.Kind {
+					day.Adjustments[j].Amount += adjustment.Amount
+					found = true
+				}
+			}
+			if !found {
+				day.Adjustments = append(day.Adjustments, adjustment)
+			}
+		}
+		breakdown.Total += prices[i].Total
+	}
+	for _, day := range days {
+		breakdown.Days = append(breakdown.Days, *day)
+	}
+	sort.Slice(breakdown.Days, func(i, j int) bool {
+		return breakdown.Days[i].Day < breakdown.Days[j].Day
+	})
+	return breakdown
+}
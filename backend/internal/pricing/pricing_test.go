@@ -0,0 +1,142 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var deadline = time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+
+func TestPriceWithoutRules(t *testing.T) {
+	entries := []Entry{{BandName: "A", Day: 1, BandPrice: 9}, {BandName: "B", Day: 1, BandPrice: 8}}
+	prices := Rules{}.Price(Player{Category: "S"}, entries)
+	require.Equal(t, []Price{
+		{BandPrice: 9, Adjustments: []Adjustment{}, Total: 9},
+		{BandPrice: 8, Adjustments: []Adjustment{}, Total: 8},
+	}, prices)
+}
+
+func TestPriceCategory(t *testing.T) {
+	rules := Rules{Categories: []CategoryPrice{
+		{Categories: []string{"P", "B1", "B2"}, Price: 5},
+		{Categories: []string{"B1"}, Price: 4},
+	}}
+	entries := []Entry{{BandName: "A", Day: 1, BandPrice: 9}, {BandName: "B", Day: 1, BandPrice: 3}}
+
+	prices := rules.Price(Player{Category: "B1"}, entries)
+	// The lowest price applies
+	require.Equal(t, []Adjustment{{Kind: Adjustment_CATEGORY, Amount: -5}}, prices[0].Adjustments)
+	require.Equal(t, 4, prices[0].Total)
+	// The bands which cost less keep their price
+	require.Empty(t, prices[1].Adjustments)
+	require.Equal(t, 3, prices[1].Total)
+
+	prices = rules.Price(Player{Category: "S"}, entries)
+	require.Equal(t, 9, prices[0].Total)
+}
+
+func TestPriceClub(t *testing.T) {
+	rules := Rules{Clubs: []ClubDiscount{{ClubNumbers: []string{"08770040"}, Discount: 2}}}
+	entries := []Entry{{BandName: "A", Day: 1, BandPrice: 9}, {BandName: "B", Day: 2, BandPrice: 1}}
+
+	prices := rules.Price(Player{ClubNumber: "08770040"}, entries)
+	require.Equal(t, 7, prices[0].Total)
+	// An entry does not cost less than nothing
+	require.Equal(t, []Adjustment{{Kind: Adjustment_CLUB, Amount: -1}}, prices[1].Adjustments)
+	require.Equal(t, 0, prices[1].Total)
+
+	prices = rules.Price(Player{}, entries)
+	require.Equal(t, 9, prices[0].Total)
+}
+
+func TestPriceMultiBand(t *testing.T) {
+	rules := Rules{MultiBand: []MultiBandDiscount{{From: 2, Discount: 1}, {From: 3, Discount: 3}}}
+	entries := []Entry{
+		{BandName: "C", Day: 1, BandPrice: 8},
+		{BandName: "A", Day: 1, BandPrice: 10},
+		{BandName: "B", Day: 1, BandPrice: 8},
+		{BandName: "E", Day: 2, BandPrice: 9},
+	}
+
+	prices := rules.Price(Player{}, entries)
+	// A is the first band of day 1, B the second and C the third
+	require.Equal(t, 5, prices[0].Total)
+	require.Equal(t, 10, prices[1].Total)
+	require.Equal(t, 7, prices[2].Total)
+	require.Equal(t, []Adjustment{{Kind: Adjustment_MULTI_BAND, Amount: -1}}, prices[2].Adjustments)
+	// The bands are counted by day
+	require.Equal(t, 9, prices[3].Total)
+}
+
+func TestPriceLate(t *testing.T) {
+	rules := Rules{Late: []LateSurcharge{
+		{After: deadline.AddDate(0, 0, 5), Surcharge: 3},
+		{After: deadline, Surcharge: 1},
+	}}
+	entries := []Entry{
+		{BandName: "A", Day: 1, BandPrice: 9, RegisteredAt: deadline},
+		{BandName: "B", Day: 1, BandPrice: 8, RegisteredAt: deadline.Add(time.Hour)},
+		{BandName: "C", Day: 2, BandPrice: 8, RegisteredAt: deadline.AddDate(0, 0, 6)},
+	}
+
+	prices := rules.Price(Player{}, entries)
+	require.Equal(t, 9, prices[0].Total)
+	require.Equal(t, 9, prices[1].Total)
+	require.Equal(t, []Adjustment{{Kind: Adjustment_LATE, Amount: 1}}, prices[1].Adjustments)
+	// The latest period applies
+	require.Equal(t, 11, prices[2].Total)
+}
+
+func TestPriceAllRules(t *testing.T) {
+	rules := Rules{
+		Categories: []CategoryPrice{{Categories: []string{"J1"}, Price: 6}},
+		Clubs:      []ClubDiscount{{ClubNumbers: []string{"08770040"}, Discount: 1}},
+		MultiBand:  []MultiBandDiscount{{From: 2, Discount: 2}},
+		Late:       []LateSurcharge{{After: deadline, Surcharge: 2}},
+	}
+	entries := []Entry{
+		{BandName: "A", Day: 1, BandPrice: 9, RegisteredAt: deadline.Add(time.Hour)},
+		{BandName: "B", Day: 1, BandPrice: 8},
+	}
+
+	prices := rules.Price(Player{Category: "J1", ClubNumber: "08770040"}, entries)
+	require.Equal(t, Price{
+		BandPrice: 9,
+		Adjustments: []Adjustment{
+			{Kind: Adjustment_CATEGORY, Amount: -3},
+			{Kind: Adjustment_CLUB, Amount: -1},
+			{Kind: Adjustment_LATE, Amount: 2},
+		},
+		Total: 7,
+	}, prices[0])
+	require.Equal(t, 3, prices[1].Total)
+
+	breakdown := NewBreakdown(entries, prices)
+	require.Equal(t, Breakdown{
+		Days: []Day{{
+			Day:       1,
+			BandPrice: 17,
+			Adjustments: []Adjustment{
+				{Kind: Adjustment_CATEGORY, Amount: -5},
+				{Kind: Adjustment_CLUB, Amount: -2},
+				{Kind: Adjustment_LATE, Amount: 2},
+				{Kind: Adjustment_MULTI_BAND, Amount: -2},
+			},
+			Total: 10,
+		}},
+		Total: 10,
+	}, breakdown)
+}
+
+func TestNewBreakdown(t *testing.T) {
+	require.Equal(t, Breakdown{Days: []Day{}}, NewBreakdown(nil, nil))
+
+	entries := []Entry{{BandName: "E", Day: 2, BandPrice: 9}, {BandName: "A", Day: 1, BandPrice: 8}}
+	breakdown := NewBreakdown(entries, Rules{}.Price(Player{}, entries))
+	require.Len(t, breakdown.Days, 2)
+	require.Equal(t, 1, breakdown.Days[0].Day)
+	require.Equal(t, 8, breakdown.Days[0].Total)
+	require.Equal(t, 17, breakdown.Total)
+}
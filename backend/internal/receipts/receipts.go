@@ -0,0 +1,149 @@
+package receipts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SuperPingPong/tournoi/internal/pdf"
+)
+
+const (
+	marginLeft  = 50
+	marginRight = pdf.PageWidth - 50
+	// pageBottom is where a new page is started
+	pageBottom = pdf.PageHeight - 80
+	lineHeight = 16
+)
+
+// Letterhead is the organizer of the tournament, at the top of the receipts
+type Letterhead struct {
+	Name    string
+	Address string
+	// SIRET is the registration number of the club, printed when set
+	SIRET string
+}
+
+// Line is an amount of the receipt, in euros, negative for discounts and refunds
+type Line struct {
+	Label  string
+	Amount int
+}
+
+// Receipt is what a member paid for a day of the tournament
+type Receipt struct {
+	Number     int
+	IssuedAt   time.Time
+	Letterhead Letterhead
+
+	MemberName string
+	PermitID   string
+	ClubName   string
+	Day        int
+	// Date of the day, zero when the dates of the tournament are not configured
+	Date time.Time
+
+	// Lines are the entries of the day and their adjustments, which sum to Due
+	Lines []Line
+	Due   int
+	// Payments are labelled with their date and method
+	Payments []Line
+	Paid     int
+}
+
+// Reference is the number printed on the receipt
+func (r Receipt) Reference() string {
+	return fmt.Sprintf("%06d", r.Number)
+}
+
+func (r Receipt) Filename() string {
+	return fmt.Sprintf("recu-%s.pdf", r.Reference())
+}
+
+func formatAmount(amount int) string {
+	return fmt.Sprintf("%d,00 €", amount)
+}
+
+// writer places the lines of the receipt one after the other, on new pages when needed
+type writer struct {
+	document *pdf.Document
+	page     *pdf.Page
+	y        float64
+}
+
+func (w *writer) next(height float64) {
+	w.y += height
+	if w.y > pageBottom {
+		w.page = w.document.AddPage()
+		w.y = 60
+	}
+}
+
+func (w *writer) row(font string, label string, amount string) {
+	w.next(lineHeight)
+	w.page.Text(marginLeft, w.y, font, 10, label)
+	w.page.TextRight(marginRight, w.y, font, 10, amount)
+}
+
+func (w *writer) rule() {
+	w.next(6)
+	w.page.Line(marginLeft, w.y, marginRight, w.y)
+}
+
+// PDF renders the receipt, in French
+func (r Receipt) PDF() []byte {
+	document := &pdf.Document{Title: "Reçu n° " + r.Reference()}
+	w := &writer{document: document, page: document.AddPage(), y: 60}
+
+	w.page.Text(marginLeft, w.y, pdf.Font_BOLD, 16, r.Letterhead.Name)
+	w.page.TextRight(marginRight, w.y, pdf.Font_BOLD, 14, "Reçu n° "+r.Reference())
+	if r.Letterhead.Address != "" {
+		w.next(lineHeight)
+		w.page.Text(marginLeft, w.y, pdf.Font_REGULAR, 10, r.Letterhead.Address)
+	}
+	if r.Letterhead.SIRET != "" {
+		w.next(lineHeight)
+		w.page.Text(marginLeft, w.y, pdf.Font_REGULAR, 10, "SIRET : "+r.Letterhead.SIRET)
+	}
+	w.next(lineHeight)
+	w.page.TextRight(marginRight, w.y, pdf.Font_REGULAR, 10, "Émis le "+r.IssuedAt.Format("02/01/2006"))
+
+	w.next(2 * lineHeight)
+	title := fmt.Sprintf("Tournoi - jour %d", r.Day)
+	if !r.Date.IsZero() {
+		title += " - " + r.Date.Format("02/01/2006")
+	}
+	w.page.Text(marginLeft, w.y, pdf.Font_BOLD, 12, title)
+	w.next(lineHeight)
+	w.page.Text(marginLeft, w.y, pdf.Font_REGULAR, 10, fmt.Sprintf("Joueur : %s, licence %s", r.MemberName, r.PermitID))
+	if r.ClubName != "" {
+		w.next(lineHeight)
+		w.page.Text(marginLeft, w.y, pdf.Font_REGULAR, 10, "Club : "+r.ClubName)
+	}
+
+	w.next(lineHeight)
+	w.row(pdf.Font_BOLD, "Inscriptions", "Montant")
+	w.rule()
+	for _, line := range r.Lines {
+		w.row(pdf.Font_REGULAR, line.Label, formatAmount(line.Amount))
+	}
+	w.rule()
+	w.row(pdf.Font_BOLD, "Total dû", formatAmount(r.Due))
+
+	w.next(lineHeight)
+	w.row(pdf.Font_BOLD, "Règlements", "Montant")
+	w.rule()
+	for _, payment := range r.Payments {
+		w.row(pdf.Font_REGULAR, payment.Label, formatAmount(payment.Amount))
+	}
+	w.rule()
+	w.row(pdf.Font_BOLD, "Total réglé", formatAmount(r.Paid))
+	if r.Due > r.Paid {
+		w.row(pdf.Font_REGULAR, "Reste dû", formatAmount(r.Due-r.Paid))
+	}
+
+	w.next(2 * lineHeight)
+	w.page.Text(marginLeft, w.y, pdf.Font_REGULAR, 10, fmt.Sprintf("%s certifie avoir reçu la somme de %s", r.Letterhead.Name, formatAmount(r.Paid)))
+	w.next(lineHeight)
+	w.page.Text(marginLeft, w.y, pdf.Font_REGULAR, 10, "au titre des droits d'inscription ci-dessus.")
+	return document.Bytes()
+}
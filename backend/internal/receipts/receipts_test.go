@@ -0,0 +1,61 @@
+package receipts
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestReceiptPDF(t *testing.T) {
+	receipt := Receipt{
+		Number:     12,
+		IssuedAt:   time.Date(2024, time.June, 8, 15, 0, 0, 0, time.UTC),
+		Letterhead: Letterhead{Name: "E.P. de Lognes", Address: "Gymnase, 77185 Lognes", SIRET: "12345678900010"},
+		MemberName: "Jean PIERRE",
+		PermitID:   "123451",
+		ClubName:   "LOGNES EP",
+		Day:        1,
+		Date:       time.Date(2024, time.June, 8, 0, 0, 0, 0, time.UTC),
+		Lines:      []Line{{Label: "Tableau A", Amount: 9}, {Label: "Tableau B", Amount: 8}, {Label: "Réduction multi-tableaux", Amount: -2}},
+		Due:        15,
+		Payments:   []Line{{Label: "08/06/2024 Espèces", Amount: 10}},
+		Paid:       10,
+	}
+	require.Equal(t, "000012", receipt.Reference())
+	require.Equal(t, "recu-000012.pdf", receipt.Filename())
+
+	content, err := charmap.Windows1252.NewDecoder().Bytes(receipt.PDF())
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(content, []byte("%PDF-")))
+	for _, text := range []string{
+		"(E.P. de Lognes)",
+		"(Reçu n° 000012)",
+		"(SIRET : 12345678900010)",
+		"(Émis le 08/06/2024)",
+		"(Tournoi - jour 1 - 08/06/2024)",
+		"(Joueur : Jean PIERRE, licence 123451)",
+		"(Réduction multi-tableaux)",
+		"(-2,00 €)",
+		"(Total dû)",
+		"(15,00 €)",
+		"(08/06/2024 Espèces)",
+		"(Reste dû)",
+		"(5,00 €)",
+	} {
+		require.Contains(t, string(content), text)
+	}
+}
+
+func TestReceiptPages(t *testing.T) {
+	receipt := Receipt{Letterhead: Letterhead{Name: "E.P. de Lognes"}, Day: 1}
+	for i := 0; i < 60; i++ {
+		receipt.Lines = append(receipt.Lines, Line{Label: "Tableau", Amount: 1})
+	}
+
+	require.Contains(t, string(receipt.PDF()), "/Count 2")
+	// The date of the day is optional
+	require.Contains(t, string(receipt.PDF()), "(Tournoi - jour 1)")
+}
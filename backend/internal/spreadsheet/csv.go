@@ -0,0 +1,27 @@
+package spreadsheet
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// WriteCSV writes the sheet delimited by semicolons and prefixed with a byte order mark, as expected by Excel in French
+func WriteCSV(w io.Writer, sheet Sheet) error {
+	if _, err := io.WriteString(w, "\xef\xbb\xbf"); err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Comma = ';'
+	for _, row := range sheet.Rows {
+		record := make([]string, len(row))
+		for i, value := range row {
+			record[i] = text(value)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
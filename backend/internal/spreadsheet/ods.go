@@ -0,0 +1,72 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const odsMimetype = "application/vnd.oasis.opendocument.spreadsheet"
+
+const odsManifest = `<?xml version="1.0" encoding="UTF-8"?>
+<manifest:manifest xmlns:manifest="urn:oasis:names:tc:opendocument:xmlns:manifest:1.0" manifest:version="1.2">
+<manifest:file-entry manifest:full-path="/" manifest:media-type="application/vnd.oasis.opendocument.spreadsheet"/>
+<manifest:file-entry manifest:full-path="content.xml" manifest:media-type="text/xml"/>
+</manifest:manifest>`
+
+// WriteODS writes the sheet as an OpenDocument spreadsheet
+func WriteODS(w io.Writer, sheet Sheet) error {
+	archive := zip.NewWriter(w)
+
+	// The mimetype has to be the first entry, stored without compression
+	writer, err := archive.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err = io.WriteString(writer, odsMimetype); err != nil {
+		return err
+	}
+
+	if writer, err = archive.Create("META-INF/manifest.xml"); err != nil {
+		return err
+	}
+	if _, err = io.WriteString(writer, odsManifest); err != nil {
+		return err
+	}
+
+	if writer, err = archive.Create("content.xml"); err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(writer, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+
+		`<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" `+
+		`xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" `+
+		`xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" office:version="1.2">`+
+		`<office:body><office:spreadsheet><table:table table:name="%s">`, escape(sheet.Name)); err != nil {
+		return err
+	}
+	for _, row := range sheet.Rows {
+		var b strings.Builder
+		b.WriteString("<table:table-row>")
+		for _, value := range row {
+			if value == nil {
+				b.WriteString("<table:table-cell/>")
+				continue
+			}
+			if n, ok := number(value); ok {
+				fmt.Fprintf(&b, `<table:table-cell office:value-type="float" office:value="%s"><text:p>%s</text:p></table:table-cell>`, n, n)
+			} else {
+				fmt.Fprintf(&b, `<table:table-cell office:value-type="string"><text:p>%s</text:p></table:table-cell>`, escape(text(value)))
+			}
+		}
+		b.WriteString("</table:table-row>")
+		if _, err = io.WriteString(writer, b.String()); err != nil {
+			return err
+		}
+	}
+	if _, err = io.WriteString(writer, "</table:table></office:spreadsheet></office:body></office:document-content>"); err != nil {
+		return err
+	}
+
+	return archive.Close()
+}
@@ -0,0 +1,88 @@
+package spreadsheet
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	Format_CSV  string = "csv"
+	Format_XLSX        = "xlsx"
+	Format_ODS         = "ods"
+)
+
+var ErrUnknownFormat = errors.New("unknown spreadsheet format")
+
+// Sheet is a table of cells, which are strings or numbers (int or float64)
+type Sheet struct {
+	Name string
+	Rows [][]interface{}
+}
+
+// ContentType returns the media type of the format
+func ContentType(format string) string {
+	switch format {
+	case Format_CSV:
+		return "text/csv; charset=utf-8"
+	case Format_XLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	case Format_ODS:
+		return "application/vnd.oasis.opendocument.spreadsheet"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// Write streams the sheet to w in the given format
+func Write(w io.Writer, format string, sheet Sheet) error {
+	switch format {
+	case Format_CSV:
+		return WriteCSV(w, sheet)
+	case Format_XLSX:
+		return WriteXLSX(w, sheet)
+	case Format_ODS:
+		return WriteODS(w, sheet)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+}
+
+// number returns the text of a numeric cell, and whether the cell is numeric
+func number(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
+// text returns the text of a cell. The texts read as a formula by spreadsheets, like =HYPERLINK(...) typed as a name,
+// are prefixed with a quote so that they stay texts.
+func text(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	if n, ok := number(value); ok {
+		return n
+	}
+	s := fmt.Sprint(value)
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func escape(value string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
@@ -0,0 +1,111 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testSheet = Sheet{
+	Name: "Base inscrits",
+	Rows: [][]interface{}{
+		{"N°", "Nom", "Points", "A"},
+		{1, "LEFÈVRE <Chloé>", 1234.5, nil},
+		{2, "D'Aubigné; Hélène", 500, "L1"},
+	},
+}
+
+func readZip(t *testing.T, content []byte) map[string]string {
+	archive, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		files[file.Name] = string(data)
+	}
+	return files
+}
+
+// requireWellFormed checks that the XML document can be parsed
+func requireWellFormed(t *testing.T, document string) {
+	decoder := xml.NewDecoder(bytes.NewReader([]byte(document)))
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			return
+		}
+		require.NoError(t, err)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	require.Equal(t, "A", columnName(0))
+	require.Equal(t, "Z", columnName(25))
+	require.Equal(t, "AA", columnName(26))
+	require.Equal(t, "AD", columnName(29))
+	require.Equal(t, "BA", columnName(52))
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buffer bytes.Buffer
+	require.NoError(t, Write(&buffer, Format_CSV, testSheet))
+	require.Equal(t, "\xef\xbb\xbfN°;Nom;Points;A\n1;LEFÈVRE <Chloé>;1234.5;\n2;\"D'Aubigné; Hélène\";500;L1\n", buffer.String())
+}
+
+func TestWriteXLSX(t *testing.T) {
+	var buffer bytes.Buffer
+	require.NoError(t, Write(&buffer, Format_XLSX, testSheet))
+
+	files := readZip(t, buffer.Bytes())
+	require.Contains(t, files, "[Content_Types].xml")
+	require.Contains(t, files, "_rels/.rels")
+	require.Contains(t, files, "xl/_rels/workbook.xml.rels")
+	require.Contains(t, files["xl/workbook.xml"], `<sheet name="Base inscrits"`)
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	requireWellFormed(t, sheet)
+	require.Contains(t, sheet, `<c r="B2" t="inlineStr"><is><t xml:space="preserve">LEFÈVRE &lt;Chloé&gt;</t></is></c>`)
+	require.Contains(t, sheet, `<c r="C2"><v>1234.5</v></c>`)
+	require.NotContains(t, sheet, `r="D2"`)
+	require.Contains(t, sheet, `<c r="D3" t="inlineStr"><is><t xml:space="preserve">L1</t></is></c>`)
+}
+
+func TestWriteODS(t *testing.T) {
+	var buffer bytes.Buffer
+	require.NoError(t, Write(&buffer, Format_ODS, testSheet))
+
+	archive, err := zip.NewReader(bytes.NewReader(buffer.Bytes()), int64(buffer.Len()))
+	require.NoError(t, err)
+	require.Equal(t, "mimetype", archive.File[0].Name)
+	require.Equal(t, zip.Store, archive.File[0].Method)
+
+	files := readZip(t, buffer.Bytes())
+	require.Equal(t, "application/vnd.oasis.opendocument.spreadsheet", files["mimetype"])
+	requireWellFormed(t, files["content.xml"])
+	require.Contains(t, files["content.xml"], `<table:table table:name="Base inscrits">`)
+	require.Contains(t, files["content.xml"], `<table:table-cell office:value-type="float" office:value="500"><text:p>500</text:p></table:table-cell>`)
+}
+
+func TestFormulaInjection(t *testing.T) {
+	sheet := Sheet{Rows: [][]interface{}{{"=HYPERLINK(\"http://example.com\")", "@SUM(A1)", "+33", "Jean-Pierre", -5}}}
+
+	var buffer bytes.Buffer
+	require.NoError(t, Write(&buffer, Format_CSV, sheet))
+	require.Equal(t, "\xef\xbb\xbf\"'=HYPERLINK(\"\"http://example.com\"\")\";'@SUM(A1);'+33;Jean-Pierre;-5\n", buffer.String())
+
+	buffer.Reset()
+	require.NoError(t, Write(&buffer, Format_XLSX, sheet))
+	require.Contains(t, readZip(t, buffer.Bytes())["xl/worksheets/sheet1.xml"], `<t xml:space="preserve">&#39;@SUM(A1)</t>`)
+}
+
+func TestWriteUnknownFormat(t *testing.T) {
+	require.ErrorIs(t, Write(io.Discard, "pdf", testSheet), ErrUnknownFormat)
+}
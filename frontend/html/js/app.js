@@ -71,9 +71,8 @@ function initDataTable() {
       {
         data: null,
         render: function(data, type, row) {
-          const bandPrices = row.Entries === null ? '' : row.Entries.map(entry => entry.BandPrice);
-          const sumOfBandPrices = bandPrices.length > 0 ? bandPrices.reduce((total, price) => total + price) : '';
-          return sumOfBandPrices;
+          // The price once the discounts and surcharges of the pricing rules are applied
+          return row.Entries === null || row.Entries.length === 0 ? '' : row.Price.Total;
         }
       },
       {
@@ -84,6 +83,7 @@ function initDataTable() {
 
           const historyButton = `<button style="display:none" type="submit" data-action="history" data-info='${rowData}'><i class="fa-solid fa-history"></i></button>`;
           const mailButton = `<button style="display: none" type="submit" data-action="mail" data-info='${rowData}'><i class="fa-solid fa-envelope"></i></button>`;
+          const badgeButton = `<button type="submit" data-action="badge" data-info='${rowData}'><i class="fa-solid fa-qrcode"></i></button>`;
 
           const editButtonStyle = isAfterDeadline() ? 'display: none' : '';
           const editButton = `<button style="${editButtonStyle}" type="submit" data-action="edit" data-info='${rowData}'><i class="fa-solid fa-pencil"></i></button>`;
@@ -92,7 +92,7 @@ function initDataTable() {
           const deleteButton = `<button style="${deleteButtonStyle}" type="submit" data-action="delete" data-info='${rowData}'><i class="fa-solid fa-rectangle-xmark" style="color: red;"></i></button>`;
 
           // const buttonsContainer = '<div class="field">' + historyButton + mailButton + editButton + '</div>';
-          const buttonsContainer = '<div class="field">' + historyButton + mailButton + editButton + deleteButton + '</div>';
+          const buttonsContainer = '<div class="field">' + historyButton + mailButton + badgeButton + editButton + deleteButton + '</div>';
           return buttonsContainer;
         }
       }
@@ -101,6 +101,11 @@ function initDataTable() {
       document.querySelector('div.toolbar').innerHTML = '<span class="onlymobile">Faire défiler sur la droite pour modifier les tableaux</span>';
       // Attach click event listener to parent element (dataTable)
       const isAdmin = settings.json.IsAdmin
+      $('#dataTable').off('click', 'button[data-action="badge"]').on('click', 'button[data-action="badge"]', function(event) {
+        event.preventDefault();
+        const memberString = $(this).attr('data-info');
+        badgeMember(memberString);
+      });
       if (isAdmin === true) {
         $('button[data-action="edit"]').show();
         $('button[data-action="delete"]').show();
@@ -246,6 +251,27 @@ function mailMemberBands(memberString) {
   });
 }
 
+function badgeMember(memberString) {
+  const member = JSON.parse(atob(memberString));
+  const badgeURL = `/api/members/${member.ID}/badge`;
+  Swal.fire({
+    title: 'Badge de pointage',
+    html:
+      getMemberHeaderHtml(member) +
+      `<img src="${badgeURL}" alt="QR code" width="256" height="256">` +
+      '<div>À présenter à la table de pointage le jour du tournoi.</div>',
+    showCancelButton: true,
+    cancelButtonText: 'Fermer',
+    confirmButtonText: 'Imprimer',
+    confirmButtonColor: '#5468D4'
+  }).then((result) => {
+    if (result.isConfirmed) {
+      const badgeWindow = window.open(badgeURL);
+      badgeWindow.onload = () => badgeWindow.print();
+    }
+  });
+}
+
 function waitForCheckboxes() {
     return new Promise((resolve) => {
         const checkboxes = document.querySelectorAll('input[type="checkbox"]');
@@ -521,7 +547,7 @@ function init() {
       cancelButtonColor: '#dc3741',
     }).then((result) => {
       if (result.isConfirmed) {
-        // window.location.href = '/api/export';
+        // window.location.href = '/api/admin/export';
         Swal.fire({
           title: 'Traitement en cours',
           html: 'Veuillez patienter...<br><progress value="0" max="10"></progress>',
@@ -539,7 +565,7 @@ function init() {
             Swal.showLoading();
             // Make AJAX call to /api/export
             // Replace the below line with your own logic
-            fetch('/api/export')
+            fetch('/api/admin/export?format=xlsx')
               .then(response => {
                 clearInterval(interval);
                 if (response.ok) {